@@ -3,13 +3,17 @@ package main
 import (
 	"context"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/joshp123/spotctl/internal/spotctl"
 )
 
 func main() {
-	ctx := context.Background()
+	// Long-running commands (scrobble) stop cleanly on Ctrl-C.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	code := spotctl.Main(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	stop()
 	os.Exit(code)
 }
 
@@ -0,0 +1,314 @@
+// Package dbus is a small D-Bus client: enough of the wire protocol to call
+// methods, export an object (MPRIS) and talk to the Secret Service, without
+// pulling a dependency into spotctl.
+package dbus
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	busName      = "org.freedesktop.DBus"
+	busPath      = ObjectPath("/org/freedesktop/DBus")
+	busInterface = "org.freedesktop.DBus"
+)
+
+// CallHandler handles incoming method calls. It must reply (Reply/ReplyError)
+// unless the call has NoReplyExpected set. Each call runs on its own goroutine.
+type CallHandler func(c *Conn, call *Message)
+
+type Conn struct {
+	nc     net.Conn
+	name   string
+	serial atomic.Uint32
+
+	wmu sync.Mutex
+
+	mu      sync.Mutex
+	pending map[uint32]chan *Message
+	handler CallHandler
+	closed  bool
+	err     error
+	done    chan struct{}
+}
+
+// SessionBus connects to $DBUS_SESSION_BUS_ADDRESS.
+func SessionBus() (*Conn, error) {
+	addr := os.Getenv("DBUS_SESSION_BUS_ADDRESS")
+	if addr == "" {
+		return nil, errors.New("dbus: DBUS_SESSION_BUS_ADDRESS is not set (no session bus)")
+	}
+	return Dial(addr)
+}
+
+// Dial connects to a bus address (unix:path=... or unix:abstract=...),
+// authenticates with EXTERNAL and registers with Hello.
+func Dial(address string) (*Conn, error) {
+	var lastErr error
+	for _, a := range strings.Split(address, ";") {
+		nc, err := dialOne(a)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		c, err := newConn(nc)
+		if err != nil {
+			_ = nc.Close()
+			lastErr = err
+			continue
+		}
+		return c, nil
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("dbus: no usable address in %q", address)
+	}
+	return nil, lastErr
+}
+
+func dialOne(address string) (net.Conn, error) {
+	transport, params, ok := strings.Cut(address, ":")
+	if !ok || transport != "unix" {
+		return nil, fmt.Errorf("dbus: unsupported address %q", address)
+	}
+	kv := map[string]string{}
+	for _, p := range strings.Split(params, ",") {
+		k, v, _ := strings.Cut(p, "=")
+		kv[k] = unescapeAddr(v)
+	}
+	switch {
+	case kv["path"] != "":
+		return net.Dial("unix", kv["path"])
+	case kv["abstract"] != "":
+		return net.Dial("unix", "@"+kv["abstract"])
+	}
+	return nil, fmt.Errorf("dbus: unsupported address %q", address)
+}
+
+func unescapeAddr(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' && i+2 < len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+3], 16, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 2
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func newConn(nc net.Conn) (*Conn, error) {
+	br := bufio.NewReader(nc)
+	if err := authExternal(nc, br); err != nil {
+		return nil, err
+	}
+	c := &Conn{nc: nc, pending: map[uint32]chan *Message{}, done: make(chan struct{})}
+	go c.readLoop(br)
+
+	reply, err := c.Call(context.Background(), busName, busPath, busInterface, "Hello", "")
+	if err != nil {
+		_ = c.Close()
+		return nil, fmt.Errorf("dbus: Hello: %w", err)
+	}
+	if len(reply.Body) == 1 {
+		c.name, _ = reply.Body[0].(string)
+	}
+	return c, nil
+}
+
+func authExternal(nc net.Conn, br *bufio.Reader) error {
+	uid := hex.EncodeToString([]byte(strconv.Itoa(os.Getuid())))
+	if _, err := fmt.Fprintf(nc, "\x00AUTH EXTERNAL %s\r\n", uid); err != nil {
+		return err
+	}
+	line, err := br.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("dbus: auth: %w", err)
+	}
+	if !strings.HasPrefix(line, "OK ") {
+		return fmt.Errorf("dbus: auth rejected: %s", strings.TrimSpace(line))
+	}
+	_, err = fmt.Fprint(nc, "BEGIN\r\n")
+	return err
+}
+
+// UniqueName is the connection's bus name (":1.42").
+func (c *Conn) UniqueName() string { return c.name }
+
+// Done is closed when the connection drops.
+func (c *Conn) Done() <-chan struct{} { return c.done }
+
+func (c *Conn) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	c.mu.Unlock()
+	return c.nc.Close()
+}
+
+// HandleCalls installs the handler for incoming method calls. Without one,
+// calls are answered with UnknownMethod.
+func (c *Conn) HandleCalls(h CallHandler) {
+	c.mu.Lock()
+	c.handler = h
+	c.mu.Unlock()
+}
+
+func (c *Conn) readLoop(br *bufio.Reader) {
+	var err error
+	defer func() {
+		c.mu.Lock()
+		c.closed = true
+		c.err = err
+		for s, ch := range c.pending {
+			close(ch)
+			delete(c.pending, s)
+		}
+		c.mu.Unlock()
+		_ = c.nc.Close()
+		close(c.done)
+	}()
+	for {
+		var m *Message
+		m, err = readMessage(br)
+		if err != nil {
+			return
+		}
+		switch m.Type {
+		case TypeMethodReturn, TypeError:
+			c.mu.Lock()
+			ch := c.pending[m.ReplySerial]
+			delete(c.pending, m.ReplySerial)
+			c.mu.Unlock()
+			if ch != nil {
+				ch <- m
+			}
+		case TypeMethodCall:
+			c.mu.Lock()
+			h := c.handler
+			c.mu.Unlock()
+			if h == nil {
+				if !m.NoReplyExpected() {
+					_ = c.ReplyError(m, ErrUnknownMethod, "no objects exported")
+				}
+				continue
+			}
+			go h(c, m)
+		}
+	}
+}
+
+func (c *Conn) send(m *Message) error {
+	m.Serial = c.serial.Add(1)
+	b, err := m.marshal()
+	if err != nil {
+		return err
+	}
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	_, err = c.nc.Write(b)
+	return err
+}
+
+// Call invokes a method and waits for the reply. D-Bus error replies are
+// returned as *Error.
+func (c *Conn) Call(ctx context.Context, dest string, path ObjectPath, iface, member string, sig Signature, args ...any) (*Message, error) {
+	m := &Message{Type: TypeMethodCall, Destination: dest, Path: path, Interface: iface, Member: member, Signature: sig, Body: args}
+	ch := make(chan *Message, 1)
+
+	c.wmu.Lock()
+	m.Serial = c.serial.Add(1)
+	b, err := m.marshal()
+	if err != nil {
+		c.wmu.Unlock()
+		return nil, err
+	}
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		c.wmu.Unlock()
+		return nil, errors.New("dbus: connection closed")
+	}
+	c.pending[m.Serial] = ch
+	c.mu.Unlock()
+	_, err = c.nc.Write(b)
+	c.wmu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-ctx.Done():
+		c.mu.Lock()
+		delete(c.pending, m.Serial)
+		c.mu.Unlock()
+		return nil, ctx.Err()
+	case reply, ok := <-ch:
+		if !ok {
+			return nil, errors.New("dbus: connection closed")
+		}
+		if reply.Type == TypeError {
+			e := &Error{Name: reply.ErrorName}
+			if len(reply.Body) > 0 {
+				e.Message, _ = reply.Body[0].(string)
+			}
+			return nil, e
+		}
+		return reply, nil
+	}
+}
+
+func (c *Conn) Reply(call *Message, sig Signature, args ...any) error {
+	if call.NoReplyExpected() {
+		return nil
+	}
+	return c.send(&Message{Type: TypeMethodReturn, Destination: call.Sender, ReplySerial: call.Serial, Signature: sig, Body: args})
+}
+
+func (c *Conn) ReplyError(call *Message, name, msg string) error {
+	if call.NoReplyExpected() {
+		return nil
+	}
+	return c.send(&Message{Type: TypeError, Destination: call.Sender, ReplySerial: call.Serial, ErrorName: name, Signature: "s", Body: []any{msg}})
+}
+
+func (c *Conn) Emit(path ObjectPath, iface, member string, sig Signature, args ...any) error {
+	return c.send(&Message{Type: TypeSignal, Path: path, Interface: iface, Member: member, Signature: sig, Body: args})
+}
+
+// RequestName claims a well-known bus name. It fails if another connection
+// already owns it.
+func (c *Conn) RequestName(ctx context.Context, name string) error {
+	const doNotQueue = 0x4
+	reply, err := c.Call(ctx, busName, busPath, busInterface, "RequestName", "su", name, uint32(doNotQueue))
+	if err != nil {
+		return err
+	}
+	if len(reply.Body) != 1 {
+		return errors.New("dbus: bad RequestName reply")
+	}
+	switch reply.Body[0].(uint32) {
+	case 1, 4: // primary owner, already owner
+		return nil
+	default:
+		return fmt.Errorf("dbus: name %s is already taken", name)
+	}
+}
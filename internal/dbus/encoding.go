@@ -0,0 +1,635 @@
+package dbus
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Go representations of D-Bus values:
+//
+//	y byte    b bool      n int16   q uint16   i int32   u uint32
+//	x int64   t uint64    d float64 s string   o ObjectPath
+//	g Signature           v Variant
+//	as []string   ao []ObjectPath   ay []byte   a{sv} map[string]Variant
+//	other arrays []any    other dicts map[any]any    structs []any
+//
+// Encoding accepts these types (plus int for i/u/x/t and map[string]any for
+// a{sv}, which is wrapped with SignatureOf); decoding always produces them.
+
+type ObjectPath string
+
+type Signature string
+
+type Variant struct {
+	Sig   Signature
+	Value any
+}
+
+// MakeVariant wraps v using its inferred signature.
+func MakeVariant(v any) Variant {
+	return Variant{Sig: SignatureOf(v), Value: v}
+}
+
+// SignatureOf infers the signature for the common Go types listed above.
+// It returns "" for anything it can't infer.
+func SignatureOf(v any) Signature {
+	switch v := v.(type) {
+	case byte:
+		return "y"
+	case bool:
+		return "b"
+	case int16:
+		return "n"
+	case uint16:
+		return "q"
+	case int32:
+		return "i"
+	case uint32:
+		return "u"
+	case int64:
+		return "x"
+	case uint64:
+		return "t"
+	case float64:
+		return "d"
+	case string:
+		return "s"
+	case ObjectPath:
+		return "o"
+	case Signature:
+		return "g"
+	case Variant:
+		return "v"
+	case []string:
+		return "as"
+	case []ObjectPath:
+		return "ao"
+	case []byte:
+		return "ay"
+	case map[string]Variant:
+		return "a{sv}"
+	case map[string]any:
+		return "a{sv}"
+	case []any:
+		s := "("
+		for _, x := range v {
+			s += string(SignatureOf(x))
+		}
+		return Signature(s + ")")
+	}
+	return ""
+}
+
+// splitSig returns the first complete type in sig and the remainder.
+func splitSig(sig string) (string, string, error) {
+	if sig == "" {
+		return "", "", errors.New("dbus: empty signature")
+	}
+	switch sig[0] {
+	case 'a':
+		elem, rest, err := splitSig(sig[1:])
+		if err != nil {
+			return "", "", err
+		}
+		return "a" + elem, rest, nil
+	case '(', '{':
+		closer := byte(')')
+		if sig[0] == '{' {
+			closer = '}'
+		}
+		depth := 0
+		for i := 0; i < len(sig); i++ {
+			switch sig[i] {
+			case '(', '{':
+				depth++
+			case ')', '}':
+				depth--
+				if depth == 0 {
+					if sig[i] != closer {
+						return "", "", fmt.Errorf("dbus: bad signature %q", sig)
+					}
+					return sig[:i+1], sig[i+1:], nil
+				}
+			}
+		}
+		return "", "", fmt.Errorf("dbus: unbalanced signature %q", sig)
+	default:
+		return sig[:1], sig[1:], nil
+	}
+}
+
+// splitAll splits a signature into its complete types.
+func splitAll(sig string) ([]string, error) {
+	var out []string
+	for sig != "" {
+		t, rest, err := splitSig(sig)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+		sig = rest
+	}
+	return out, nil
+}
+
+func alignOf(t byte) int {
+	switch t {
+	case 'y', 'g', 'v':
+		return 1
+	case 'n', 'q':
+		return 2
+	case 'x', 't', 'd', '(', '{':
+		return 8
+	default: // b i u s o a h
+		return 4
+	}
+}
+
+// encoder always writes little-endian messages.
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) align(n int) {
+	for len(e.buf)%n != 0 {
+		e.buf = append(e.buf, 0)
+	}
+}
+
+func (e *encoder) u32(v uint32) {
+	e.align(4)
+	e.buf = binary.LittleEndian.AppendUint32(e.buf, v)
+}
+
+func (e *encoder) str(s string) {
+	e.u32(uint32(len(s)))
+	e.buf = append(e.buf, s...)
+	e.buf = append(e.buf, 0)
+}
+
+func (e *encoder) sig(s string) {
+	e.buf = append(e.buf, byte(len(s)))
+	e.buf = append(e.buf, s...)
+	e.buf = append(e.buf, 0)
+}
+
+func toInt64(v any) (int64, bool) {
+	switch v := v.(type) {
+	case int:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint16:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	case uint64:
+		return int64(v), true
+	case byte:
+		return int64(v), true
+	}
+	return 0, false
+}
+
+func (e *encoder) encodeAll(sig string, vals []any) error {
+	types, err := splitAll(sig)
+	if err != nil {
+		return err
+	}
+	if len(types) != len(vals) {
+		return fmt.Errorf("dbus: signature %q wants %d values, got %d", sig, len(types), len(vals))
+	}
+	for i, t := range types {
+		if err := e.encode(t, vals[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *encoder) encode(t string, v any) error {
+	bad := func() error { return fmt.Errorf("dbus: cannot encode %T as %q", v, t) }
+	switch t[0] {
+	case 'y':
+		n, ok := toInt64(v)
+		if !ok {
+			return bad()
+		}
+		e.buf = append(e.buf, byte(n))
+	case 'b':
+		b, ok := v.(bool)
+		if !ok {
+			return bad()
+		}
+		var n uint32
+		if b {
+			n = 1
+		}
+		e.u32(n)
+	case 'n', 'q':
+		n, ok := toInt64(v)
+		if !ok {
+			return bad()
+		}
+		e.align(2)
+		e.buf = binary.LittleEndian.AppendUint16(e.buf, uint16(n))
+	case 'i', 'u':
+		n, ok := toInt64(v)
+		if !ok {
+			return bad()
+		}
+		e.u32(uint32(n))
+	case 'x', 't':
+		n, ok := toInt64(v)
+		if !ok {
+			return bad()
+		}
+		e.align(8)
+		e.buf = binary.LittleEndian.AppendUint64(e.buf, uint64(n))
+	case 'd':
+		f, ok := v.(float64)
+		if !ok {
+			return bad()
+		}
+		e.align(8)
+		e.buf = binary.LittleEndian.AppendUint64(e.buf, math.Float64bits(f))
+	case 's':
+		s, ok := v.(string)
+		if !ok {
+			return bad()
+		}
+		e.str(s)
+	case 'o':
+		switch s := v.(type) {
+		case ObjectPath:
+			e.str(string(s))
+		case string:
+			e.str(s)
+		default:
+			return bad()
+		}
+	case 'g':
+		switch s := v.(type) {
+		case Signature:
+			e.sig(string(s))
+		case string:
+			e.sig(s)
+		default:
+			return bad()
+		}
+	case 'v':
+		vv, ok := v.(Variant)
+		if !ok {
+			vv = MakeVariant(v)
+		}
+		if vv.Sig == "" {
+			return fmt.Errorf("dbus: cannot infer variant signature for %T", vv.Value)
+		}
+		e.sig(string(vv.Sig))
+		return e.encode(string(vv.Sig), vv.Value)
+	case '(':
+		fields, ok := v.([]any)
+		if !ok {
+			return bad()
+		}
+		e.align(8)
+		return e.encodeAll(t[1:len(t)-1], fields)
+	case 'a':
+		return e.encodeArray(t[1:], v)
+	default:
+		return fmt.Errorf("dbus: unsupported type %q", t)
+	}
+	return nil
+}
+
+func (e *encoder) encodeArray(elem string, v any) error {
+	e.u32(0)
+	lenPos := len(e.buf) - 4
+	e.align(alignOf(elem[0]))
+	start := len(e.buf)
+
+	var err error
+	if elem[0] == '{' {
+		inner := elem[1 : len(elem)-1]
+		kt, vt, _ := splitSig(inner)
+		entry := func(k, val any) {
+			if err != nil {
+				return
+			}
+			e.align(8)
+			if err = e.encode(kt, k); err == nil {
+				err = e.encode(vt, val)
+			}
+		}
+		switch m := v.(type) {
+		case map[string]Variant:
+			for _, k := range sortedKeys(m) {
+				entry(k, m[k])
+			}
+		case map[string]any:
+			for _, k := range sortedKeys(m) {
+				entry(k, m[k])
+			}
+		case map[ObjectPath]any:
+			for k, val := range m {
+				entry(k, val)
+			}
+		case map[any]any:
+			for k, val := range m {
+				entry(k, val)
+			}
+		default:
+			return fmt.Errorf("dbus: cannot encode %T as a%s", v, elem)
+		}
+	} else {
+		switch s := v.(type) {
+		case []byte:
+			e.buf = append(e.buf, s...)
+		case []string:
+			for _, x := range s {
+				if err = e.encode(elem, x); err != nil {
+					break
+				}
+			}
+		case []ObjectPath:
+			for _, x := range s {
+				if err = e.encode(elem, x); err != nil {
+					break
+				}
+			}
+		case []any:
+			for _, x := range s {
+				if err = e.encode(elem, x); err != nil {
+					break
+				}
+			}
+		default:
+			return fmt.Errorf("dbus: cannot encode %T as a%s", v, elem)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	binary.LittleEndian.PutUint32(e.buf[lenPos:], uint32(len(e.buf)-start))
+	return nil
+}
+
+type decoder struct {
+	buf   []byte
+	pos   int
+	order binary.ByteOrder
+}
+
+var errShort = errors.New("dbus: message truncated")
+
+func (d *decoder) align(n int) error {
+	for d.pos%n != 0 {
+		d.pos++
+	}
+	if d.pos > len(d.buf) {
+		return errShort
+	}
+	return nil
+}
+
+func (d *decoder) take(n int) ([]byte, error) {
+	if d.pos+n > len(d.buf) {
+		return nil, errShort
+	}
+	b := d.buf[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+func (d *decoder) u32() (uint32, error) {
+	if err := d.align(4); err != nil {
+		return 0, err
+	}
+	b, err := d.take(4)
+	if err != nil {
+		return 0, err
+	}
+	return d.order.Uint32(b), nil
+}
+
+func (d *decoder) str() (string, error) {
+	n, err := d.u32()
+	if err != nil {
+		return "", err
+	}
+	b, err := d.take(int(n) + 1)
+	if err != nil {
+		return "", err
+	}
+	return string(b[:n]), nil
+}
+
+func (d *decoder) sig() (string, error) {
+	b, err := d.take(1)
+	if err != nil {
+		return "", err
+	}
+	s, err := d.take(int(b[0]) + 1)
+	if err != nil {
+		return "", err
+	}
+	return string(s[:b[0]]), nil
+}
+
+func (d *decoder) decodeAll(sig string) ([]any, error) {
+	types, err := splitAll(sig)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]any, 0, len(types))
+	for _, t := range types {
+		v, err := d.decode(t)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+func (d *decoder) decode(t string) (any, error) {
+	switch t[0] {
+	case 'y':
+		b, err := d.take(1)
+		if err != nil {
+			return nil, err
+		}
+		return b[0], nil
+	case 'b':
+		n, err := d.u32()
+		return n != 0, err
+	case 'n', 'q':
+		if err := d.align(2); err != nil {
+			return nil, err
+		}
+		b, err := d.take(2)
+		if err != nil {
+			return nil, err
+		}
+		if t[0] == 'n' {
+			return int16(d.order.Uint16(b)), nil
+		}
+		return d.order.Uint16(b), nil
+	case 'i':
+		n, err := d.u32()
+		return int32(n), err
+	case 'u', 'h':
+		return d.u32()
+	case 'x', 't', 'd':
+		if err := d.align(8); err != nil {
+			return nil, err
+		}
+		b, err := d.take(8)
+		if err != nil {
+			return nil, err
+		}
+		n := d.order.Uint64(b)
+		switch t[0] {
+		case 'x':
+			return int64(n), nil
+		case 't':
+			return n, nil
+		default:
+			return math.Float64frombits(n), nil
+		}
+	case 's':
+		return d.str()
+	case 'o':
+		s, err := d.str()
+		return ObjectPath(s), err
+	case 'g':
+		s, err := d.sig()
+		return Signature(s), err
+	case 'v':
+		s, err := d.sig()
+		if err != nil {
+			return nil, err
+		}
+		if _, rest, err := splitSig(s); err != nil || rest != "" {
+			return nil, fmt.Errorf("dbus: bad variant signature %q", s)
+		}
+		v, err := d.decode(s)
+		return Variant{Sig: Signature(s), Value: v}, err
+	case '(':
+		if err := d.align(8); err != nil {
+			return nil, err
+		}
+		return d.decodeAll(t[1 : len(t)-1])
+	case 'a':
+		return d.decodeArray(t[1:])
+	}
+	return nil, fmt.Errorf("dbus: unsupported type %q", t)
+}
+
+func (d *decoder) decodeArray(elem string) (any, error) {
+	n, err := d.u32()
+	if err != nil {
+		return nil, err
+	}
+	if err := d.align(alignOf(elem[0])); err != nil {
+		return nil, err
+	}
+	end := d.pos + int(n)
+	if end > len(d.buf) {
+		return nil, errShort
+	}
+
+	if elem[0] == '{' {
+		kt, vt, _ := splitSig(elem[1 : len(elem)-1])
+		var sm map[string]any
+		var om map[ObjectPath]any
+		var am map[any]any
+		switch kt {
+		case "s":
+			sm = map[string]any{}
+		case "o":
+			om = map[ObjectPath]any{}
+		default:
+			am = map[any]any{}
+		}
+		for d.pos < end {
+			if err := d.align(8); err != nil {
+				return nil, err
+			}
+			k, err := d.decode(kt)
+			if err != nil {
+				return nil, err
+			}
+			v, err := d.decode(vt)
+			if err != nil {
+				return nil, err
+			}
+			switch {
+			case sm != nil:
+				sm[k.(string)] = v
+			case om != nil:
+				om[k.(ObjectPath)] = v
+			default:
+				am[k] = v
+			}
+		}
+		if vt == "v" && sm != nil {
+			out := make(map[string]Variant, len(sm))
+			for k, v := range sm {
+				out[k] = v.(Variant)
+			}
+			return out, nil
+		}
+		switch {
+		case sm != nil:
+			return sm, nil
+		case om != nil:
+			return om, nil
+		}
+		return am, nil
+	}
+
+	if elem == "y" {
+		b, err := d.take(int(n))
+		return append([]byte(nil), b...), err
+	}
+	var items []any
+	for d.pos < end {
+		v, err := d.decode(elem)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, v)
+	}
+	switch elem {
+	case "s":
+		out := make([]string, len(items))
+		for i, v := range items {
+			out[i] = v.(string)
+		}
+		return out, nil
+	case "o":
+		out := make([]ObjectPath, len(items))
+		for i, v := range items {
+			out[i] = v.(ObjectPath)
+		}
+		return out, nil
+	}
+	return items, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,191 @@
+package dbus
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+type MessageType byte
+
+const (
+	TypeMethodCall   MessageType = 1
+	TypeMethodReturn MessageType = 2
+	TypeError        MessageType = 3
+	TypeSignal       MessageType = 4
+)
+
+const flagNoReplyExpected = 0x1
+
+// Header field codes.
+const (
+	fieldPath        = 1
+	fieldInterface   = 2
+	fieldMember      = 3
+	fieldErrorName   = 4
+	fieldReplySerial = 5
+	fieldDestination = 6
+	fieldSender      = 7
+	fieldSignature   = 8
+)
+
+// Messages larger than this are rejected (the spec maximum is 128 MiB; we
+// never need anything close).
+const maxMessageSize = 8 << 20
+
+type Message struct {
+	Type   MessageType
+	Flags  byte
+	Serial uint32
+
+	Path        ObjectPath
+	Interface   string
+	Member      string
+	ErrorName   string
+	ReplySerial uint32
+	Destination string
+	Sender      string
+	Signature   Signature
+
+	Body []any
+}
+
+// NoReplyExpected reports whether the caller asked not to get a reply.
+func (m *Message) NoReplyExpected() bool { return m.Flags&flagNoReplyExpected != 0 }
+
+func (m *Message) marshal() ([]byte, error) {
+	body := &encoder{}
+	if m.Signature != "" {
+		if err := body.encodeAll(string(m.Signature), m.Body); err != nil {
+			return nil, err
+		}
+	}
+
+	var fields []any
+	add := func(code byte, v Variant) {
+		fields = append(fields, []any{code, v})
+	}
+	if m.Path != "" {
+		add(fieldPath, Variant{Sig: "o", Value: m.Path})
+	}
+	if m.Interface != "" {
+		add(fieldInterface, Variant{Sig: "s", Value: m.Interface})
+	}
+	if m.Member != "" {
+		add(fieldMember, Variant{Sig: "s", Value: m.Member})
+	}
+	if m.ErrorName != "" {
+		add(fieldErrorName, Variant{Sig: "s", Value: m.ErrorName})
+	}
+	if m.ReplySerial != 0 {
+		add(fieldReplySerial, Variant{Sig: "u", Value: m.ReplySerial})
+	}
+	if m.Destination != "" {
+		add(fieldDestination, Variant{Sig: "s", Value: m.Destination})
+	}
+	if m.Signature != "" {
+		add(fieldSignature, Variant{Sig: "g", Value: m.Signature})
+	}
+
+	h := &encoder{}
+	h.buf = append(h.buf, 'l', byte(m.Type), m.Flags, 1)
+	h.u32(uint32(len(body.buf)))
+	h.u32(m.Serial)
+	if err := h.encode("a(yv)", fields); err != nil {
+		return nil, err
+	}
+	h.align(8)
+	return append(h.buf, body.buf...), nil
+}
+
+func readMessage(r io.Reader) (*Message, error) {
+	fixed := make([]byte, 16)
+	if _, err := io.ReadFull(r, fixed); err != nil {
+		return nil, err
+	}
+	var order binary.ByteOrder
+	switch fixed[0] {
+	case 'l':
+		order = binary.LittleEndian
+	case 'B':
+		order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("dbus: bad endianness byte %q", fixed[0])
+	}
+	bodyLen := order.Uint32(fixed[4:8])
+	fieldsLen := order.Uint32(fixed[12:16])
+	headerLen := 16 + int(fieldsLen)
+	pad := (8 - headerLen%8) % 8
+	total := headerLen + pad + int(bodyLen)
+	if total > maxMessageSize {
+		return nil, errors.New("dbus: message too large")
+	}
+	buf := make([]byte, total)
+	copy(buf, fixed)
+	if _, err := io.ReadFull(r, buf[16:]); err != nil {
+		return nil, err
+	}
+
+	m := &Message{Type: MessageType(fixed[1]), Flags: fixed[2], Serial: order.Uint32(fixed[8:12])}
+	d := &decoder{buf: buf[:headerLen], pos: 12, order: order}
+	fv, err := d.decode("a(yv)")
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range fv.([]any) {
+		st := f.([]any)
+		code := st[0].(byte)
+		v := st[1].(Variant).Value
+		switch code {
+		case fieldPath:
+			m.Path, _ = v.(ObjectPath)
+		case fieldInterface:
+			m.Interface, _ = v.(string)
+		case fieldMember:
+			m.Member, _ = v.(string)
+		case fieldErrorName:
+			m.ErrorName, _ = v.(string)
+		case fieldReplySerial:
+			m.ReplySerial, _ = v.(uint32)
+		case fieldDestination:
+			m.Destination, _ = v.(string)
+		case fieldSender:
+			m.Sender, _ = v.(string)
+		case fieldSignature:
+			m.Signature, _ = v.(Signature)
+		}
+	}
+
+	if m.Signature != "" {
+		bd := &decoder{buf: buf[headerLen+pad:], order: order}
+		m.Body, err = bd.decodeAll(string(m.Signature))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// Error is a D-Bus error reply.
+type Error struct {
+	Name    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return e.Name
+	}
+	return e.Name + ": " + e.Message
+}
+
+// Well-known error names.
+const (
+	ErrUnknownMethod    = "org.freedesktop.DBus.Error.UnknownMethod"
+	ErrUnknownInterface = "org.freedesktop.DBus.Error.UnknownInterface"
+	ErrUnknownProperty  = "org.freedesktop.DBus.Error.UnknownProperty"
+	ErrPropertyReadOnly = "org.freedesktop.DBus.Error.PropertyReadOnly"
+	ErrInvalidArgs      = "org.freedesktop.DBus.Error.InvalidArgs"
+	ErrFailed           = "org.freedesktop.DBus.Error.Failed"
+)
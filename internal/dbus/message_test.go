@@ -0,0 +1,66 @@
+package dbus
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func TestMessageRoundTrip(t *testing.T) {
+	in := &Message{
+		Type:      TypeSignal,
+		Serial:    7,
+		Path:      "/org/mpris/MediaPlayer2",
+		Interface: "org.freedesktop.DBus.Properties",
+		Member:    "PropertiesChanged",
+		Signature: "sa{sv}as",
+		Body: []any{
+			"org.mpris.MediaPlayer2.Player",
+			map[string]Variant{
+				"PlaybackStatus": MakeVariant("Playing"),
+				"Volume":         MakeVariant(0.5),
+				"Metadata": MakeVariant(map[string]Variant{
+					"mpris:trackid": MakeVariant(ObjectPath("/t/1")),
+					"mpris:length":  MakeVariant(int64(222000000)),
+					"xesam:artist":  MakeVariant([]string{"The Killers"}),
+				}),
+			},
+			[]string{"Position"},
+		},
+	}
+	b, err := in.marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := readMessage(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Member != in.Member || out.Path != in.Path || out.Signature != in.Signature || out.Serial != 7 {
+		t.Fatalf("header=%+v", out)
+	}
+	if !reflect.DeepEqual(out.Body, in.Body) {
+		t.Fatalf("body=%#v", out.Body)
+	}
+}
+
+func TestStructArray(t *testing.T) {
+	// Secret Service GetSecrets shape: a{o(oayays)}
+	in := map[ObjectPath]any{
+		"/item/1": []any{ObjectPath("/session/1"), []byte{}, []byte("s3cret"), "text/plain"},
+	}
+	e := &encoder{}
+	if err := e.encode("a{o(oayays)}", in); err != nil {
+		t.Fatal(err)
+	}
+	d := &decoder{buf: e.buf, order: binary.LittleEndian}
+	v, err := d.decode("a{o(oayays)}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := v.(map[ObjectPath]any)["/item/1"].([]any)
+	if string(got[2].([]byte)) != "s3cret" {
+		t.Fatalf("got=%#v", got)
+	}
+}
@@ -0,0 +1,527 @@
+// Package mpris exposes Spotify Connect playback on the session bus as an
+// MPRIS2 player, so media keys, playerctl and desktop widgets can control
+// whatever device Spotify is playing on.
+package mpris
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joshp123/spotctl/internal/dbus"
+	"github.com/joshp123/spotctl/internal/spotify"
+)
+
+const (
+	BusName = "org.mpris.MediaPlayer2.spotctl"
+	Path    = dbus.ObjectPath("/org/mpris/MediaPlayer2")
+
+	ifaceRoot   = "org.mpris.MediaPlayer2"
+	ifacePlayer = "org.mpris.MediaPlayer2.Player"
+	ifaceProps  = "org.freedesktop.DBus.Properties"
+	ifaceIntro  = "org.freedesktop.DBus.Introspectable"
+	ifacePeer   = "org.freedesktop.DBus.Peer"
+
+	noTrack = dbus.ObjectPath("/org/mpris/MediaPlayer2/TrackList/NoTrack")
+)
+
+// Player is the subset of *spotify.Client the bridge needs. Commands go to
+// the active device (nil device id).
+type Player interface {
+	PlaybackState(ctx context.Context) (*spotify.PlaybackState, error)
+	Play(ctx context.Context, deviceID string, req spotify.PlayRequest) error
+	Resume(ctx context.Context, deviceID *string) error
+	Pause(ctx context.Context, deviceID *string) error
+	Next(ctx context.Context, deviceID *string) error
+	Previous(ctx context.Context, deviceID *string) error
+	Seek(ctx context.Context, deviceID *string, positionMs int) error
+	Volume(ctx context.Context, deviceID *string, pct int) error
+	SetShuffle(ctx context.Context, deviceID *string, on bool) error
+	SetRepeat(ctx context.Context, deviceID *string, state string) error
+}
+
+type Options struct {
+	// Interval between playback polls (default 2s). Changes made through
+	// MPRIS refresh immediately.
+	Interval time.Duration
+	// Logf reports poll errors; nil discards them.
+	Logf func(format string, args ...any)
+	Now  func() time.Time
+}
+
+type server struct {
+	ctx  context.Context
+	conn *dbus.Conn
+	p    Player
+	opt  Options
+
+	mu    sync.Mutex
+	st    *spotify.PlaybackState
+	stAt  time.Time
+	props map[string]dbus.Variant
+}
+
+// Serve exports the player on conn, claims BusName and keeps the exported
+// properties in sync with Spotify until ctx is done or the bus connection
+// drops.
+func Serve(ctx context.Context, conn *dbus.Conn, p Player, opt Options) error {
+	if opt.Interval <= 0 {
+		opt.Interval = 2 * time.Second
+	}
+	if opt.Now == nil {
+		opt.Now = time.Now
+	}
+	if opt.Logf == nil {
+		opt.Logf = func(string, ...any) {}
+	}
+	s := &server{ctx: ctx, conn: conn, p: p, opt: opt}
+	s.refresh()
+	conn.HandleCalls(s.handle)
+	if err := conn.RequestName(ctx, BusName); err != nil {
+		return err
+	}
+
+	t := time.NewTicker(opt.Interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-conn.Done():
+			return errors.New("mpris: session bus connection closed")
+		case <-t.C:
+			s.refresh()
+		}
+	}
+}
+
+// refresh polls playback and emits PropertiesChanged/Seeked for whatever
+// moved since the last poll.
+func (s *server) refresh() {
+	st, err := s.p.PlaybackState(s.ctx)
+	if err != nil {
+		if s.ctx.Err() == nil {
+			s.opt.Logf("WARN: %v", err)
+		}
+		return
+	}
+	now := s.opt.Now()
+
+	s.mu.Lock()
+	expected := s.positionLocked(now)
+	prevTrack := trackID(s.st)
+	s.st, s.stAt = st, now
+	next := playerProps(st, s.positionLocked(now))
+	prev := s.props
+	s.props = next
+	s.mu.Unlock()
+
+	if prev == nil {
+		return
+	}
+	changed := map[string]dbus.Variant{}
+	for k, v := range next {
+		if k == "Position" {
+			continue
+		}
+		if !reflect.DeepEqual(prev[k], v) {
+			changed[k] = v
+		}
+	}
+	if len(changed) > 0 {
+		_ = s.conn.Emit(Path, ifaceProps, "PropertiesChanged", "sa{sv}as", ifacePlayer, changed, []string{})
+	}
+	// Position is not tracked by PropertiesChanged; clients extrapolate it and
+	// expect Seeked when it jumps within the same track.
+	pos := next["Position"].Value.(int64)
+	if st != nil && trackID(st) == prevTrack && absDiff(pos, expected) > int64(3*time.Second/time.Microsecond) {
+		_ = s.conn.Emit(Path, ifacePlayer, "Seeked", "x", pos)
+	}
+}
+
+// positionLocked extrapolates the playback position (µs) from the last poll.
+func (s *server) positionLocked(now time.Time) int64 {
+	if s.st == nil {
+		return 0
+	}
+	pos := int64(s.st.ProgressMs) * 1000
+	if s.st.IsPlaying {
+		pos += now.Sub(s.stAt).Microseconds()
+	}
+	if d := int64(s.st.Item.DurationMs) * 1000; d > 0 && pos > d {
+		pos = d
+	}
+	return pos
+}
+
+func absDiff(a, b int64) int64 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+func trackID(st *spotify.PlaybackState) dbus.ObjectPath {
+	if st == nil || st.Item.ID == "" {
+		return noTrack
+	}
+	return dbus.ObjectPath("/org/mpris/MediaPlayer2/Track/" + st.Item.ID)
+}
+
+func rootProps() map[string]dbus.Variant {
+	return map[string]dbus.Variant{
+		"CanQuit":             dbus.MakeVariant(false),
+		"CanRaise":            dbus.MakeVariant(false),
+		"HasTrackList":        dbus.MakeVariant(false),
+		"Identity":            dbus.MakeVariant("spotctl"),
+		"SupportedUriSchemes": dbus.MakeVariant([]string{"spotify", "https"}),
+		"SupportedMimeTypes":  dbus.MakeVariant([]string{}),
+	}
+}
+
+func playerProps(st *spotify.PlaybackState, pos int64) map[string]dbus.Variant {
+	status, loop, shuffle, volume := "Stopped", "None", false, 0.0
+	if st != nil {
+		status = "Paused"
+		if st.IsPlaying {
+			status = "Playing"
+		}
+		switch st.RepeatState {
+		case "track":
+			loop = "Track"
+		case "context":
+			loop = "Playlist"
+		}
+		shuffle = st.Shuffle
+		volume = float64(st.Device.VolumePercent) / 100
+	}
+	active := st != nil
+	return map[string]dbus.Variant{
+		"PlaybackStatus": dbus.MakeVariant(status),
+		"LoopStatus":     dbus.MakeVariant(loop),
+		"Rate":           dbus.MakeVariant(1.0),
+		"MinimumRate":    dbus.MakeVariant(1.0),
+		"MaximumRate":    dbus.MakeVariant(1.0),
+		"Shuffle":        dbus.MakeVariant(shuffle),
+		"Metadata":       dbus.MakeVariant(metadata(st)),
+		"Volume":         dbus.MakeVariant(volume),
+		"Position":       dbus.MakeVariant(pos),
+		"CanGoNext":      dbus.MakeVariant(active),
+		"CanGoPrevious":  dbus.MakeVariant(active),
+		"CanPlay":        dbus.MakeVariant(active),
+		"CanPause":       dbus.MakeVariant(active),
+		"CanSeek":        dbus.MakeVariant(active && st.Item.DurationMs > 0),
+		"CanControl":     dbus.MakeVariant(true),
+	}
+}
+
+func metadata(st *spotify.PlaybackState) map[string]dbus.Variant {
+	m := map[string]dbus.Variant{"mpris:trackid": dbus.MakeVariant(trackID(st))}
+	if st == nil || st.Item.URI == "" {
+		return m
+	}
+	it := st.Item
+	m["mpris:length"] = dbus.MakeVariant(int64(it.DurationMs) * 1000)
+	m["xesam:title"] = dbus.MakeVariant(it.Name)
+	m["xesam:album"] = dbus.MakeVariant(it.Album.Name)
+	artists := make([]string, 0, len(it.Artists))
+	for _, a := range it.Artists {
+		artists = append(artists, a.Name)
+	}
+	m["xesam:artist"] = dbus.MakeVariant(artists)
+	if kind, id, ok := strings.Cut(strings.TrimPrefix(it.URI, "spotify:"), ":"); ok {
+		m["xesam:url"] = dbus.MakeVariant("https://open.spotify.com/" + kind + "/" + id)
+	}
+	// Spotify lists album art widest first.
+	if len(it.Album.Images) > 0 {
+		m["mpris:artUrl"] = dbus.MakeVariant(it.Album.Images[0].URL)
+	}
+	return m
+}
+
+func (s *server) snapshot(iface string) (map[string]dbus.Variant, bool) {
+	switch iface {
+	case ifaceRoot:
+		return rootProps(), true
+	case ifacePlayer:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return playerProps(s.st, s.positionLocked(s.opt.Now())), true
+	}
+	return nil, false
+}
+
+func (s *server) handle(c *dbus.Conn, call *dbus.Message) {
+	if call.Path != Path {
+		_ = c.ReplyError(call, dbus.ErrUnknownMethod, fmt.Sprintf("no object at %s", call.Path))
+		return
+	}
+	var err error
+	switch call.Interface {
+	case ifaceProps:
+		err = s.handleProps(c, call)
+	case ifaceIntro:
+		if call.Member != "Introspect" {
+			err = &dbus.Error{Name: dbus.ErrUnknownMethod, Message: call.Member}
+			break
+		}
+		err = c.Reply(call, "s", introspectXML)
+	case ifacePeer:
+		if call.Member != "Ping" {
+			err = &dbus.Error{Name: dbus.ErrUnknownMethod, Message: call.Member}
+			break
+		}
+		err = c.Reply(call, "")
+	case ifaceRoot:
+		// Raise and Quit are no-ops: CanRaise/CanQuit are false.
+		if call.Member != "Raise" && call.Member != "Quit" {
+			err = &dbus.Error{Name: dbus.ErrUnknownMethod, Message: call.Member}
+			break
+		}
+		err = c.Reply(call, "")
+	case ifacePlayer, "":
+		err = s.handlePlayer(call)
+		if err == nil {
+			err = c.Reply(call, "")
+		}
+	default:
+		err = &dbus.Error{Name: dbus.ErrUnknownInterface, Message: call.Interface}
+	}
+	if err != nil {
+		var de *dbus.Error
+		if !errors.As(err, &de) {
+			de = &dbus.Error{Name: dbus.ErrFailed, Message: err.Error()}
+		}
+		_ = c.ReplyError(call, de.Name, de.Message)
+	}
+}
+
+func (s *server) handleProps(c *dbus.Conn, call *dbus.Message) error {
+	iface, _ := arg[string](call, 0)
+	props, ok := s.snapshot(iface)
+	if !ok {
+		return &dbus.Error{Name: dbus.ErrUnknownInterface, Message: iface}
+	}
+	switch call.Member {
+	case "GetAll":
+		return c.Reply(call, "a{sv}", props)
+	case "Get":
+		name, _ := arg[string](call, 1)
+		v, ok := props[name]
+		if !ok {
+			return &dbus.Error{Name: dbus.ErrUnknownProperty, Message: name}
+		}
+		return c.Reply(call, "v", v)
+	case "Set":
+		name, _ := arg[string](call, 1)
+		v, _ := arg[dbus.Variant](call, 2)
+		if err := s.setProp(iface, name, v.Value); err != nil {
+			return err
+		}
+		s.refresh()
+		return c.Reply(call, "")
+	}
+	return &dbus.Error{Name: dbus.ErrUnknownMethod, Message: call.Member}
+}
+
+func (s *server) setProp(iface, name string, v any) error {
+	if iface != ifacePlayer {
+		return &dbus.Error{Name: dbus.ErrPropertyReadOnly, Message: name}
+	}
+	invalid := &dbus.Error{Name: dbus.ErrInvalidArgs, Message: fmt.Sprintf("bad value for %s: %v", name, v)}
+	switch name {
+	case "Volume":
+		f, ok := v.(float64)
+		if !ok {
+			return invalid
+		}
+		pct := int(f*100 + 0.5)
+		return s.p.Volume(s.ctx, nil, max(0, min(100, pct)))
+	case "Shuffle":
+		b, ok := v.(bool)
+		if !ok {
+			return invalid
+		}
+		return s.p.SetShuffle(s.ctx, nil, b)
+	case "LoopStatus":
+		l, _ := v.(string)
+		state, ok := map[string]string{"None": "off", "Track": "track", "Playlist": "context"}[l]
+		if !ok {
+			return invalid
+		}
+		return s.p.SetRepeat(s.ctx, nil, state)
+	case "Rate":
+		// Spotify has no playback rate; 1.0 is the only supported value.
+		if f, ok := v.(float64); !ok || f != 1 {
+			return invalid
+		}
+		return nil
+	}
+	return &dbus.Error{Name: dbus.ErrPropertyReadOnly, Message: name}
+}
+
+func (s *server) handlePlayer(call *dbus.Message) error {
+	var err error
+	switch call.Member {
+	case "Play":
+		err = s.p.Resume(s.ctx, nil)
+	case "Pause", "Stop":
+		err = s.p.Pause(s.ctx, nil)
+	case "PlayPause":
+		s.mu.Lock()
+		playing := s.st != nil && s.st.IsPlaying
+		s.mu.Unlock()
+		if playing {
+			err = s.p.Pause(s.ctx, nil)
+		} else {
+			err = s.p.Resume(s.ctx, nil)
+		}
+	case "Next":
+		err = s.p.Next(s.ctx, nil)
+	case "Previous":
+		err = s.p.Previous(s.ctx, nil)
+	case "Seek":
+		off, ok := arg[int64](call, 0)
+		if !ok {
+			return &dbus.Error{Name: dbus.ErrInvalidArgs, Message: "Seek wants (x)"}
+		}
+		s.mu.Lock()
+		pos := s.positionLocked(s.opt.Now()) + off
+		s.mu.Unlock()
+		err = s.p.Seek(s.ctx, nil, int(max(0, pos)/1000))
+	case "SetPosition":
+		id, _ := arg[dbus.ObjectPath](call, 0)
+		pos, ok := arg[int64](call, 1)
+		if !ok {
+			return &dbus.Error{Name: dbus.ErrInvalidArgs, Message: "SetPosition wants (ox)"}
+		}
+		s.mu.Lock()
+		current := trackID(s.st)
+		s.mu.Unlock()
+		// Per spec, stale track ids and negative positions are ignored.
+		if id != current || pos < 0 {
+			return nil
+		}
+		err = s.p.Seek(s.ctx, nil, int(pos/1000))
+	case "OpenUri":
+		raw, _ := arg[string](call, 0)
+		err = s.openURI(raw)
+	default:
+		return &dbus.Error{Name: dbus.ErrUnknownMethod, Message: call.Member}
+	}
+	if err != nil {
+		return err
+	}
+	s.refresh()
+	return nil
+}
+
+func (s *server) openURI(raw string) error {
+	uri, kind, err := spotify.NormalizeURI(raw)
+	if err == nil && kind == spotify.URIKindUnknown {
+		err = fmt.Errorf("not a Spotify URI: %s", raw)
+	}
+	if err != nil {
+		return &dbus.Error{Name: dbus.ErrInvalidArgs, Message: err.Error()}
+	}
+	req, err := spotify.PlayRequestFor(uri, kind)
+	if err != nil {
+		return &dbus.Error{Name: dbus.ErrInvalidArgs, Message: err.Error()}
+	}
+	s.mu.Lock()
+	var deviceID string
+	if s.st != nil {
+		deviceID = s.st.Device.ID
+	}
+	s.mu.Unlock()
+	if deviceID == "" {
+		return errors.New("no active Spotify device")
+	}
+	return s.p.Play(s.ctx, deviceID, req)
+}
+
+func arg[T any](call *dbus.Message, i int) (T, bool) {
+	var zero T
+	if i >= len(call.Body) {
+		return zero, false
+	}
+	v, ok := call.Body[i].(T)
+	return v, ok
+}
+
+const introspectXML = `<!DOCTYPE node PUBLIC "-//freedesktop//DTD D-BUS Object Introspection 1.0//EN"
+ "http://www.freedesktop.org/standards/dbus/1.0/introspect.dtd">
+<node>
+  <interface name="org.freedesktop.DBus.Introspectable">
+    <method name="Introspect"><arg name="xml" type="s" direction="out"/></method>
+  </interface>
+  <interface name="org.freedesktop.DBus.Peer">
+    <method name="Ping"/>
+  </interface>
+  <interface name="org.freedesktop.DBus.Properties">
+    <method name="Get">
+      <arg name="interface" type="s" direction="in"/>
+      <arg name="property" type="s" direction="in"/>
+      <arg name="value" type="v" direction="out"/>
+    </method>
+    <method name="GetAll">
+      <arg name="interface" type="s" direction="in"/>
+      <arg name="properties" type="a{sv}" direction="out"/>
+    </method>
+    <method name="Set">
+      <arg name="interface" type="s" direction="in"/>
+      <arg name="property" type="s" direction="in"/>
+      <arg name="value" type="v" direction="in"/>
+    </method>
+    <signal name="PropertiesChanged">
+      <arg name="interface" type="s"/>
+      <arg name="changed" type="a{sv}"/>
+      <arg name="invalidated" type="as"/>
+    </signal>
+  </interface>
+  <interface name="org.mpris.MediaPlayer2">
+    <method name="Raise"/>
+    <method name="Quit"/>
+    <property name="CanQuit" type="b" access="read"/>
+    <property name="CanRaise" type="b" access="read"/>
+    <property name="HasTrackList" type="b" access="read"/>
+    <property name="Identity" type="s" access="read"/>
+    <property name="SupportedUriSchemes" type="as" access="read"/>
+    <property name="SupportedMimeTypes" type="as" access="read"/>
+  </interface>
+  <interface name="org.mpris.MediaPlayer2.Player">
+    <method name="Next"/>
+    <method name="Previous"/>
+    <method name="Pause"/>
+    <method name="PlayPause"/>
+    <method name="Stop"/>
+    <method name="Play"/>
+    <method name="Seek"><arg name="Offset" type="x" direction="in"/></method>
+    <method name="SetPosition">
+      <arg name="TrackId" type="o" direction="in"/>
+      <arg name="Position" type="x" direction="in"/>
+    </method>
+    <method name="OpenUri"><arg name="Uri" type="s" direction="in"/></method>
+    <signal name="Seeked"><arg name="Position" type="x"/></signal>
+    <property name="PlaybackStatus" type="s" access="read"/>
+    <property name="LoopStatus" type="s" access="readwrite"/>
+    <property name="Rate" type="d" access="readwrite"/>
+    <property name="Shuffle" type="b" access="readwrite"/>
+    <property name="Metadata" type="a{sv}" access="read"/>
+    <property name="Volume" type="d" access="readwrite"/>
+    <property name="Position" type="x" access="read"/>
+    <property name="MinimumRate" type="d" access="read"/>
+    <property name="MaximumRate" type="d" access="read"/>
+    <property name="CanGoNext" type="b" access="read"/>
+    <property name="CanGoPrevious" type="b" access="read"/>
+    <property name="CanPlay" type="b" access="read"/>
+    <property name="CanPause" type="b" access="read"/>
+    <property name="CanSeek" type="b" access="read"/>
+    <property name="CanControl" type="b" access="read"/>
+  </interface>
+</node>
+`
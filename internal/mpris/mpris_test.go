@@ -0,0 +1,148 @@
+package mpris
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/joshp123/spotctl/internal/dbus"
+	"github.com/joshp123/spotctl/internal/dbus/dbustest"
+	"github.com/joshp123/spotctl/internal/spotify"
+)
+
+type fakePlayer struct {
+	mu    sync.Mutex
+	st    spotify.PlaybackState
+	calls []string
+}
+
+func (f *fakePlayer) record(s string) {
+	f.mu.Lock()
+	f.calls = append(f.calls, s)
+	f.mu.Unlock()
+}
+
+func (f *fakePlayer) PlaybackState(context.Context) (*spotify.PlaybackState, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	st := f.st
+	return &st, nil
+}
+
+func (f *fakePlayer) Play(_ context.Context, id string, req spotify.PlayRequest) error {
+	f.record("play " + id + " " + req.ContextURI + strings.Join(req.URIs, ","))
+	return nil
+}
+
+func (f *fakePlayer) Resume(context.Context, *string) error {
+	f.record("resume")
+	f.mu.Lock()
+	f.st.IsPlaying = true
+	f.mu.Unlock()
+	return nil
+}
+
+func (f *fakePlayer) Pause(context.Context, *string) error {
+	f.record("pause")
+	f.mu.Lock()
+	f.st.IsPlaying = false
+	f.mu.Unlock()
+	return nil
+}
+
+func (f *fakePlayer) Next(context.Context, *string) error     { f.record("next"); return nil }
+func (f *fakePlayer) Previous(context.Context, *string) error { f.record("previous"); return nil }
+func (f *fakePlayer) Seek(context.Context, *string, int) error {
+	f.record("seek")
+	return nil
+}
+func (f *fakePlayer) Volume(context.Context, *string, int) error {
+	f.record("volume")
+	return nil
+}
+func (f *fakePlayer) SetShuffle(context.Context, *string, bool) error {
+	f.record("shuffle")
+	return nil
+}
+func (f *fakePlayer) SetRepeat(_ context.Context, _ *string, state string) error {
+	f.record("repeat " + state)
+	return nil
+}
+
+func TestServeOverBus(t *testing.T) {
+	addr := dbustest.PrivateBus(t)
+
+	fp := &fakePlayer{st: spotify.PlaybackState{
+		Device:     spotify.Device{ID: "dev1", Name: "Kitchen", VolumePercent: 40},
+		ProgressMs: 1000,
+		Item: spotify.Track{
+			ID: "4uLU6hMCjMI75M1A2tKUQC", Name: "Mr. Brightside", URI: "spotify:track:4uLU6hMCjMI75M1A2tKUQC",
+			DurationMs: 222000, Artists: []spotify.Artist{{Name: "The Killers"}},
+		},
+	}}
+
+	srvConn, err := dbus.Dial(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srvConn.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = Serve(ctx, srvConn, fp, Options{Interval: time.Hour}) }()
+
+	cl, err := dbus.Dial(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cl.Close()
+
+	get := func(name string) any {
+		t.Helper()
+		var reply *dbus.Message
+		// Serve claims the name asynchronously.
+		for i := 0; i < 50; i++ {
+			reply, err = cl.Call(ctx, BusName, Path, ifaceProps, "Get", "ss", ifacePlayer, name)
+			if err == nil {
+				break
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		if err != nil {
+			t.Fatalf("Get %s: %v", name, err)
+		}
+		return reply.Body[0].(dbus.Variant).Value
+	}
+
+	if got := get("PlaybackStatus"); got != "Paused" {
+		t.Fatalf("PlaybackStatus=%v", got)
+	}
+	if got := get("Volume"); got != 0.4 {
+		t.Fatalf("Volume=%v", got)
+	}
+	md := get("Metadata").(map[string]dbus.Variant)
+	if md["xesam:title"].Value != "Mr. Brightside" || md["mpris:length"].Value != int64(222000000) {
+		t.Fatalf("Metadata=%v", md)
+	}
+
+	if _, err := cl.Call(ctx, BusName, Path, ifacePlayer, "PlayPause", ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := get("PlaybackStatus"); got != "Playing" {
+		t.Fatalf("PlaybackStatus after PlayPause=%v", got)
+	}
+	if _, err := cl.Call(ctx, BusName, Path, ifaceProps, "Set", "ssv", ifacePlayer, "LoopStatus", dbus.MakeVariant("Playlist")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cl.Call(ctx, BusName, Path, ifaceProps, "Set", "ssv", ifacePlayer, "Position", dbus.MakeVariant(int64(0))); err == nil {
+		t.Fatal("expected Position to be read-only")
+	}
+
+	fp.mu.Lock()
+	calls := strings.Join(fp.calls, ";")
+	fp.mu.Unlock()
+	if calls != "resume;repeat context" {
+		t.Fatalf("calls=%q", calls)
+	}
+}
@@ -0,0 +1,164 @@
+// Package rpc is a minimal JSON-RPC 2.0 implementation over newline-delimited
+// JSON streams (Unix sockets, stdio). Just enough for spotctl's daemon and MCP
+// server; no batching.
+package rpc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Standard JSON-RPC error codes.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// IsNotification reports whether the request expects no response.
+func (r *Request) IsNotification() bool { return len(r.ID) == 0 }
+
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+// Handler returns a JSON-marshalable result or an error. Returning an *Error
+// controls the code; any other error becomes CodeInternalError.
+type Handler func(ctx context.Context, method string, params json.RawMessage) (any, error)
+
+// Serve reads requests from r and writes responses to w until r is exhausted
+// or ctx is done. Requests are handled sequentially, in order.
+func Serve(ctx context.Context, r io.Reader, w io.Writer, h Handler) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64<<10), 16<<20)
+	enc := &encoder{w: w}
+	for sc.Scan() {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		line := sc.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var req Request
+		if err := json.Unmarshal(line, &req); err != nil {
+			_ = enc.write(Response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &Error{Code: CodeParseError, Message: err.Error()}})
+			continue
+		}
+		if req.Method == "" {
+			if !req.IsNotification() {
+				_ = enc.write(Response{JSONRPC: "2.0", ID: req.ID, Error: &Error{Code: CodeInvalidRequest, Message: "missing method"}})
+			}
+			continue
+		}
+		res, err := h(ctx, req.Method, req.Params)
+		if req.IsNotification() {
+			continue
+		}
+		resp := Response{JSONRPC: "2.0", ID: req.ID}
+		if err != nil {
+			var re *Error
+			if !errors.As(err, &re) {
+				re = &Error{Code: CodeInternalError, Message: err.Error()}
+			}
+			resp.Error = re
+		} else {
+			b, err := json.Marshal(res)
+			if err != nil {
+				resp.Error = &Error{Code: CodeInternalError, Message: err.Error()}
+			} else {
+				resp.Result = b
+			}
+		}
+		if err := enc.write(resp); err != nil {
+			return err
+		}
+	}
+	return sc.Err()
+}
+
+type encoder struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (e *encoder) write(v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, err = e.w.Write(append(b, '\n'))
+	return err
+}
+
+// Call sends one request on rw and waits for its response. It is meant for
+// short-lived connections carrying a single call.
+func Call(ctx context.Context, rw io.ReadWriter, method string, params any, out any) error {
+	p, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	if err := (&encoder{w: rw}).write(Request{JSONRPC: "2.0", ID: json.RawMessage("1"), Method: method, Params: p}); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	var resp Response
+	go func() {
+		sc := bufio.NewScanner(rw)
+		sc.Buffer(make([]byte, 0, 64<<10), 16<<20)
+		if !sc.Scan() {
+			err := sc.Err()
+			if err == nil {
+				err = io.ErrUnexpectedEOF
+			}
+			done <- err
+			return
+		}
+		done <- json.Unmarshal(sc.Bytes(), &resp)
+	}()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-done:
+		if err != nil {
+			return err
+		}
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if out != nil && len(resp.Result) > 0 {
+		return json.Unmarshal(resp.Result, out)
+	}
+	return nil
+}
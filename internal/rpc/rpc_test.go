@@ -0,0 +1,42 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+)
+
+func TestServeCall(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	ctx := context.Background()
+
+	go func() {
+		defer server.Close()
+		_ = Serve(ctx, server, server, func(ctx context.Context, method string, params json.RawMessage) (any, error) {
+			if method != "echo" {
+				return nil, &Error{Code: CodeMethodNotFound, Message: method}
+			}
+			var p map[string]string
+			if err := json.Unmarshal(params, &p); err != nil {
+				return nil, err
+			}
+			return p, nil
+		})
+	}()
+
+	var out map[string]string
+	if err := Call(ctx, client, "echo", map[string]string{"a": "b"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out["a"] != "b" {
+		t.Fatalf("out=%v", out)
+	}
+
+	err := Call(ctx, client, "nope", nil, nil)
+	re, ok := err.(*Error)
+	if !ok || re.Code != CodeMethodNotFound {
+		t.Fatalf("err=%v", err)
+	}
+}
@@ -0,0 +1,143 @@
+package scrobble
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ListenBrainz submit API (also implemented by Maloja, Koito, multi-scrobbler
+// and other Last.fm-style self-hosted services):
+//
+//	POST {base}/1/submit-listens
+//	Authorization: Token <user token>
+//	{"listen_type":"single","payload":[{"listened_at":..., "track_metadata":{...}}]}
+
+type ListenType string
+
+const (
+	ListenPlayingNow ListenType = "playing_now"
+	ListenSingle     ListenType = "single"
+)
+
+type Listen struct {
+	ListenedAt    int64         `json:"listened_at,omitempty"`
+	TrackMetadata TrackMetadata `json:"track_metadata"`
+}
+
+type TrackMetadata struct {
+	ArtistName     string         `json:"artist_name"`
+	TrackName      string         `json:"track_name"`
+	ReleaseName    string         `json:"release_name,omitempty"`
+	AdditionalInfo AdditionalInfo `json:"additional_info"`
+}
+
+type AdditionalInfo struct {
+	DurationMs       int      `json:"duration_ms,omitempty"`
+	SpotifyID        string   `json:"spotify_id,omitempty"` // open.spotify.com track URL
+	SpotifyArtistIDs []string `json:"spotify_artist_ids,omitempty"`
+	MediaPlayer      string   `json:"media_player,omitempty"`
+	SubmissionClient string   `json:"submission_client,omitempty"`
+	MusicService     string   `json:"music_service,omitempty"`
+}
+
+type ClientOptions struct {
+	HTTP    *http.Client
+	BaseURL string // default https://api.listenbrainz.org
+	Token   string
+}
+
+type Client struct {
+	hc    *http.Client
+	base  string
+	token string
+}
+
+func NewClient(opt ClientOptions) *Client {
+	base := strings.TrimRight(opt.BaseURL, "/")
+	if base == "" {
+		base = "https://api.listenbrainz.org"
+	}
+	hc := opt.HTTP
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	return &Client{hc: hc, base: base, token: opt.Token}
+}
+
+// SubmitError is a non-2xx response from the submit endpoint.
+type SubmitError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *SubmitError) Error() string {
+	return fmt.Sprintf("listenbrainz submit failed (%d): %s", e.StatusCode, e.Message)
+}
+
+// Temporary reports whether resubmitting the same listen later may succeed.
+func (e *SubmitError) Temporary() bool {
+	return e.StatusCode == 429 || e.StatusCode >= 500
+}
+
+// Retryable reports whether a failed submission should be spooled for a later
+// retry (network trouble, rate limiting, server errors) rather than dropped.
+func Retryable(err error) bool {
+	var se *SubmitError
+	if errors.As(err, &se) {
+		return se.Temporary()
+	}
+	var ne net.Error
+	if errors.As(err, &ne) {
+		return true
+	}
+	var oe *net.OpError
+	return errors.As(err, &oe)
+}
+
+func (c *Client) Submit(ctx context.Context, typ ListenType, l Listen) error {
+	if typ == ListenPlayingNow {
+		l.ListenedAt = 0
+	}
+	body, err := json.Marshal(struct {
+		ListenType ListenType `json:"listen_type"`
+		Payload    []Listen   `json:"payload"`
+	}{ListenType: typ, Payload: []Listen{l}})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.base+"/1/submit-listens", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Token "+c.token)
+
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var e struct {
+			Error string `json:"error"`
+		}
+		msg := strings.TrimSpace(string(b))
+		if json.Unmarshal(b, &e) == nil && e.Error != "" {
+			msg = e.Error
+		}
+		if msg == "" {
+			msg = resp.Status
+		}
+		return &SubmitError{StatusCode: resp.StatusCode, Message: msg}
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package scrobble
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/joshp123/spotctl/internal/spotify"
+)
+
+// Don't hammer an unreachable endpoint on every poll.
+const spoolRetryInterval = 30 * time.Second
+
+type Scrobbler struct {
+	client  *Client
+	store   *Store
+	tracker Tracker
+
+	// Logf receives one line per submitted/spooled/dropped listen. Optional.
+	Logf func(format string, args ...any)
+
+	lastFlush time.Time
+}
+
+func New(client *Client, store *Store) *Scrobbler {
+	return &Scrobbler{client: client, store: store}
+}
+
+// Observe feeds one playback poll through the tracker and submits whatever it
+// produces. Only unrecoverable errors (bad token, broken state dir) are
+// returned; transient submit failures go to the spool.
+func (s *Scrobbler) Observe(ctx context.Context, up spotify.PlaybackUpdate) error {
+	if up.Err != nil {
+		return nil
+	}
+	for _, ev := range s.tracker.Observe(up.At, up.State) {
+		l := listenFromEvent(ev)
+		switch ev.Kind {
+		case EventNowPlaying:
+			// Best-effort; playing_now is never spooled.
+			if err := s.client.Submit(ctx, ListenPlayingNow, l); err != nil {
+				if fatal(err) {
+					return err
+				}
+			}
+		case EventListen:
+			if err := s.submit(ctx, up.At, l); err != nil {
+				return err
+			}
+		}
+	}
+	if len(s.store.Pending()) > 0 && up.At.Sub(s.lastFlush) >= spoolRetryInterval {
+		return s.Flush(ctx, up.At)
+	}
+	return nil
+}
+
+// submit sends l, after anything still spooled so listens arrive in order; if
+// the spool can't be drained, l joins the end of it.
+func (s *Scrobbler) submit(ctx context.Context, now time.Time, l Listen) error {
+	if s.store.Seen(l) {
+		s.logf("Duplicate, skipped: %s — %s", l.TrackMetadata.TrackName, l.TrackMetadata.ArtistName)
+		return nil
+	}
+	if len(s.store.Pending()) > 0 {
+		if err := s.Flush(ctx, now); err != nil {
+			return err
+		}
+		if n := len(s.store.Pending()); n > 0 {
+			s.logf("Spooled (behind %d): %s — %s", n, l.TrackMetadata.TrackName, l.TrackMetadata.ArtistName)
+			return s.store.Enqueue(l)
+		}
+	}
+	err := s.client.Submit(ctx, ListenSingle, l)
+	switch {
+	case err == nil:
+		s.logf("Scrobbled: %s — %s", l.TrackMetadata.TrackName, l.TrackMetadata.ArtistName)
+		return s.store.Done(l)
+	case fatal(err):
+		return err
+	case Retryable(err):
+		s.logf("Spooled (%v): %s — %s", err, l.TrackMetadata.TrackName, l.TrackMetadata.ArtistName)
+		return s.store.Enqueue(l)
+	default:
+		s.logf("Dropped (%v): %s — %s", err, l.TrackMetadata.TrackName, l.TrackMetadata.ArtistName)
+		return nil
+	}
+}
+
+// Flush retries spooled listens in order, stopping at the first transient
+// failure so ordering is preserved for the next attempt.
+func (s *Scrobbler) Flush(ctx context.Context, now time.Time) error {
+	s.lastFlush = now
+	for _, l := range s.store.Pending() {
+		err := s.client.Submit(ctx, ListenSingle, l)
+		switch {
+		case err == nil:
+			s.logf("Scrobbled (from spool): %s — %s", l.TrackMetadata.TrackName, l.TrackMetadata.ArtistName)
+			if err := s.store.Done(l); err != nil {
+				return err
+			}
+		case fatal(err):
+			return err
+		case Retryable(err):
+			return nil
+		default:
+			s.logf("Dropped (%v): %s — %s", err, l.TrackMetadata.TrackName, l.TrackMetadata.ArtistName)
+			if err := s.store.Drop(l); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Scrobbler) logf(format string, args ...any) {
+	if s.Logf != nil {
+		s.Logf(format, args...)
+	}
+}
+
+// fatal errors won't go away by retrying: the token is wrong.
+func fatal(err error) bool {
+	var se *SubmitError
+	return errors.As(err, &se) && (se.StatusCode == 401 || se.StatusCode == 403)
+}
+
+func listenFromEvent(ev Event) Listen {
+	t := ev.Track
+	var artistIDs []string
+	for _, a := range t.Artists {
+		if a.ID != "" {
+			artistIDs = append(artistIDs, "https://open.spotify.com/artist/"+a.ID)
+		}
+	}
+	var spotifyID string
+	if t.ID != "" {
+		spotifyID = "https://open.spotify.com/track/" + t.ID
+	}
+	return Listen{
+		ListenedAt: ev.ListenedAt.Unix(),
+		TrackMetadata: TrackMetadata{
+			ArtistName:  t.DisplayArtists(),
+			TrackName:   t.DisplayName(),
+			ReleaseName: t.Album.Name,
+			AdditionalInfo: AdditionalInfo{
+				DurationMs:       t.DurationMs,
+				SpotifyID:        spotifyID,
+				SpotifyArtistIDs: artistIDs,
+				MediaPlayer:      ev.Device,
+				SubmissionClient: "spotctl",
+				MusicService:     "spotify.com",
+			},
+		},
+	}
+}
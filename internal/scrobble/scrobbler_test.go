@@ -0,0 +1,213 @@
+package scrobble
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/joshp123/spotctl/internal/spotify"
+)
+
+type fakeListenBrainz struct {
+	mu    sync.Mutex
+	down  bool
+	types []ListenType
+	names []string // track names of submitted single listens, in order
+	auth  string
+}
+
+func (f *fakeListenBrainz) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.URL.Path != "/1/submit-listens" {
+		w.WriteHeader(404)
+		return
+	}
+	if f.down {
+		w.WriteHeader(503)
+		return
+	}
+	var body struct {
+		ListenType ListenType `json:"listen_type"`
+		Payload    []Listen   `json:"payload"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || len(body.Payload) != 1 {
+		w.WriteHeader(400)
+		return
+	}
+	f.auth = r.Header.Get("Authorization")
+	f.types = append(f.types, body.ListenType)
+	if body.ListenType == ListenSingle {
+		f.names = append(f.names, body.Payload[0].TrackMetadata.TrackName)
+	}
+	w.Write([]byte(`{"status":"ok"}`))
+}
+
+func (f *fakeListenBrainz) count(t ListenType) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, x := range f.types {
+		if x == t {
+			n++
+		}
+	}
+	return n
+}
+
+func playing(progressMs int) *spotify.PlaybackState {
+	return &spotify.PlaybackState{
+		Device:     spotify.Device{ID: "d1", Name: "Desk"},
+		IsPlaying:  true,
+		ProgressMs: progressMs,
+		Item: spotify.Track{
+			ID: "3n3Ppam7vgaVa1iaRUc9Lp", URI: "spotify:track:3n3Ppam7vgaVa1iaRUc9Lp", Type: "track",
+			Name: "Mr. Brightside", DurationMs: 600000,
+			Artists: []spotify.Artist{{Name: "The Killers"}},
+		},
+	}
+}
+
+func TestThreshold(t *testing.T) {
+	if got := Threshold(3 * time.Minute); got != 90*time.Second {
+		t.Fatalf("3m => %s", got)
+	}
+	if got := Threshold(10 * time.Minute); got != 4*time.Minute {
+		t.Fatalf("10m => %s", got)
+	}
+}
+
+func TestTrackerIgnoresSeek(t *testing.T) {
+	var tr Tracker
+	t0 := time.Unix(1_700_000_000, 0)
+	if evs := tr.Observe(t0, playing(0)); len(evs) != 1 || evs[0].Kind != EventNowPlaying {
+		t.Fatalf("events=%v", evs)
+	}
+	// Seek to 3:00 five seconds later: only 5s of listening.
+	if evs := tr.Observe(t0.Add(5*time.Second), playing(180000)); len(evs) != 0 {
+		t.Fatalf("events=%v", evs)
+	}
+}
+
+func TestScrobblerSpoolAndDedup(t *testing.T) {
+	lb := &fakeListenBrainz{down: true}
+	srv := httptest.NewServer(lb)
+	defer srv.Close()
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	newScrobbler := func() *Scrobbler {
+		store, err := OpenStore(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return New(NewClient(ClientOptions{HTTP: srv.Client(), BaseURL: srv.URL, Token: "tok"}), store)
+	}
+
+	s := newScrobbler()
+	t0 := time.Unix(1_700_000_000, 0)
+	for i := 0; i <= 250; i += 10 {
+		at := t0.Add(time.Duration(i) * time.Second)
+		if err := s.Observe(ctx, spotify.PlaybackUpdate{At: at, State: playing(i * 1000)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(s.store.Pending()); n != 1 {
+		t.Fatalf("pending=%d", n)
+	}
+
+	// Restart with the service back up: the spool drains, and re-observing the
+	// same play (a couple of seconds of estimate drift) doesn't submit twice.
+	lb.mu.Lock()
+	lb.down = false
+	lb.mu.Unlock()
+	s = newScrobbler()
+	if err := s.Flush(ctx, t0.Add(255*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	for i := 260; i <= 510; i += 10 {
+		at := t0.Add(time.Duration(i)*time.Second + 2*time.Second)
+		if err := s.Observe(ctx, spotify.PlaybackUpdate{At: at, State: playing(i * 1000)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := lb.count(ListenSingle); n != 1 {
+		t.Fatalf("single listens=%d", n)
+	}
+	if lb.auth != "Token tok" {
+		t.Fatalf("auth=%q", lb.auth)
+	}
+	if n := len(s.store.Pending()); n != 0 {
+		t.Fatalf("pending=%d", n)
+	}
+}
+
+func TestScrobblerFlushesSpoolFirst(t *testing.T) {
+	lb := &fakeListenBrainz{down: true}
+	srv := httptest.NewServer(lb)
+	defer srv.Close()
+	store, err := OpenStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := New(NewClient(ClientOptions{HTTP: srv.Client(), BaseURL: srv.URL, Token: "tok"}), store)
+	ctx := context.Background()
+
+	short := func(progressMs int) *spotify.PlaybackState {
+		st := playing(progressMs)
+		st.Item.ID, st.Item.URI, st.Item.Name, st.Item.DurationMs = "7ouMYWpwJ422jRcDASZB7P", "spotify:track:7ouMYWpwJ422jRcDASZB7P", "Short", 20000
+		return st
+	}
+	t0 := time.Unix(1_700_000_000, 0)
+	for i := 0; i <= 250; i += 10 {
+		if err := s.Observe(ctx, spotify.PlaybackUpdate{At: t0.Add(time.Duration(i) * time.Second), State: playing(i * 1000)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Back up within the retry interval: the next listen must not overtake
+	// the spooled one.
+	lb.mu.Lock()
+	lb.down = false
+	lb.mu.Unlock()
+	for i := 260; i <= 270; i += 10 {
+		if err := s.Observe(ctx, spotify.PlaybackUpdate{At: t0.Add(time.Duration(i) * time.Second), State: short((i - 260) * 1000)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := strings.Join(lb.names, ", "); got != "Mr. Brightside, Short" {
+		t.Fatalf("submitted %q", got)
+	}
+	if n := len(s.store.Pending()); n != 0 {
+		t.Fatalf("pending=%d", n)
+	}
+}
+
+func TestStoreSeenWithoutSpotifyID(t *testing.T) {
+	store, err := OpenStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	local := func(artist, title string) Listen {
+		return Listen{ListenedAt: 1_700_000_000, TrackMetadata: TrackMetadata{ArtistName: artist, TrackName: title}}
+	}
+	if err := store.Done(local("Artist", "One")); err != nil {
+		t.Fatal(err)
+	}
+	if !store.Seen(local("Artist", "One")) {
+		t.Fatal("same local track not seen")
+	}
+	if store.Seen(local("Artist", "Two")) {
+		t.Fatal("different local track treated as seen")
+	}
+	if err := store.Enqueue(local("Other", "Three")); err != nil {
+		t.Fatal(err)
+	}
+	if store.Seen(local("Other", "Four")) {
+		t.Fatal("different pending local track treated as seen")
+	}
+}
@@ -0,0 +1,143 @@
+package scrobble
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// Keep enough history to cover a restart in the middle of a long session.
+const maxSubmitted = 500
+
+type submitted struct {
+	SpotifyID  string `json:"spotify_id"`
+	ArtistName string `json:"artist_name,omitempty"`
+	TrackName  string `json:"track_name,omitempty"`
+	ListenedAt int64  `json:"listened_at"`
+}
+
+// trackKey identifies the track for de-duplication: its Spotify ID, or artist
+// and title when there is none (local files).
+func trackKey(spotifyID, artist, track string) string {
+	if spotifyID != "" {
+		return spotifyID
+	}
+	return artist + "\x00" + track
+}
+
+func listenKey(l Listen) string {
+	return trackKey(l.TrackMetadata.AdditionalInfo.SpotifyID, l.TrackMetadata.ArtistName, l.TrackMetadata.TrackName)
+}
+
+type storeState struct {
+	Pending   []Listen    `json:"pending"`
+	Submitted []submitted `json:"submitted"`
+}
+
+// Store persists the offline spool (listens that failed to submit) and a
+// rolling log of submitted listens used for de-duplication across restarts.
+type Store struct {
+	path string
+	st   storeState
+}
+
+func OpenStore(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	s := &Store{path: filepath.Join(dir, "scrobble.json")}
+	b, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &s.st); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Store) Pending() []Listen {
+	return append([]Listen(nil), s.st.Pending...)
+}
+
+// Seen reports whether l was already submitted or is waiting in the spool.
+// listened_at is an estimate (poll time minus progress), so two estimates of
+// the same play can differ by a few seconds; anything within half the track
+// (at most a minute) is treated as the same play.
+func (s *Store) Seen(l Listen) bool {
+	key := listenKey(l)
+	tol := int64(60)
+	if d := int64(l.TrackMetadata.AdditionalInfo.DurationMs / 2000); d > 0 && d < tol {
+		tol = d
+	}
+	near := func(a, b int64) bool {
+		if a > b {
+			a, b = b, a
+		}
+		return b-a <= tol
+	}
+	for _, x := range s.st.Submitted {
+		if trackKey(x.SpotifyID, x.ArtistName, x.TrackName) == key && near(x.ListenedAt, l.ListenedAt) {
+			return true
+		}
+	}
+	for _, x := range s.st.Pending {
+		if listenKey(x) == key && near(x.ListenedAt, l.ListenedAt) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Store) Enqueue(l Listen) error {
+	s.st.Pending = append(s.st.Pending, l)
+	return s.save()
+}
+
+// Done records l as submitted and removes it from the spool.
+func (s *Store) Done(l Listen) error {
+	s.remove(l)
+	sub := submitted{SpotifyID: l.TrackMetadata.AdditionalInfo.SpotifyID, ListenedAt: l.ListenedAt}
+	if sub.SpotifyID == "" {
+		sub.ArtistName, sub.TrackName = l.TrackMetadata.ArtistName, l.TrackMetadata.TrackName
+	}
+	s.st.Submitted = append(s.st.Submitted, sub)
+	if n := len(s.st.Submitted); n > maxSubmitted {
+		s.st.Submitted = append([]submitted(nil), s.st.Submitted[n-maxSubmitted:]...)
+	}
+	return s.save()
+}
+
+// Drop removes l from the spool without recording it as submitted.
+func (s *Store) Drop(l Listen) error {
+	s.remove(l)
+	return s.save()
+}
+
+func (s *Store) remove(l Listen) {
+	out := s.st.Pending[:0]
+	key := listenKey(l)
+	for _, x := range s.st.Pending {
+		if x.ListenedAt == l.ListenedAt && listenKey(x) == key {
+			continue
+		}
+		out = append(out, x)
+	}
+	s.st.Pending = out
+}
+
+func (s *Store) save() error {
+	b, err := json.MarshalIndent(s.st, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
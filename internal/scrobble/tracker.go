@@ -0,0 +1,96 @@
+package scrobble
+
+import (
+	"time"
+
+	"github.com/joshp123/spotctl/internal/spotify"
+)
+
+// A track counts as listened once it has played for half its duration or
+// four minutes, whichever comes first (ListenBrainz / Last.fm rule).
+const maxThreshold = 4 * time.Minute
+
+type EventKind string
+
+const (
+	EventNowPlaying EventKind = "playing_now"
+	EventListen     EventKind = "listen"
+)
+
+type Event struct {
+	Kind       EventKind
+	Track      spotify.Track
+	Device     string
+	ListenedAt time.Time // estimated start of the play
+}
+
+type play struct {
+	track     spotify.Track
+	device    string
+	startedAt time.Time
+	played    time.Duration
+
+	lastAt       time.Time
+	lastProgress int
+	lastPlaying  bool
+
+	announced bool
+	scrobbled bool
+}
+
+// Tracker turns successive playback polls into now-playing and listen events.
+// Only time observed while playing counts towards the threshold, so seeking
+// forward or leaving a track paused never produces a listen.
+type Tracker struct {
+	cur *play
+}
+
+func Threshold(duration time.Duration) time.Duration {
+	if duration <= 0 {
+		return maxThreshold
+	}
+	if half := duration / 2; half < maxThreshold {
+		return half
+	}
+	return maxThreshold
+}
+
+func (t *Tracker) Observe(at time.Time, st *spotify.PlaybackState) []Event {
+	if st == nil || st.Item.URI == "" || (st.Item.Type != "" && st.Item.Type != "track") {
+		t.cur = nil
+		return nil
+	}
+
+	p := t.cur
+	// A new play: different track, or the same track starting over after it
+	// was already counted (repeat-one).
+	if p == nil || p.track.URI != st.Item.URI || (p.scrobbled && st.ProgressMs < p.lastProgress) {
+		p = &play{
+			track:     st.Item,
+			device:    st.Device.Name,
+			startedAt: at.Add(-time.Duration(st.ProgressMs) * time.Millisecond),
+		}
+		t.cur = p
+	} else if p.lastPlaying && st.IsPlaying {
+		wall := at.Sub(p.lastAt)
+		progress := time.Duration(st.ProgressMs-p.lastProgress) * time.Millisecond
+		if progress > 0 {
+			p.played += min(wall, progress)
+		}
+	}
+	p.lastAt = at
+	p.lastProgress = st.ProgressMs
+	p.lastPlaying = st.IsPlaying
+
+	var out []Event
+	if st.IsPlaying && !p.announced {
+		p.announced = true
+		out = append(out, Event{Kind: EventNowPlaying, Track: p.track, Device: p.device, ListenedAt: p.startedAt})
+	}
+	need := Threshold(time.Duration(p.track.DurationMs) * time.Millisecond)
+	if !p.scrobbled && p.played >= need {
+		p.scrobbled = true
+		out = append(out, Event{Kind: EventListen, Track: p.track, Device: p.device, ListenedAt: p.startedAt})
+	}
+	return out
+}
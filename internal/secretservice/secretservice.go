@@ -0,0 +1,137 @@
+// Package secretservice reads and writes secrets in the freedesktop Secret
+// Service (GNOME Keyring, KWallet, KeePassXC) over the session bus. Items
+// are found by their attributes, the same way `secret-tool lookup` does.
+package secretservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/joshp123/spotctl/internal/dbus"
+)
+
+const (
+	busName      = "org.freedesktop.secrets"
+	servicePath  = dbus.ObjectPath("/org/freedesktop/secrets")
+	defaultColl  = dbus.ObjectPath("/org/freedesktop/secrets/aliases/default")
+	ifaceService = "org.freedesktop.Secret.Service"
+	ifaceColl    = "org.freedesktop.Secret.Collection"
+	ifaceSession = "org.freedesktop.Secret.Session"
+	noPrompt     = dbus.ObjectPath("/")
+)
+
+// ErrNotFound means no item matches the attributes.
+var ErrNotFound = errors.New("secretservice: no matching item")
+
+// ErrLocked means matching items exist but the collection is locked and
+// unlocking would need an interactive prompt.
+var ErrLocked = errors.New("secretservice: keyring is locked; unlock it (e.g. log in to the desktop session) and retry")
+
+// Client is a session with the Secret Service. Secrets travel with the
+// "plain" algorithm, which is fine on the (per-user) session bus.
+type Client struct {
+	conn    *dbus.Conn
+	session dbus.ObjectPath
+}
+
+// Open starts a session on conn.
+func Open(ctx context.Context, conn *dbus.Conn) (*Client, error) {
+	reply, err := conn.Call(ctx, busName, servicePath, ifaceService, "OpenSession", "sv", "plain", dbus.MakeVariant(""))
+	if err != nil {
+		return nil, fmt.Errorf("secretservice: open session: %w", err)
+	}
+	if len(reply.Body) != 2 {
+		return nil, errors.New("secretservice: bad OpenSession reply")
+	}
+	session, ok := reply.Body[1].(dbus.ObjectPath)
+	if !ok {
+		return nil, errors.New("secretservice: bad OpenSession reply")
+	}
+	return &Client{conn: conn, session: session}, nil
+}
+
+// Close ends the session (not the bus connection).
+func (c *Client) Close(ctx context.Context) error {
+	_, err := c.conn.Call(ctx, busName, c.session, ifaceSession, "Close", "")
+	return err
+}
+
+// Lookup returns the secret of the first item matching attrs.
+func (c *Client) Lookup(ctx context.Context, attrs map[string]string) ([]byte, error) {
+	reply, err := c.conn.Call(ctx, busName, servicePath, ifaceService, "SearchItems", "a{ss}", stringMap(attrs))
+	if err != nil {
+		return nil, fmt.Errorf("secretservice: search: %w", err)
+	}
+	if len(reply.Body) != 2 {
+		return nil, errors.New("secretservice: bad SearchItems reply")
+	}
+	unlocked, _ := reply.Body[0].([]dbus.ObjectPath)
+	locked, _ := reply.Body[1].([]dbus.ObjectPath)
+	if len(unlocked) == 0 && len(locked) > 0 {
+		unlocked, err = c.unlock(ctx, locked)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(unlocked) == 0 {
+		return nil, ErrNotFound
+	}
+
+	item := unlocked[0]
+	reply, err = c.conn.Call(ctx, busName, servicePath, ifaceService, "GetSecrets", "aoo", []dbus.ObjectPath{item}, c.session)
+	if err != nil {
+		return nil, fmt.Errorf("secretservice: get secret: %w", err)
+	}
+	secrets, _ := reply.Body[0].(map[dbus.ObjectPath]any)
+	s, ok := secrets[item].([]any)
+	if !ok || len(s) != 4 {
+		return nil, errors.New("secretservice: bad GetSecrets reply")
+	}
+	value, _ := s[2].([]byte)
+	return value, nil
+}
+
+// unlock tries a promptless unlock (some services allow it, e.g. when the
+// login keyring is merely marked locked).
+func (c *Client) unlock(ctx context.Context, items []dbus.ObjectPath) ([]dbus.ObjectPath, error) {
+	reply, err := c.conn.Call(ctx, busName, servicePath, ifaceService, "Unlock", "ao", items)
+	if err != nil {
+		return nil, fmt.Errorf("secretservice: unlock: %w", err)
+	}
+	if len(reply.Body) != 2 {
+		return nil, errors.New("secretservice: bad Unlock reply")
+	}
+	unlocked, _ := reply.Body[0].([]dbus.ObjectPath)
+	if prompt, _ := reply.Body[1].(dbus.ObjectPath); len(unlocked) == 0 && prompt != noPrompt {
+		return nil, ErrLocked
+	}
+	return unlocked, nil
+}
+
+// Store creates (or replaces) an item with attrs in the default collection.
+func (c *Client) Store(ctx context.Context, label string, attrs map[string]string, secret []byte) error {
+	props := map[string]dbus.Variant{
+		"org.freedesktop.Secret.Item.Label":      dbus.MakeVariant(label),
+		"org.freedesktop.Secret.Item.Attributes": {Sig: "a{ss}", Value: stringMap(attrs)},
+	}
+	s := []any{c.session, []byte{}, secret, "text/plain"}
+	reply, err := c.conn.Call(ctx, busName, defaultColl, ifaceColl, "CreateItem", "a{sv}(oayays)b", props, s, true)
+	if err != nil {
+		return fmt.Errorf("secretservice: store: %w", err)
+	}
+	if len(reply.Body) == 2 {
+		if item, _ := reply.Body[0].(dbus.ObjectPath); item == noPrompt {
+			return ErrLocked
+		}
+	}
+	return nil
+}
+
+func stringMap(m map[string]string) map[string]any {
+	out := make(map[string]any, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,106 @@
+package secretservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/joshp123/spotctl/internal/dbus"
+	"github.com/joshp123/spotctl/internal/dbus/dbustest"
+)
+
+// fakeService is just enough of org.freedesktop.secrets: items identified by
+// their attributes.
+type fakeService struct {
+	mu      sync.Mutex
+	paths   map[string]dbus.ObjectPath
+	secrets map[dbus.ObjectPath][]byte
+}
+
+func attrKey(v any) string {
+	m, _ := v.(map[string]any)
+	parts := make([]string, 0, len(m))
+	for k, v := range m {
+		parts = append(parts, fmt.Sprintf("%s=%v", k, v))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+func (f *fakeService) handle(c *dbus.Conn, call *dbus.Message) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch call.Member {
+	case "OpenSession":
+		_ = c.Reply(call, "vo", dbus.MakeVariant(""), dbus.ObjectPath("/org/freedesktop/secrets/session/1"))
+	case "Close":
+		_ = c.Reply(call, "")
+	case "SearchItems":
+		found := []dbus.ObjectPath{}
+		if p, ok := f.paths[attrKey(call.Body[0])]; ok {
+			found = append(found, p)
+		}
+		_ = c.Reply(call, "aoao", found, []dbus.ObjectPath{})
+	case "GetSecrets":
+		out := map[dbus.ObjectPath]any{}
+		for _, it := range call.Body[0].([]dbus.ObjectPath) {
+			out[it] = []any{call.Body[1], []byte{}, f.secrets[it], "text/plain"}
+		}
+		_ = c.Reply(call, "a{o(oayays)}", out)
+	case "CreateItem":
+		props := call.Body[0].(map[string]dbus.Variant)
+		key := attrKey(props["org.freedesktop.Secret.Item.Attributes"].Value)
+		p, ok := f.paths[key]
+		if !ok {
+			p = dbus.ObjectPath(fmt.Sprintf("/org/freedesktop/secrets/collection/login/%d", len(f.paths)+1))
+			f.paths[key] = p
+		}
+		f.secrets[p] = call.Body[1].([]any)[2].([]byte)
+		_ = c.Reply(call, "oo", p, dbus.ObjectPath("/"))
+	default:
+		_ = c.ReplyError(call, dbus.ErrUnknownMethod, call.Member)
+	}
+}
+
+func TestStoreAndLookup(t *testing.T) {
+	addr := dbustest.PrivateBus(t)
+	ctx := context.Background()
+
+	srv, err := dbus.Dial(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	f := &fakeService{paths: map[string]dbus.ObjectPath{}, secrets: map[dbus.ObjectPath][]byte{}}
+	srv.HandleCalls(f.handle)
+	if err := srv.RequestName(ctx, busName); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := dbus.Dial(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	c, err := Open(ctx, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close(ctx)
+
+	attrs := map[string]string{"service": "spotctl", "key": "refresh_token"}
+	if _, err := c.Lookup(ctx, attrs); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("lookup before store: %v", err)
+	}
+	if err := c.Store(ctx, "spotctl refresh token", attrs, []byte("rt")); err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.Lookup(ctx, attrs)
+	if err != nil || string(got) != "rt" {
+		t.Fatalf("lookup=%q err=%v", got, err)
+	}
+}
@@ -5,10 +5,13 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"os"
+	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/joshp123/spotctl/internal/spotify"
 )
@@ -28,21 +31,92 @@ func (e *exitError) Error() string {
 func (e *exitError) Unwrap() error { return e.err }
 
 func Main(ctx context.Context, args []string, stdout, stderr io.Writer) int {
-	if err := run(ctx, args, stdout, stderr); err != nil {
-		var ee *exitError
-		if errors.As(err, &ee) {
-			if ee.err != nil {
-				fmt.Fprintln(stderr, humanizeError(ee.err).Error())
-			}
+	c := newCLI()
+	args = c.prepareArgs(args)
+	if code, ok := c.runViaDaemon(ctx, args, stdout, stderr); ok {
+		return code
+	}
+	return c.main(ctx, args, stdout, stderr)
+}
+
+func (c *cli) main(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if c.stderr == nil {
+		c.stderr = stderr
+	}
+	err := c.run(ctx, args, stdout, stderr)
+	if err == nil {
+		return 0
+	}
+	code := classifyError(err).exit
+	var ee *exitError
+	if errors.As(err, &ee) {
+		if ee.err == nil {
 			return ee.code
 		}
+		code = ee.code
+	}
+	if wantsJSON(args) {
+		writeJSONError(stderr, err)
+	} else {
 		fmt.Fprintln(stderr, humanizeError(err).Error())
-		return 1
 	}
-	return 0
+	return code
 }
 
-func run(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+// wantsJSON reports whether --json was given anywhere, so failures are
+// reported as JSON too.
+func wantsJSON(args []string) bool {
+	for _, a := range args {
+		if a == "--json" || a == "-json" || a == "--json=true" {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *cli) run(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	// Global flags may appear anywhere (agents tend to append them).
+	noCache, args := popBoolFlag(args, "--no-cache")
+	if noCache {
+		ctx = spotify.WithoutCache(ctx)
+	}
+	profileFlag, _, args, err := popStringFlag(args, "--profile")
+	if err != nil {
+		return &exitError{code: 2, err: err}
+	}
+	if profileFlag != "" {
+		c.profileFlag = profileFlag
+	}
+	trace, args := popBoolFlag(args, "--trace")
+	if trace || os.Getenv("SPOTCTL_DEBUG") != "" || os.Getenv("SPOTCTL_DEBUG_HTTP") != "" {
+		c.logger = slog.New(slog.NewJSONHandler(stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	}
+	// SPOTCTL_REPLAY answers every request from a recorded session (HAR from
+	// --record, or a JSON cassette) for offline regression tests.
+	if p := strings.TrimSpace(os.Getenv("SPOTCTL_REPLAY")); p != "" && c.client == nil {
+		rp, err := spotify.LoadReplayer(expandPath(p))
+		if err != nil {
+			return err
+		}
+		c.hc = &http.Client{Timeout: c.hc.Timeout, Transport: rp}
+		ctx = spotify.WithoutCache(ctx)
+	}
+	record, _, args, err := popStringFlag(args, "--record")
+	if err != nil {
+		return &exitError{code: 2, err: err}
+	}
+	if record == "" {
+		record = strings.TrimSpace(os.Getenv("SPOTCTL_RECORD"))
+	}
+	if record != "" && c.client == nil {
+		rec := spotify.NewRecorder(c.hc.Transport)
+		c.hc = &http.Client{Timeout: c.hc.Timeout, Transport: rec}
+		defer func() {
+			if err := rec.Save(expandPath(record)); err != nil {
+				fmt.Fprintf(stderr, "WARN: write --record file: %v\n", err)
+			}
+		}()
+	}
 	if len(args) == 0 {
 		printUsage(stderr)
 		return &exitError{code: 2, err: errors.New("missing command")}
@@ -55,32 +129,55 @@ func run(ctx context.Context, args []string, stdout, stderr io.Writer) error {
 		printUsage(stdout)
 		return nil
 	}
-
-	cli := newCLI()
+	if c.cfgErr != nil && cmd != "config" {
+		return &exitError{code: 2, err: c.cfgErr}
+	}
+	// profile commands must work even when the selected profile is gone.
+	if cmd != "profile" && cmd != "config" && c.prof == nil {
+		name, prof, err := loadActiveProfile(c.profileFlag)
+		if err != nil {
+			return err
+		}
+		c.profName, c.prof = name, prof
+	}
 
 	switch cmd {
 	case "device":
-		return cli.cmdDevice(ctx, args, stdout, stderr)
+		return c.cmdDevice(ctx, args, stdout, stderr)
 	case "status":
-		return cli.cmdStatus(ctx, args, stdout, stderr)
+		return c.cmdStatus(ctx, args, stdout, stderr)
 	case "transfer":
-		return cli.cmdTransfer(ctx, args, stdout, stderr)
+		return c.cmdTransfer(ctx, args, stdout, stderr)
 	case "play":
-		return cli.cmdPlay(ctx, args, stdout, stderr)
+		return c.cmdPlay(ctx, args, stdout, stderr)
 	case "pause":
-		return cli.cmdPause(ctx, args, stdout, stderr)
+		return c.cmdPause(ctx, args, stdout, stderr)
 	case "next":
-		return cli.cmdNext(ctx, args, stdout, stderr)
+		return c.cmdNext(ctx, args, stdout, stderr)
 	case "previous", "prev":
-		return cli.cmdPrevious(ctx, args, stdout, stderr)
+		return c.cmdPrevious(ctx, args, stdout, stderr)
 	case "volume":
-		return cli.cmdVolume(ctx, args, stdout, stderr)
+		return c.cmdVolume(ctx, args, stdout, stderr)
 	case "playlist":
-		return cli.cmdPlaylist(ctx, args, stdout, stderr)
+		return c.cmdPlaylist(ctx, args, stdout, stderr)
 	case "search":
-		return cli.cmdSearch(ctx, args, stdout, stderr)
+		return c.cmdSearch(ctx, args, stdout, stderr)
 	case "auth":
-		return cli.cmdAuth(ctx, args, stdout, stderr)
+		return c.cmdAuth(ctx, args, stdout, stderr)
+	case "scrobble":
+		return c.cmdScrobble(ctx, args, stdout, stderr)
+	case "daemon":
+		return c.cmdDaemon(ctx, args, stdout, stderr)
+	case "mcp":
+		return c.cmdMCP(ctx, args, stdout, stderr)
+	case "serve":
+		return c.cmdServe(ctx, args, stdout, stderr)
+	case "mpris":
+		return c.cmdMPRIS(ctx, args, stdout, stderr)
+	case "profile":
+		return c.cmdProfile(ctx, args, stdout, stderr)
+	case "config":
+		return c.cmdConfig(ctx, args, stdout, stderr)
 	default:
 		printUsage(stderr)
 		return &exitError{code: 2, err: fmt.Errorf("unknown command: %s", cmd)}
@@ -89,39 +186,159 @@ func run(ctx context.Context, args []string, stdout, stderr io.Writer) error {
 
 type cli struct {
 	client *spotify.Client
+	tok    *spotify.TokenManager
 	hc     *http.Client
+
+	playerCacheTTL time.Duration // daemon only
+	logger         *slog.Logger  // --trace
+	stderr         io.Writer     // warnings from the shared client (set by main)
+
+	profileFlag string   // --profile
+	profName    string   // active profile; "" means env vars only
+	prof        *profile // nil without a profile
+
+	cfg    fileConfig // config.toml (see prepareArgs)
+	cfgErr error
+
+	// forced settings win over profile, env and config: a daemon request
+	// carries the client's effective per-command settings this way.
+	forced map[string]string
 }
 
 func newCLI() *cli {
 	return &cli{hc: spotify.DefaultHTTPClient(spotify.DefaultHTTPClientOptions{})}
 }
 
-func (c *cli) ensureClient(ctx context.Context) error {
-	if c.client != nil {
-		return nil
+// ensureClient sets up c.client on first use and, if scopes are given,
+// fails fast with a *spotify.ScopeError when the token lacks any of them.
+func (c *cli) ensureClient(ctx context.Context, scopes ...string) error {
+	if c.client == nil {
+		if err := c.initClient(ctx); err != nil {
+			return err
+		}
+	}
+	return c.tok.RequireScopes(ctx, scopes...)
+}
+
+func (c *cli) initClient(ctx context.Context) error {
+	src, err := spotify.ParseCredentialSource(c.settingValue("credentials"))
+	if err != nil {
+		return &exitError{code: 2, err: err}
 	}
-	creds, err := spotify.LoadCredentialsFromEnv()
+	creds, err := src.Credentials(ctx)
 	if err != nil {
 		return err
 	}
+	creds.PublicClient = c.settingBool("public_client")
+	if err := creds.Check(); err != nil {
+		return fmt.Errorf("%w: set SPOTIFY_CLIENT_SECRET, or public_client = true (SPOTCTL_PUBLIC_CLIENT=1) for a PKCE-only app", err)
+	}
 
-	accountsBase := strings.TrimSpace(os.Getenv("SPOTIFY_ACCOUNTS_BASE"))
-	cachePath := strings.TrimSpace(os.Getenv("SPOTCTL_TOKEN_CACHE"))
+	accountsBase := c.settingValue("accounts_base")
+	cachePath := c.tokenCachePath()
+	var cacheKey []byte
+	if p := c.settingValue("token_cache_key"); p != "" && cachePath != "" {
+		if cacheKey, err = spotify.CacheKeyFromFile(expandPath(p)); err != nil {
+			return err
+		}
+	}
+
+	// Spotify may rotate the refresh token on refresh; put the new one back
+	// where it came from. The env hook/file belong to the env credentials,
+	// not to a profile's own source.
+	var writer spotify.RefreshTokenWriter
+	envCreds := c.prof == nil || c.prof.Credentials == ""
+	if hook := strings.TrimSpace(os.Getenv("SPOTCTL_REFRESH_TOKEN_HOOK")); hook != "" && envCreds {
+		writer = spotify.RefreshTokenCommand{Command: hook}
+	} else if p := strings.TrimSpace(os.Getenv("SPOTIFY_REFRESH_TOKEN_FILE")); p != "" && envCreds {
+		writer = spotify.RefreshTokenFile{Path: expandPath(p)}
+	} else if w, ok := src.(spotify.RefreshTokenWriter); ok {
+		writer = w
+	}
+	warn := c.stderr
+	if warn == nil {
+		warn = io.Discard
+	}
 
 	tok, err := spotify.NewTokenManager(creds, spotify.TokenManagerOptions{
-		HTTP:         c.hc,
-		AccountsBase: accountsBase,
-		CachePath:    cachePath,
+		HTTP:               c.hc,
+		AccountsBase:       accountsBase,
+		CachePath:          cachePath,
+		CacheKey:           cacheKey,
+		Logger:             c.logger,
+		RefreshTokenWriter: writer,
+		Logf: func(format string, args ...any) {
+			fmt.Fprintf(warn, "WARN: "+format+"\n", args...)
+		},
 	})
 	if err != nil {
 		return err
 	}
 
-	apiBase := strings.TrimSpace(os.Getenv("SPOTIFY_API_BASE"))
-	c.client = spotify.NewClient(tok, spotify.ClientOptions{HTTP: c.hc, APIBase: apiBase})
+	retry := spotify.RetryPolicy{
+		MaxAttempts:   int(c.settingInt("max_attempts")),
+		MaxRetryAfter: time.Duration(c.settingInt("max_retry_after_secs")) * time.Second,
+	}
+	maxBody := c.settingInt("max_response_bytes")
+
+	// The 429 cooldown lives next to the token cache so consecutive runs
+	// share it.
+	var limitState string
+	if cachePath != "" {
+		limitState = filepath.Join(filepath.Dir(expandPath(cachePath)), "ratelimit.json")
+	}
+	limiter := spotify.NewRateLimiter(spotify.RateLimiterOptions{StatePath: limitState})
+
+	var cache *spotify.HTTPCache
+	if dir := httpCacheDir(); dir != "" {
+		// Responses are per account (and may be Cache-Control: private).
+		cache = spotify.NewHTTPCache(dir, true)
+	}
+
+	apiBase := c.settingValue("api_base")
+	c.tok = tok
+	c.client = spotify.NewClient(tok, spotify.ClientOptions{
+		HTTP:             c.hc,
+		APIBase:          apiBase,
+		PlayerCacheTTL:   c.playerCacheTTL,
+		Retry:            retry,
+		Limiter:          limiter,
+		Cache:            cache,
+		Logger:           c.logger,
+		MaxResponseBytes: maxBody,
+		Market:           c.settingValue("market"),
+	})
 	return nil
 }
 
+// tokenCachePath is the token_cache setting, else token.json in the cache
+// dir (per profile). "off" disables the cache.
+func (c *cli) tokenCachePath() string {
+	switch p := c.settingValue("token_cache"); {
+	case p == "off":
+		return ""
+	case p != "":
+		return expandPath(p)
+	case c.profName != "":
+		return defaultProfileTokenCache(c.profName)
+	}
+	d, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(d, "spotctl", "token.json")
+}
+
+// httpCacheDir is $SPOTCTL_CACHE_DIR. The HTTP cache is opt-in: unset (or
+// "off") disables it.
+func httpCacheDir() string {
+	d := strings.TrimSpace(os.Getenv("SPOTCTL_CACHE_DIR"))
+	if d == "off" {
+		return ""
+	}
+	return expandPath(d)
+}
+
 func printUsage(w io.Writer) {
 	fmt.Fprintln(w, strings.TrimSpace(`spotctl - Spotify Web API CLI (refresh-token OAuth)
 
@@ -139,17 +356,72 @@ Usage:
   spotctl playlist create --name <name> [--public] [--description <text>] [--json]
   spotctl playlist add --playlist <id|uri|url> <track-uri...> [--json]
   spotctl playlist privacy --playlist <id|uri|url> (--private|--public) [--json]
-  spotctl playlist cleanup [--prefix spotctl-test:] [--regex <re>] [--apply --yes] [--json]
+  spotctl playlist cleanup [--prefix spotctl-test:] [--regex <re>] [--apply --yes] [--concurrency N] [--json]
+
+  spotctl serve [--listen 127.0.0.1:8787] [--auth-token-file <path>]
+  spotctl mcp                (MCP server over stdio)
+  spotctl daemon [--socket <path>] [--cache-ttl 2s]
+  spotctl mpris [--interval 2s]   (MPRIS2 player on the session bus)
+  spotctl scrobble --token-file <path> [--listenbrainz-url <url>] [--interval 5s] [--state-dir <dir>]
+
+  spotctl profile list [--json]
+  spotctl profile add <name> [--credentials <source>] [--public-client] [--token-cache <path>] [--device <name|id>]
+                             [--api-base <url>] [--accounts-base <url>]
+  spotctl profile remove <name>
+  spotctl profile use (<name> | --none)
+
+  spotctl config path
+  spotctl config get <key> [--reveal]
+  spotctl config set <key> <value>       (value "" removes the key; aliases.<name> sets an alias)
+  spotctl config show [--json] [--reveal]
 
+  spotctl auth status [--json]
   spotctl auth url --redirect-uri <uri>
   spotctl auth exchange --redirect-uri <uri> (--code <code> | --redirect-url <full-url>)
-  spotctl auth login --redirect-uri <https://localhost:port/callback>
-  spotctl auth bootstrap-agenix --secrets-dir <path> --redirect-uri <https://localhost:port/callback>
+  spotctl auth login --redirect-uri <http://127.0.0.1:port/callback> [--headless] [--public] [--timeout 5m] [--cert-dir <dir>]
+  spotctl auth bootstrap --sink agenix|sops|file|env-file|exec --dest <dir|file|command>
+                         --redirect-uri <http://127.0.0.1:port/callback> [--headless] [--public] [--timeout 5m] [--cert-dir <dir>]
 
 Auth env (values or file paths):
   SPOTIFY_CLIENT_ID
   SPOTIFY_CLIENT_SECRET
   SPOTIFY_REFRESH_TOKEN
+  SPOTCTL_CREDENTIALS  where to read them instead: env (default), secret-service[:<service>],
+                       pass[:<prefix>], gopass[:<prefix>], command:<cmd printing JSON>
+  SPOTCTL_PUBLIC_CLIENT=1  the app is a PKCE-only public client: no SPOTIFY_CLIENT_SECRET
+
+Other env:
+  SPOTCTL_PROFILE      profile to use (like --profile); its settings replace the env vars they cover
+  SPOTCTL_CONFIG       config file (default: $XDG_CONFIG_HOME/spotctl/config.toml)
+  SPOTCTL_DEVICE       default device for play/transfer
+  SPOTCTL_OUTPUT       default output format: text or json
+  SPOTCTL_MARKET       search market (ISO country code, or from_token)
+  SPOTCTL_SOCKET       daemon socket path (default: $XDG_RUNTIME_DIR/spotctl/daemon[-<profile>].sock)
+  SPOTCTL_NO_DAEMON=1  never use a running daemon
+  SPOTCTL_MAX_ATTEMPTS          tries per request, including the first (default 3)
+  SPOTCTL_MAX_RETRY_AFTER_SECS  longest 429 Retry-After to wait out (default 15)
+  SPOTCTL_MAX_RESPONSE_BYTES    largest response body to read (default 2 MiB)
+  SPOTCTL_REFRESH_TOKEN_HOOK    command that stores a rotated refresh token (read from stdin)
+  SPOTCTL_TOKEN_CACHE  access token cache file (default: $XDG_CACHE_HOME/spotctl/token.json; "off"
+                       disables); shared between processes, a 429 cooldown is kept next to it
+  SPOTCTL_TOKEN_CACHE_KEY  keyfile or age identity to encrypt the token cache with
+  SPOTCTL_REPLAY       serve all HTTP from a recorded HAR/JSON cassette (offline tests)
+  SPOTCTL_CACHE_DIR    enable the HTTP response cache in this dir, e.g. $XDG_CACHE_HOME/spotctl/http (default: off)
+
+Global flags:
+  --profile <name>     use a named profile (credentials, token cache, default device, API bases)
+  --output text|json   output format for commands that support --json
+  --no-cache           bypass the HTTP response cache for this command
+  --trace              log every request (JSON, redacted) to stderr; also SPOTCTL_DEBUG=1
+  --record <file.har>  save redacted request/response pairs as HAR; also SPOTCTL_RECORD
+
+Settings come from flags, then the active profile, then env, then config.toml
+(see spotctl config --help).
+
+Exit codes:
+  0 ok, 1 other error, 2 invalid arguments, 3 device not available / no active device,
+  4 unauthorized, 5 missing scope, 6 Premium required, 7 restricted, 8 not found, 9 rate limited
+  (with --json, errors are printed to stderr as {"error":{code,reason,message,hint}})
 
 Notes:
   - Device targeting is strict: if the requested device isn't listed in /me/player/devices,
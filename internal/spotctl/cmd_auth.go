@@ -22,6 +22,15 @@ var defaultScopes = []string{
 	"user-read-private",
 }
 
+// Scopes commands check up front (see ensureClient), so a token minted with
+// fewer scopes gets a hint instead of a bare 403.
+var (
+	scopesReadPlayback = []string{"user-read-playback-state"}
+	scopesControl      = []string{"user-read-playback-state", "user-modify-playback-state"}
+	// Adding to or editing a playlist may hit a public or a private one.
+	scopesPlaylistModify = []string{"playlist-modify-public", "playlist-modify-private"}
+)
+
 func (c *cli) cmdAuth(ctx context.Context, args []string, stdout, stderr io.Writer) error {
 	if len(args) == 0 {
 		return &exitError{code: 2, err: errors.New("missing subcommand for auth")}
@@ -29,14 +38,18 @@ func (c *cli) cmdAuth(ctx context.Context, args []string, stdout, stderr io.Writ
 	sub := args[0]
 	args = args[1:]
 	switch sub {
+	case "status":
+		return c.cmdAuthStatus(ctx, args, stdout, stderr)
 	case "url":
 		return c.cmdAuthURL(ctx, args, stdout, stderr)
 	case "exchange":
 		return c.cmdAuthExchange(ctx, args, stdout, stderr)
 	case "login":
 		return c.cmdAuthLogin(ctx, args, stdout, stderr)
+	case "bootstrap":
+		return c.cmdAuthBootstrap(ctx, args, stdout, stderr)
 	case "bootstrap-agenix":
-		return c.cmdAuthBootstrapAgenix(ctx, args, stdout, stderr)
+		return c.cmdAuthBootstrap(ctx, bootstrapAgenixArgs(args), stdout, stderr)
 	default:
 		return &exitError{code: 2, err: fmt.Errorf("unknown auth subcommand: %s", sub)}
 	}
@@ -0,0 +1,133 @@
+package spotctl
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/joshp123/spotctl/internal/spotify"
+)
+
+// bootstrap:
+// - prompts for client id/secret (no secret for a public client)
+// - runs browser auth with local callback
+// - writes the secrets to a SecretSink (no token copy/paste)
+func (c *cli) cmdAuthBootstrap(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("auth bootstrap", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	sinkKind := fs.String("sink", "", "Where to write the secrets: agenix, sops, file, env-file or exec")
+	dest := fs.String("dest", "", "agenix: secrets dir (with secrets.nix); sops: encrypted file; file: directory; env-file: file; exec: command")
+	redirectURI := fs.String("redirect-uri", "", "Redirect URI (must match Spotify app settings). Recommended: http://127.0.0.1:8899/callback")
+	headless := fs.Bool("headless", false, "No callback server: print the URL, then paste the redirect URL on stdin")
+	timeout := fs.Duration("timeout", defaultLoginTimeout, "Give up waiting for the browser after this long")
+	certDir := fs.String("cert-dir", "", "Keep the https callback cert (and a local CA to trust) in this directory")
+	public := fs.Bool("public", c.settingBool("public_client"), "Public client: PKCE only, no client secret (default: the public_client setting)")
+	if err := parseFlags(fs, args, stderr); err != nil {
+		return err
+	}
+	if *sinkKind == "" {
+		return &exitError{code: 2, err: errors.New("missing --sink (agenix, sops, file, env-file, exec)")}
+	}
+	if *dest == "" {
+		return &exitError{code: 2, err: errors.New("missing --dest")}
+	}
+	if *redirectURI == "" {
+		return &exitError{code: 2, err: errors.New("missing --redirect-uri")}
+	}
+	if fs.NArg() != 0 {
+		return &exitError{code: 2, err: errors.New("auth bootstrap takes no positional args")}
+	}
+	sink, err := spotify.ParseSecretSink(*sinkKind, *dest)
+	if err != nil {
+		return &exitError{code: 2, err: err}
+	}
+	if err := sink.Check(); err != nil {
+		return err
+	}
+
+	clientID, err := readSecretOrPrompt("SPOTIFY_CLIENT_ID", stderr)
+	if err != nil {
+		return err
+	}
+	var clientSecret string
+	if !*public {
+		clientSecret, err = readSecretOrPrompt("SPOTIFY_CLIENT_SECRET", stderr)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Write the app credentials first: a broken sink fails before the
+	// browser round trip.
+	if *public {
+		fmt.Fprintf(stderr, "Writing client id to %s...\n", sink)
+	} else {
+		fmt.Fprintf(stderr, "Writing client id/secret to %s...\n", sink)
+	}
+	if err := sink.WriteSecret(ctx, "client_id", clientID); err != nil {
+		return err
+	}
+	if !*public {
+		if err := sink.WriteSecret(ctx, "client_secret", clientSecret); err != nil {
+			return err
+		}
+	}
+
+	// Now mint refresh token via login flow.
+	ex, err := c.authorize(ctx, spotify.Credentials{ClientID: clientID, ClientSecret: clientSecret, PublicClient: *public}, authorizeOptions{
+		RedirectURI: *redirectURI,
+		Scopes:      defaultScopes,
+		ShowDialog:  true,
+		Headless:    *headless,
+		Timeout:     *timeout,
+		CertDir:     *certDir,
+	}, stderr)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stderr, "Writing refresh token to %s...\n", sink)
+	if err := sink.WriteSecret(ctx, "refresh_token", ex.RefreshToken); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(stdout, "OK")
+	if *public {
+		fmt.Fprintln(stderr, "Public client: set public_client = true (or SPOTCTL_PUBLIC_CLIENT=1) wherever these secrets are used.")
+	}
+	return nil
+}
+
+// bootstrapAgenixArgs maps the older `auth bootstrap-agenix --secrets-dir`
+// onto `auth bootstrap --sink agenix --dest`.
+func bootstrapAgenixArgs(args []string) []string {
+	out := []string{"--sink", "agenix"}
+	for _, a := range args {
+		switch {
+		case a == "--secrets-dir" || a == "-secrets-dir":
+			a = "--dest"
+		case strings.HasPrefix(a, "--secrets-dir="), strings.HasPrefix(a, "-secrets-dir="):
+			a = "--dest=" + a[strings.Index(a, "=")+1:]
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+func expandPath(p string) string {
+	if strings.HasPrefix(p, "~") {
+		h, _ := os.UserHomeDir()
+		if p == "~" {
+			return h
+		}
+		if strings.HasPrefix(p, "~/") {
+			return filepath.Join(h, p[2:])
+		}
+	}
+	return p
+}
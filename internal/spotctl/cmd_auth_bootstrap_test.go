@@ -0,0 +1,135 @@
+package spotctl
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// toServer sends every request (here: the code exchange) to srv.
+type toServer struct{ srv *httptest.Server }
+
+func (t toServer) RoundTrip(r *http.Request) (*http.Response, error) {
+	u, _ := url.Parse(t.srv.URL)
+	r = r.Clone(r.Context())
+	r.URL.Scheme, r.URL.Host = u.Scheme, u.Host
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+// approveLogin plays the browser: it reads stderr until the authorize URL
+// shows up and sends the callback for it.
+func approveLogin() (io.Writer, func() string) {
+	pr, pw := io.Pipe()
+	var log bytes.Buffer
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		sc := bufio.NewScanner(pr)
+		for sc.Scan() {
+			line := sc.Text()
+			log.WriteString(line + "\n")
+			if !strings.HasPrefix(line, "https://accounts.spotify.com/authorize") {
+				continue
+			}
+			u, _ := url.Parse(line)
+			q := u.Query()
+			cb := q.Get("redirect_uri") + "?code=c0de&state=" + url.QueryEscape(q.Get("state"))
+			go func() {
+				if resp, err := http.Get(cb); err == nil {
+					resp.Body.Close()
+				}
+			}()
+		}
+	}()
+	return pw, func() string {
+		pw.Close()
+		wg.Wait()
+		return log.String()
+	}
+}
+
+func TestAuthBootstrapPublic(t *testing.T) {
+	tests := []struct {
+		name       string
+		flags, env []string
+		wantPublic bool
+	}{
+		{"confidential", nil, []string{"SPOTIFY_CLIENT_SECRET=sec"}, false},
+		{"--public", []string{"--public"}, nil, true},
+		{"public_client setting", nil, []string{"SPOTCTL_PUBLIC_CLIENT=1"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeAPI(t, "")
+			t.Setenv("SPOTIFY_CLIENT_SECRET", "")
+			t.Setenv("PATH", t.TempDir()) // no browser to open
+			for _, kv := range tt.env {
+				k, v, _ := strings.Cut(kv, "=")
+				t.Setenv(k, v)
+			}
+
+			var auth string
+			var form url.Values
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				auth = r.Header.Get("Authorization")
+				_ = r.ParseForm()
+				form = r.PostForm
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprint(w, `{"access_token":"at","token_type":"Bearer","expires_in":3600,"refresh_token":"rt-new"}`)
+			}))
+			defer srv.Close()
+
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			redirect := "http://" + ln.Addr().String() + "/callback"
+			ln.Close()
+
+			dest := t.TempDir()
+			c := newCLI()
+			c.hc = &http.Client{Transport: toServer{srv}}
+			stderr, done := approveLogin()
+			args := append([]string{"auth", "bootstrap", "--sink", "file", "--dest", dest, "--redirect-uri", redirect, "--timeout", "10s"}, tt.flags...)
+			var out bytes.Buffer
+			code := c.main(context.Background(), args, &out, stderr)
+			log := done()
+			if code != 0 {
+				t.Fatalf("exit %d:\n%s", code, log)
+			}
+
+			rt, _ := os.ReadFile(filepath.Join(dest, "refresh_token"))
+			if strings.TrimSpace(string(rt)) != "rt-new" {
+				t.Fatalf("refresh_token=%q", rt)
+			}
+			_, err = os.Stat(filepath.Join(dest, "client_secret"))
+			if tt.wantPublic {
+				if err == nil {
+					t.Fatal("public bootstrap wrote a client_secret")
+				}
+				if auth != "" || form.Get("client_id") != "cid" {
+					t.Fatalf("public exchange: Authorization=%q form=%v", auth, form)
+				}
+			} else {
+				if err != nil {
+					t.Fatal("client_secret not written")
+				}
+				if !strings.HasPrefix(auth, "Basic ") {
+					t.Fatalf("confidential exchange without basic auth: %q", auth)
+				}
+			}
+		})
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"io"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/joshp123/spotctl/internal/spotify"
 )
@@ -18,10 +19,14 @@ import (
 func (c *cli) cmdAuthLogin(ctx context.Context, args []string, stdout, stderr io.Writer) error {
 	fs := flag.NewFlagSet("auth login", flag.ContinueOnError)
 	fs.SetOutput(io.Discard)
-	redirectURI := fs.String("redirect-uri", "", "Redirect URI (must match Spotify app settings). Recommended: http://localhost:8899/callback")
+	redirectURI := fs.String("redirect-uri", "", "Redirect URI (must match Spotify app settings). Recommended: http://127.0.0.1:8899/callback")
 	scopes := fs.String("scopes", strings.Join(defaultScopes, " "), "Space-separated scopes")
 	showDialog := fs.Bool("show-dialog", true, "Force auth dialog")
 	noOpen := fs.Bool("no-open", false, "Do not auto-open browser; print URL only")
+	headless := fs.Bool("headless", false, "No callback server: print the URL, then paste the redirect URL on stdin")
+	timeout := fs.Duration("timeout", defaultLoginTimeout, "Give up waiting for the browser after this long")
+	certDir := fs.String("cert-dir", "", "Keep the https callback cert (and a local CA to trust) in this directory")
+	public := fs.Bool("public", c.settingBool("public_client"), "Public client: PKCE only, no client secret (default: the public_client setting)")
 	if err := parseFlags(fs, args, stderr); err != nil {
 		return err
 	}
@@ -36,62 +41,169 @@ func (c *cli) cmdAuthLogin(ctx context.Context, args []string, stdout, stderr io
 	if err != nil {
 		return err
 	}
-	clientSecret, err := readSecretOrPrompt("SPOTIFY_CLIENT_SECRET", stderr)
+	// Public clients authenticate with PKCE alone; the refresh token then
+	// only works in public-client mode too.
+	var clientSecret string
+	if !*public {
+		clientSecret, err = readSecretOrPrompt("SPOTIFY_CLIENT_SECRET", stderr)
+		if err != nil {
+			return err
+		}
+	}
+
+	ex, err := c.authorize(ctx, spotify.Credentials{ClientID: clientID, ClientSecret: clientSecret, PublicClient: *public}, authorizeOptions{
+		RedirectURI: *redirectURI,
+		Scopes:      strings.Fields(*scopes),
+		ShowDialog:  *showDialog,
+		NoOpen:      *noOpen,
+		Headless:    *headless,
+		Timeout:     *timeout,
+		CertDir:     *certDir,
+	}, stderr)
 	if err != nil {
 		return err
 	}
 
+	// stdout only: refresh token
+	fmt.Fprintln(stdout, ex.RefreshToken)
+	fmt.Fprintln(stderr, "OK. Use this as SPOTIFY_REFRESH_TOKEN (value or file).")
+	if *public {
+		fmt.Fprintln(stderr, "Public client: set public_client = true (`spotctl config set public_client true` or SPOTCTL_PUBLIC_CLIENT=1). The refresh token rotates; spotctl keeps the current one in its token cache.")
+	}
+	return nil
+}
+
+type authorizeOptions struct {
+	RedirectURI string
+	Scopes      []string
+	ShowDialog  bool
+	NoOpen      bool
+	// Headless skips the callback server: the user opens the URL anywhere
+	// and pastes the URL the browser was redirected to (the page itself may
+	// fail to load) on stdin. For logins over SSH.
+	Headless bool
+	Timeout  time.Duration // 0: wait forever
+	CertDir  string        // see spotify.CallbackServerOptions
+}
+
+const defaultLoginTimeout = 5 * time.Minute
+
+// authorize runs the browser half of the authorization code flow (always
+// with PKCE) and exchanges the code.
+func (c *cli) authorize(ctx context.Context, creds spotify.Credentials, opt authorizeOptions, stderr io.Writer) (spotify.AuthCodeExchangeResult, error) {
 	state, err := randomState()
 	if err != nil {
-		return err
+		return spotify.AuthCodeExchangeResult{}, err
 	}
-
-	// Start the callback server before opening the browser.
-	fmt.Fprintln(stderr, "Starting local HTTPS callback server (browser will warn about self-signed cert)...")
-	cb, err := spotify.StartLocalCallbackServer(*redirectURI)
+	pkce, err := spotify.NewPKCE()
 	if err != nil {
-		return err
+		return spotify.AuthCodeExchangeResult{}, err
+	}
+	waitCtx := ctx
+	if opt.Timeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, opt.Timeout)
+		defer cancel()
 	}
-	defer cb.Close()
-
-	authURL := spotify.AuthorizationURL(clientID, spotify.AuthURLOptions{
-		RedirectURI: cb.RedirectURL,
-		Scopes:      strings.Fields(*scopes),
-		ShowDialog:  *showDialog,
-		State:       state,
-	})
 
-	fmt.Fprintln(stderr, "Open this URL if your browser didn't open automatically:")
-	fmt.Fprintln(stderr, authURL)
-	if !*noOpen {
-		_ = openURL(ctx, authURL)
+	redirectURL := opt.RedirectURI
+	var cb *spotify.LocalCallbackServer
+	if !opt.Headless {
+		// Start the callback server before opening the browser.
+		if strings.HasPrefix(opt.RedirectURI, "https:") {
+			fmt.Fprintln(stderr, "Starting local HTTPS callback server (browser will warn about self-signed cert)...")
+		} else {
+			fmt.Fprintln(stderr, "Starting local callback server...")
+		}
+		cb, err = spotify.StartLocalCallbackServer(opt.RedirectURI, spotify.CallbackServerOptions{State: state, CertDir: opt.CertDir})
+		if err != nil {
+			return spotify.AuthCodeExchangeResult{}, &exitError{code: 2, err: err}
+		}
+		defer cb.Close()
+		redirectURL = cb.RedirectURL
+		if cb.CAFile != "" {
+			fmt.Fprintf(stderr, "Callback cert is kept in %s; trust %s to skip the browser warning.\n", opt.CertDir, cb.CAFile)
+		}
 	}
 
-	res, err := cb.Wait(ctx)
-	if err != nil {
-		return err
+	authURL := spotify.AuthorizationURL(creds.ClientID, spotify.AuthURLOptions{
+		RedirectURI:         redirectURL,
+		Scopes:              opt.Scopes,
+		ShowDialog:          opt.ShowDialog,
+		State:               state,
+		CodeChallenge:       pkce.Challenge,
+		CodeChallengeMethod: "S256",
+	})
+
+	var res spotify.CallbackResult
+	if opt.Headless {
+		fmt.Fprintln(stderr, "Open this URL in a browser on any machine:")
+		fmt.Fprintln(stderr, authURL)
+		fmt.Fprintln(stderr, "After approving, the browser goes to the redirect URI (the page may not load). Paste that full URL here:")
+		line, err := readLine(waitCtx, os.Stdin)
+		if err != nil {
+			return spotify.AuthCodeExchangeResult{}, loginWaitError(err, opt.Timeout)
+		}
+		res, err = spotify.ParseCallbackURL(line)
+		if err != nil {
+			return spotify.AuthCodeExchangeResult{}, &exitError{code: 2, err: err}
+		}
+	} else {
+		fmt.Fprintln(stderr, "Open this URL if your browser didn't open automatically:")
+		fmt.Fprintln(stderr, authURL)
+		if !opt.NoOpen {
+			_ = openURL(ctx, authURL)
+		}
+		res, err = cb.Wait(waitCtx)
+		if err != nil {
+			return spotify.AuthCodeExchangeResult{}, loginWaitError(err, opt.Timeout)
+		}
 	}
 	if res.Error != "" {
-		return fmt.Errorf("spotify auth error: %s", res.Error)
+		return spotify.AuthCodeExchangeResult{}, fmt.Errorf("spotify auth error: %s", res.Error)
 	}
 	if res.Code == "" {
-		return errors.New("spotify callback missing code")
+		return spotify.AuthCodeExchangeResult{}, errors.New("spotify callback missing code")
 	}
-	if res.State != "" && res.State != state {
-		return errors.New("spotify callback state mismatch")
+	if res.State != state {
+		// Only reachable in headless mode; the server drops these itself.
+		return spotify.AuthCodeExchangeResult{}, errors.New("spotify callback state mismatch (URL from another login?)")
 	}
 
-	ex, err := spotify.ExchangeAuthorizationCode(ctx, c.hc, spotify.Credentials{ClientID: clientID, ClientSecret: clientSecret}, res.Code, spotify.AuthCodeExchangeOptions{
-		RedirectURI: cb.RedirectURL,
+	return spotify.ExchangeAuthorizationCode(ctx, c.hc, creds, res.Code, spotify.AuthCodeExchangeOptions{
+		RedirectURI:  redirectURL,
+		CodeVerifier: pkce.Verifier,
 	})
-	if err != nil {
-		return err
+}
+
+func loginWaitError(err error, timeout time.Duration) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("gave up waiting for the Spotify redirect after %s (--timeout)", timeout)
 	}
+	return err
+}
 
-	// stdout only: refresh token
-	fmt.Fprintln(stdout, ex.RefreshToken)
-	fmt.Fprintln(stderr, "OK. Use this as SPOTIFY_REFRESH_TOKEN (value or file).")
-	return nil
+// readLine reads one line, or gives up when ctx ends (the read itself keeps
+// blocking in the background; the process is about to exit anyway).
+func readLine(ctx context.Context, r io.Reader) (string, error) {
+	type result struct {
+		line string
+		err  error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		line, err := bufio.NewReader(r).ReadString('\n')
+		if errors.Is(err, io.EOF) && line != "" {
+			err = nil
+		}
+		ch <- result{line, err}
+	}()
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case res := <-ch:
+		return res.line, res.err
+	}
 }
 
 func randomState() (string, error) {
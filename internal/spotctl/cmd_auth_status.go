@@ -0,0 +1,122 @@
+package spotctl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/joshp123/spotctl/internal/spotify"
+)
+
+type authStatus struct {
+	Profile    string       `json:"profile,omitempty"`
+	User       spotify.User `json:"user"`
+	Scopes     scopeStatus  `json:"scopes"`
+	ExpiresAt  time.Time    `json:"expires_at"`
+	TokenCache string       `json:"token_cache,omitempty"`
+	Encrypted  bool         `json:"token_cache_encrypted,omitempty"`
+}
+
+type scopeStatus struct {
+	Granted []string `json:"granted"`
+	Default []string `json:"default"`
+	Missing []string `json:"missing"`
+}
+
+func (c *cli) cmdAuthStatus(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	jsonTrailing, args := popBoolFlag(args, "--json")
+	fs := flag.NewFlagSet("auth status", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	jsonOut := fs.Bool("json", false, "JSON output")
+	if err := parseFlags(fs, args, stderr); err != nil {
+		return err
+	}
+	if jsonTrailing {
+		*jsonOut = true
+	}
+	if fs.NArg() != 0 {
+		return &exitError{code: 2, err: errors.New("auth status takes no positional args")}
+	}
+
+	if err := c.ensureClient(ctx); err != nil {
+		return err
+	}
+	// Always refresh: the point is to check the refresh token still works
+	// and what it grants now.
+	if _, err := c.tok.ForceRefresh(ctx); err != nil {
+		return err
+	}
+	tok, err := c.tok.Token(ctx)
+	if err != nil {
+		return err
+	}
+	me, err := c.client.Me(ctx)
+	if err != nil {
+		return err
+	}
+
+	st := authStatus{
+		Profile: c.profName,
+		User:    me,
+		Scopes: scopeStatus{
+			Granted: tok.Scopes(),
+			Default: defaultScopes,
+			Missing: tok.MissingScopes(defaultScopes...),
+		},
+		ExpiresAt:  tok.ExpiresAt,
+		TokenCache: expandPath(c.tok.CachePath()),
+		Encrypted:  c.tok.CacheEncrypted(),
+	}
+	if st.Scopes.Granted == nil {
+		st.Scopes.Granted = []string{}
+	}
+	if st.Scopes.Missing == nil {
+		st.Scopes.Missing = []string{}
+	}
+
+	if *jsonOut {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(st)
+	}
+
+	product := me.Product
+	if product == "" {
+		product = "unknown"
+	}
+	if c.profName != "" {
+		fmt.Fprintf(stdout, "Profile: %s\n", c.profName)
+	}
+	fmt.Fprintf(stdout, "User:    %s (%s)\n", me.ID, product)
+	switch {
+	case len(st.Scopes.Granted) == 0:
+		fmt.Fprintln(stdout, "Scopes:  not reported by Spotify")
+	case len(st.Scopes.Missing) == 0:
+		fmt.Fprintf(stdout, "Scopes:  %s\n", strings.Join(st.Scopes.Granted, " "))
+	default:
+		fmt.Fprintf(stdout, "Scopes:  %s\n", strings.Join(st.Scopes.Granted, " "))
+		fmt.Fprintf(stdout, "Missing: %s\n", strings.Join(st.Scopes.Missing, " "))
+	}
+	fmt.Fprintf(stdout, "Expires: %s (in %s)\n", tok.ExpiresAt.Local().Format(time.RFC3339), time.Until(tok.ExpiresAt).Round(time.Second))
+	if st.TokenCache != "" {
+		enc := ""
+		if st.Encrypted {
+			enc = " (encrypted)"
+		}
+		fmt.Fprintf(stdout, "Cache:   %s%s\n", st.TokenCache, enc)
+	} else {
+		fmt.Fprintln(stdout, "Cache:   off (token_cache = \"off\"; every run refreshes)")
+	}
+	if len(st.Scopes.Missing) > 0 {
+		fmt.Fprintln(stderr, "Some commands will fail for lack of scopes. Re-run `spotctl auth login` to grant the default scopes.")
+	}
+	if me.Product != "" && me.Product != "premium" {
+		fmt.Fprintln(stderr, "Playback control via the Web API needs Spotify Premium.")
+	}
+	return nil
+}
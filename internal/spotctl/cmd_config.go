@@ -0,0 +1,250 @@
+package spotctl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/joshp123/spotctl/internal/spotify"
+	"github.com/joshp123/spotctl/internal/toml"
+)
+
+func (c *cli) cmdConfig(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		printConfigUsage(stderr)
+		return &exitError{code: 2, err: errors.New("missing subcommand for config (get, set, path, show)")}
+	}
+	sub := args[0]
+	args = args[1:]
+	if sub == "-h" || sub == "--help" || sub == "help" {
+		printConfigUsage(stdout)
+		return nil
+	}
+	// The active profile's settings count for get/show too.
+	if c.prof == nil {
+		if name, prof, err := loadActiveProfile(c.profileFlag); err == nil {
+			c.profName, c.prof = name, prof
+		}
+	}
+	switch sub {
+	case "path":
+		if len(args) != 0 {
+			return &exitError{code: 2, err: errors.New("config path takes no args")}
+		}
+		p, err := configPath()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, p)
+		return nil
+	case "get":
+		return c.cmdConfigGet(args, stdout)
+	case "set":
+		return c.cmdConfigSet(args, stderr)
+	case "show":
+		return c.cmdConfigShow(args, stdout)
+	default:
+		return &exitError{code: 2, err: fmt.Errorf("unknown config subcommand: %s", sub)}
+	}
+}
+
+func printConfigUsage(w io.Writer) {
+	var profileKeys []string
+	for _, s := range settings {
+		if s.profile != nil {
+			profileKeys = append(profileKeys, s.key)
+		}
+	}
+	fmt.Fprintf(w, `Usage:
+  spotctl config path
+  spotctl config get <key> [--reveal]
+  spotctl config set <key> <value>   (value "" removes the key; aliases.<name> sets an alias)
+  spotctl config show [--json] [--reveal]
+
+A setting's value is the first one set of:
+  1. the command's flag (--device, --output, ...)
+  2. the active profile (--profile, SPOTCTL_PROFILE or profile use), for the
+     keys a profile can set: %s.
+     A profile replaces the env vars it covers.
+  3. the env var (see config show)
+  4. config.toml
+config show lists every key with the layer its value came from.
+`, strings.Join(profileKeys, ", "))
+}
+
+// configLoaded reports a broken config file (the other commands refuse to run
+// with one; config get/show need it too).
+func (c *cli) configLoaded() error {
+	if c.cfgErr != nil {
+		return &exitError{code: 2, err: c.cfgErr}
+	}
+	return nil
+}
+
+// cmdConfigGet prints the effective value; unset keys exit 1 with no output.
+func (c *cli) cmdConfigGet(args []string, stdout io.Writer) error {
+	reveal, args := popBoolFlag(args, "--reveal")
+	if len(args) != 1 {
+		return &exitError{code: 2, err: errors.New("usage: spotctl config get <key> [--reveal]")}
+	}
+	if err := c.configLoaded(); err != nil {
+		return err
+	}
+	key := args[0]
+	if name, ok := strings.CutPrefix(key, "aliases."); ok {
+		v, ok := c.cfg.Aliases[name]
+		if !ok {
+			return &exitError{code: 1}
+		}
+		fmt.Fprintln(stdout, v)
+		return nil
+	}
+	if _, ok := lookupSetting(key); !ok {
+		return &exitError{code: 2, err: fmt.Errorf("unknown config key %q (see `spotctl config show`)", key)}
+	}
+	v, _ := c.setting(key)
+	if v == "" {
+		return &exitError{code: 1}
+	}
+	if !reveal {
+		v = redactSetting(key, v)
+	}
+	fmt.Fprintln(stdout, v)
+	return nil
+}
+
+// cmdConfigSet edits config.toml in place; an empty value removes the key.
+func (c *cli) cmdConfigSet(args []string, stderr io.Writer) error {
+	if len(args) != 2 {
+		return &exitError{code: 2, err: errors.New(`usage: spotctl config set <key> <value> (value "" removes the key)`)}
+	}
+	key, value := args[0], strings.TrimSpace(args[1])
+
+	table, name := "", key
+	var typed any = value
+	if alias, ok := strings.CutPrefix(key, "aliases."); ok {
+		table, name = "aliases", alias
+		if alias == "" || builtinCommands[alias] {
+			return &exitError{code: 2, err: fmt.Errorf("alias %q would shadow a built-in command", alias)}
+		}
+		if _, err := splitWords(value); err != nil {
+			return &exitError{code: 2, err: fmt.Errorf("alias %s: %w", alias, err)}
+		}
+	} else {
+		s, ok := lookupSetting(key)
+		if !ok {
+			return &exitError{code: 2, err: fmt.Errorf("unknown config key %q (see `spotctl config show`)", key)}
+		}
+		if value != "" {
+			v, err := validateSetting(s, value)
+			if err != nil {
+				return &exitError{code: 2, err: err}
+			}
+			typed = v
+		}
+	}
+
+	path, raw, _, err := readConfig()
+	if err != nil && raw == nil {
+		return err
+	}
+	var out []byte
+	if value == "" {
+		out, err = toml.Delete(raw, table, name)
+	} else {
+		out, err = toml.Set(raw, table, name, typed)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	// Don't write a file spotctl can't read back.
+	var check fileConfig
+	if err := toml.Decode(out, &check); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if err := spotify.WriteFileAtomic(path, out); err != nil {
+		return err
+	}
+	if s, ok := lookupSetting(key); ok && value != "" {
+		if v := os.Getenv(s.env); strings.TrimSpace(v) != "" {
+			fmt.Fprintf(stderr, "Note: %s is set and overrides the config file.\n", s.env)
+		}
+	}
+	return nil
+}
+
+type configShowEntry struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source,omitempty"` // profile <name>, env <VAR> or config
+	Env    string `json:"env"`
+	Doc    string `json:"doc"`
+}
+
+func (c *cli) cmdConfigShow(args []string, stdout io.Writer) error {
+	jsonOut, args := popBoolFlag(args, "--json")
+	reveal, args := popBoolFlag(args, "--reveal")
+	if len(args) != 0 {
+		return &exitError{code: 2, err: errors.New("usage: spotctl config show [--json] [--reveal]")}
+	}
+	if err := c.configLoaded(); err != nil {
+		return err
+	}
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+
+	entries := make([]configShowEntry, 0, len(settings))
+	for _, s := range settings {
+		v, src := c.setting(s.key)
+		if !reveal {
+			v = redactSetting(s.key, v)
+		}
+		entries = append(entries, configShowEntry{Key: s.key, Value: v, Source: src, Env: s.env, Doc: s.doc})
+	}
+	aliases := c.cfg.Aliases
+	if aliases == nil {
+		aliases = map[string]string{}
+	}
+
+	if jsonOut {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			Path     string            `json:"path"`
+			Profile  string            `json:"profile,omitempty"`
+			Settings []configShowEntry `json:"settings"`
+			Aliases  map[string]string `json:"aliases"`
+		}{path, c.profName, entries, aliases})
+	}
+
+	fmt.Fprintf(stdout, "# %s\n", path)
+	if c.profName != "" {
+		fmt.Fprintf(stdout, "# profile: %s\n", c.profName)
+	}
+	for _, e := range entries {
+		if e.Source == "" {
+			fmt.Fprintf(stdout, "%-21s (unset) %s; env %s\n", e.Key, e.Doc, e.Env)
+			continue
+		}
+		fmt.Fprintf(stdout, "%-21s %s  [%s]\n", e.Key, e.Value, e.Source)
+	}
+	if len(aliases) > 0 {
+		names := make([]string, 0, len(aliases))
+		for n := range aliases {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		fmt.Fprintln(stdout, "\naliases:")
+		for _, n := range names {
+			fmt.Fprintf(stdout, "  %-19s %s\n", n, aliases[n])
+		}
+	}
+	return nil
+}
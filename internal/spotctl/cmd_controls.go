@@ -14,7 +14,7 @@ func (c *cli) cmdPause(ctx context.Context, args []string, stdout, stderr io.Wri
 	if err != nil {
 		return err
 	}
-	if err := c.ensureClient(ctx); err != nil {
+	if err := c.ensureClient(ctx, scopesControl...); err != nil {
 		return err
 	}
 	deviceID, err := c.resolveOptionalDeviceID(ctx, selector)
@@ -33,7 +33,7 @@ func (c *cli) cmdNext(ctx context.Context, args []string, stdout, stderr io.Writ
 	if err != nil {
 		return err
 	}
-	if err := c.ensureClient(ctx); err != nil {
+	if err := c.ensureClient(ctx, scopesControl...); err != nil {
 		return err
 	}
 	deviceID, err := c.resolveOptionalDeviceID(ctx, selector)
@@ -52,7 +52,7 @@ func (c *cli) cmdPrevious(ctx context.Context, args []string, stdout, stderr io.
 	if err != nil {
 		return err
 	}
-	if err := c.ensureClient(ctx); err != nil {
+	if err := c.ensureClient(ctx, scopesControl...); err != nil {
 		return err
 	}
 	deviceID, err := c.resolveOptionalDeviceID(ctx, selector)
@@ -81,21 +81,17 @@ func (c *cli) cmdVolume(ctx context.Context, args []string, stdout, stderr io.Wr
 		return &exitError{code: 2, err: errors.New("volume must be an int 0-100")}
 	}
 
-	if err := c.ensureClient(ctx); err != nil {
+	if err := c.ensureClient(ctx, scopesControl...); err != nil {
 		return err
 	}
 
-	var deviceID *string
+	var selector *string
 	if *deviceSel != "" {
-		dev, devs, err := c.client.ResolveDevice(ctx, *deviceSel)
-		if err != nil {
-			return err
-		}
-		if dev == nil {
-			return &exitError{code: 3, err: errors.New(strictDeviceMessage(*deviceSel, devs))}
-		}
-		tmp := dev.ID
-		deviceID = &tmp
+		selector = deviceSel
+	}
+	deviceID, err := c.resolveOptionalDeviceID(ctx, selector)
+	if err != nil {
+		return err
 	}
 
 	if err := c.client.Volume(ctx, deviceID, pct); err != nil {
@@ -0,0 +1,301 @@
+package spotctl
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/joshp123/spotctl/internal/rpc"
+)
+
+// spotctl daemon keeps one authenticated client (and its access token) alive
+// and runs CLI commands on behalf of short-lived invocations over a Unix
+// socket. Wire format is JSON-RPC 2.0, one message per line:
+//
+//	-> {"jsonrpc":"2.0","id":1,"method":"run","params":{"args":["status","--json"],"fingerprint":"…"}}
+//	<- {"jsonrpc":"2.0","id":1,"result":{"code":0,"stdout":"...","stderr":""}}
+//
+// A command must behave as if run directly, so the client also sends its
+// per-command settings and a fingerprint of everything the daemon bakes
+// into its shared client (profile, credentials env, API bases, token cache,
+// …; paths made absolute against each side's cwd). On a mismatch the daemon
+// refuses and the client runs the command itself. The output format is
+// already applied to the args (see prepareArgs).
+
+type daemonRunParams struct {
+	Args        []string          `json:"args"`
+	Settings    map[string]string `json:"settings,omitempty"` // see daemonRequestSettings
+	Fingerprint string            `json:"fingerprint"`
+}
+
+type daemonRunResult struct {
+	Code    int    `json:"code"`
+	Stdout  string `json:"stdout"`
+	Stderr  string `json:"stderr"`
+	Refused string `json:"refused,omitempty"` // not run; the client should run it
+}
+
+// daemonRequestSettings apply per command, so the client's values are sent
+// with each request; all other settings are part of the fingerprint.
+var daemonRequestSettings = map[string]bool{"device": true, "output": true}
+
+// daemonEnv are the env vars besides settings that shape the daemon's
+// client.
+var daemonEnv = []string{
+	"SPOTIFY_CLIENT_ID", "SPOTIFY_CLIENT_SECRET", "SPOTIFY_REFRESH_TOKEN",
+	"SPOTIFY_CLIENT_ID_FILE", "SPOTIFY_CLIENT_SECRET_FILE", "SPOTIFY_REFRESH_TOKEN_FILE",
+	"SPOTCTL_REFRESH_TOKEN_HOOK", "SPOTCTL_CACHE_DIR",
+}
+
+// clientFingerprint hashes the setup the shared client was built from.
+func (c *cli) clientFingerprint() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "profile=%s\n", c.profName)
+	for _, s := range settings {
+		if daemonRequestSettings[s.key] {
+			continue
+		}
+		v := c.settingValue(s.key)
+		if s.isPath && v != "off" {
+			v = absPath(v)
+		}
+		fmt.Fprintf(h, "%s=%s\n", s.key, v)
+	}
+	for _, k := range daemonEnv {
+		v := strings.TrimSpace(os.Getenv(k))
+		// Secrets may be file paths; relative ones depend on the cwd.
+		if strings.HasSuffix(k, "_FILE") || strings.Contains(v, "/") || k == "SPOTCTL_CACHE_DIR" {
+			v = absPath(v)
+		}
+		fmt.Fprintf(h, "%s=%s\n", k, v)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func absPath(p string) string {
+	if p == "" {
+		return ""
+	}
+	if a, err := filepath.Abs(expandPath(p)); err == nil {
+		return a
+	}
+	return p
+}
+
+// Commands the daemon runs. Everything else (auth flows, other long-running
+// commands, anything reading stdin) always runs in-process.
+var daemonCommands = map[string]bool{
+	"device": true, "status": true, "transfer": true, "play": true,
+	"pause": true, "next": true, "previous": true, "prev": true,
+	"volume": true, "playlist": true, "search": true,
+}
+
+func daemonForwardable(args []string) bool {
+	if len(args) == 0 || !daemonCommands[args[0]] {
+		return false
+	}
+	// --trace/--record/replay act on this process's HTTP traffic; the
+	// daemon's shared client can't honor them per command.
+	if os.Getenv("SPOTCTL_RECORD") != "" || os.Getenv("SPOTCTL_REPLAY") != "" {
+		return false
+	}
+	for _, a := range args {
+		switch flagName(a) {
+		case "stdin", "h", "help", "trace", "record":
+			return false
+		}
+	}
+	return true
+}
+
+// flagName is the name of a -flag/--flag[=value] argument, or "".
+func flagName(a string) string {
+	if !strings.HasPrefix(a, "-") {
+		return ""
+	}
+	name, _, _ := strings.Cut(strings.TrimLeft(a, "-"), "=")
+	return name
+}
+
+// defaultSocketPath gives each profile its own daemon (daemon-<profile>.sock),
+// since a daemon holds one account's client.
+func defaultSocketPath(profile string) string {
+	if p := strings.TrimSpace(os.Getenv("SPOTCTL_SOCKET")); p != "" {
+		return expandPath(p)
+	}
+	name := "daemon.sock"
+	if profile != "" {
+		name = "daemon-" + profile + ".sock"
+	}
+	return filepath.Join(runtimeDir(), name)
+}
+
+// runtimeDir holds per-user sockets and other ephemeral state.
+func runtimeDir() string {
+	if d := strings.TrimSpace(os.Getenv("XDG_RUNTIME_DIR")); d != "" {
+		return filepath.Join(d, "spotctl")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("spotctl-%d", os.Getuid()))
+}
+
+// runViaDaemon forwards the invocation to a running daemon. ok=false means
+// "no usable daemon" and the caller should run the command itself.
+func (c *cli) runViaDaemon(ctx context.Context, args []string, stdout, stderr io.Writer) (code int, ok bool) {
+	// The profile picks the daemon (socket); the daemon already runs as it.
+	profileFlag, _, args, err := popStringFlag(args, "--profile")
+	if err != nil || os.Getenv("SPOTCTL_NO_DAEMON") != "" || !daemonForwardable(args) || c.cfgErr != nil {
+		return 0, false
+	}
+	name, prof, err := loadActiveProfile(profileFlag)
+	if err != nil {
+		return 0, false
+	}
+	req := *c
+	req.profName, req.prof = name, prof
+	params := daemonRunParams{Args: args, Settings: map[string]string{}, Fingerprint: req.clientFingerprint()}
+	for key := range daemonRequestSettings {
+		params.Settings[key] = req.settingValue(key)
+	}
+
+	path := defaultSocketPath(name)
+	if _, err := os.Stat(path); err != nil {
+		return 0, false
+	}
+	d := net.Dialer{Timeout: 250 * time.Millisecond}
+	conn, err := d.DialContext(ctx, "unix", path)
+	if err != nil {
+		// Stale socket (daemon died): fall back to running locally.
+		return 0, false
+	}
+	defer conn.Close()
+
+	var res daemonRunResult
+	if err := rpc.Call(ctx, conn, "run", params, &res); err != nil {
+		// The command may or may not have run; don't retry it locally.
+		fmt.Fprintf(stderr, "spotctl daemon (%s): %v\n", path, err)
+		return 1, true
+	}
+	if res.Refused != "" {
+		return 0, false
+	}
+	_, _ = io.WriteString(stdout, res.Stdout)
+	_, _ = io.WriteString(stderr, res.Stderr)
+	return res.Code, true
+}
+
+func (c *cli) cmdDaemon(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("daemon", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	socket := fs.String("socket", "", "Unix socket path (default: $SPOTCTL_SOCKET or $XDG_RUNTIME_DIR/spotctl/daemon[-<profile>].sock)")
+	cacheTTL := fs.Duration("cache-ttl", 2*time.Second, "Cache device list + playback state this long (0 disables)")
+	if err := parseFlags(fs, args, stderr); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return &exitError{code: 2, err: errors.New("daemon takes no positional args")}
+	}
+
+	path := *socket
+	if path == "" {
+		path = defaultSocketPath(c.profName)
+	}
+	path = expandPath(path)
+
+	c.playerCacheTTL = *cacheTTL
+	if err := c.ensureClient(ctx); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	if conn, err := net.DialTimeout("unix", path, 250*time.Millisecond); err == nil {
+		_ = conn.Close()
+		return fmt.Errorf("another spotctl daemon is already listening on %s", path)
+	}
+	_ = os.Remove(path) // stale socket from a crashed daemon
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+	if err := os.Chmod(path, 0o600); err != nil {
+		_ = ln.Close()
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		_ = ln.Close()
+	}()
+
+	fmt.Fprintf(stderr, "spotctl daemon listening on %s\n", path)
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			_ = rpc.Serve(ctx, conn, conn, c.daemonHandler)
+		}()
+	}
+}
+
+func (c *cli) daemonHandler(ctx context.Context, method string, params json.RawMessage) (any, error) {
+	switch method {
+	case "ping":
+		return map[string]any{"pid": os.Getpid()}, nil
+	case "run":
+		var p daemonRunParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &rpc.Error{Code: rpc.CodeInvalidParams, Message: err.Error()}
+		}
+		if !daemonForwardable(p.Args) {
+			return nil, &rpc.Error{Code: rpc.CodeInvalidParams, Message: fmt.Sprintf("command not served by daemon: %q", p.Args)}
+		}
+		if p.Fingerprint != c.clientFingerprint() {
+			return daemonRunResult{Refused: "the daemon runs with different settings or credentials"}, nil
+		}
+		var out, errOut bytes.Buffer
+		code := c.forRequest(p.Settings).main(ctx, p.Args, &out, &errOut)
+		return daemonRunResult{Code: code, Stdout: out.String(), Stderr: errOut.String()}, nil
+	default:
+		return nil, &rpc.Error{Code: rpc.CodeMethodNotFound, Message: "unknown method: " + method}
+	}
+}
+
+// forRequest is a copy of c for one daemon request: commands may tweak cli
+// fields, so nothing mutable is shared except the client itself.
+func (c *cli) forRequest(forced map[string]string) *cli {
+	sub := *c
+	if c.prof != nil {
+		p := *c.prof
+		sub.prof = &p
+	}
+	sub.cfg.Aliases = make(map[string]string, len(c.cfg.Aliases))
+	for k, v := range c.cfg.Aliases {
+		sub.cfg.Aliases[k] = v
+	}
+	sub.forced = make(map[string]string, len(forced))
+	for k, v := range forced {
+		if daemonRequestSettings[k] {
+			sub.forced[k] = v
+		}
+	}
+	return &sub
+}
@@ -0,0 +1,87 @@
+package spotctl
+
+import (
+	"os"
+	"testing"
+)
+
+func TestClientFingerprint(t *testing.T) {
+	t.Setenv("SPOTCTL_TOKEN_CACHE", "tok.json")
+	t.Setenv("SPOTCTL_DEVICE", "Kitchen")
+	c := &cli{}
+	base := c.clientFingerprint()
+
+	// Per-command settings travel with the request instead.
+	t.Setenv("SPOTCTL_DEVICE", "Desk")
+	if c.clientFingerprint() != base {
+		t.Fatal("device changed the fingerprint")
+	}
+
+	t.Setenv("SPOTIFY_API_BASE", "http://127.0.0.1:1")
+	if c.clientFingerprint() == base {
+		t.Fatal("api base didn't change the fingerprint")
+	}
+	t.Setenv("SPOTIFY_API_BASE", "")
+
+	// A relative path means another file from another directory.
+	wd, _ := os.Getwd()
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	if c.clientFingerprint() == base {
+		t.Fatal("relative token cache from another cwd matched")
+	}
+}
+
+func TestForRequest(t *testing.T) {
+	c := &cli{
+		profName: "work",
+		prof:     &profile{Device: "Office"},
+		cfg:      fileConfig{Aliases: map[string]string{"np": "status"}},
+	}
+	sub := c.forRequest(map[string]string{"device": "Kitchen", "api_base": "http://evil"})
+	sub.prof.Device = "changed"
+	sub.cfg.Aliases["np"] = "changed"
+	if c.prof.Device != "Office" || c.cfg.Aliases["np"] != "status" {
+		t.Fatal("request copy shares state with the daemon")
+	}
+	if got := sub.settingValue("device"); got != "Kitchen" {
+		t.Fatalf("device=%q", got)
+	}
+	// Only per-command settings can be forced by a client.
+	if got, _ := sub.setting("api_base"); got == "http://evil" {
+		t.Fatal("client forced a client-bound setting")
+	}
+}
+
+func TestDaemonForwardable(t *testing.T) {
+	t.Setenv("SPOTCTL_RECORD", "")
+	t.Setenv("SPOTCTL_REPLAY", "")
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{[]string{"status", "--json"}, true},
+		{[]string{"play", "--device", "Desk", "spotify:track:x"}, true},
+		{[]string{"search", "tracks", "stdin"}, true},
+		{[]string{"auth", "status"}, false},
+		{[]string{"playlist", "add-query", "--stdin"}, false},
+		{[]string{"playlist", "add-query", "-stdin"}, false},
+		{[]string{"playlist", "add-query", "--stdin=true"}, false},
+		{[]string{"playlist", "add-query", "-stdin=1"}, false},
+		{[]string{"status", "--trace"}, false},
+		{[]string{"status", "-trace=true"}, false},
+		{[]string{"status", "--record", "out.har"}, false},
+		{[]string{"status", "-record", "out.har"}, false},
+		{[]string{"status", "--record=out.har"}, false},
+		{[]string{"status", "-h"}, false},
+		{[]string{"status", "--help=true"}, false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := daemonForwardable(tt.args); got != tt.want {
+			t.Errorf("daemonForwardable(%q) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
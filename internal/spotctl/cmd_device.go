@@ -36,7 +36,7 @@ func (c *cli) cmdDeviceList(ctx context.Context, args []string, stdout, stderr i
 		*jsonOut = true
 	}
 
-	if err := c.ensureClient(ctx); err != nil {
+	if err := c.ensureClient(ctx, scopesReadPlayback...); err != nil {
 		return err
 	}
 
@@ -0,0 +1,109 @@
+package spotctl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/joshp123/spotctl/internal/rpc"
+)
+
+// spotctl mcp serves the Model Context Protocol over stdio (newline-delimited
+// JSON-RPC). Tools mirror the CLI and share its guard rails: device targeting
+// is strict and playlist adds only accept track URIs that GetTrack confirms.
+
+var mcpProtocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+type mcpContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type mcpToolResult struct {
+	Content           []mcpContent `json:"content"`
+	StructuredContent any          `json:"structuredContent,omitempty"`
+	IsError           bool         `json:"isError,omitempty"`
+}
+
+func (c *cli) cmdMCP(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("mcp", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	if err := parseFlags(fs, args, stderr); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return &exitError{code: 2, err: errors.New("mcp takes no positional args")}
+	}
+
+	tools := toolSpecs()
+	byName := toolsByName()
+
+	return rpc.Serve(ctx, os.Stdin, stdout, func(ctx context.Context, method string, params json.RawMessage) (any, error) {
+		switch method {
+		case "initialize":
+			var p struct {
+				ProtocolVersion string `json:"protocolVersion"`
+			}
+			_ = json.Unmarshal(params, &p)
+			version := mcpProtocolVersions[0]
+			for _, v := range mcpProtocolVersions {
+				if v == p.ProtocolVersion {
+					version = v
+				}
+			}
+			return map[string]any{
+				"protocolVersion": version,
+				"capabilities":    map[string]any{"tools": map[string]any{}},
+				"serverInfo":      map[string]any{"name": "spotctl", "version": "0.1.0"},
+				"instructions":    "Device targeting is strict: if a device is not listed by the devices tool, ask the user to open Spotify on it. Never invent Spotify URIs; use search and copy the returned uri.",
+			}, nil
+		case "ping":
+			return map[string]any{}, nil
+		case "tools/list":
+			return map[string]any{"tools": tools}, nil
+		case "tools/call":
+			var p struct {
+				Name      string          `json:"name"`
+				Arguments json.RawMessage `json:"arguments"`
+			}
+			if err := json.Unmarshal(params, &p); err != nil {
+				return nil, &rpc.Error{Code: rpc.CodeInvalidParams, Message: err.Error()}
+			}
+			t, ok := byName[p.Name]
+			if !ok {
+				return nil, &rpc.Error{Code: rpc.CodeInvalidParams, Message: "unknown tool: " + p.Name}
+			}
+			if len(p.Arguments) == 0 {
+				p.Arguments = json.RawMessage("{}")
+			}
+			return c.callMCPTool(ctx, t, p.Arguments), nil
+		default:
+			if strings.HasPrefix(method, "notifications/") {
+				return nil, nil
+			}
+			return nil, &rpc.Error{Code: rpc.CodeMethodNotFound, Message: "unknown method: " + method}
+		}
+	})
+}
+
+func (c *cli) callMCPTool(ctx context.Context, t toolSpec, args json.RawMessage) mcpToolResult {
+	res, err := t.run(ctx, c, args)
+	if err != nil {
+		e := newErrorBody(err)
+		text := e.Message
+		if e.Hint != "" {
+			text += "\n" + e.Hint
+		}
+		return mcpToolResult{
+			Content:           []mcpContent{{Type: "text", Text: text}},
+			StructuredContent: map[string]any{"error": e},
+			IsError:           true,
+		}
+	}
+	b, _ := json.MarshalIndent(res, "", "  ")
+	return mcpToolResult{Content: []mcpContent{{Type: "text", Text: string(b)}}, StructuredContent: res}
+}
@@ -0,0 +1,46 @@
+package spotctl
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/joshp123/spotctl/internal/dbus"
+	"github.com/joshp123/spotctl/internal/mpris"
+)
+
+func (c *cli) cmdMPRIS(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("mpris", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	interval := fs.Duration("interval", 2*time.Second, "Playback poll interval")
+	if err := parseFlags(fs, args, stderr); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return &exitError{code: 2, err: errors.New("mpris takes no positional args")}
+	}
+
+	if err := c.ensureClient(ctx, scopesControl...); err != nil {
+		return err
+	}
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	fmt.Fprintf(stderr, "Exporting %s on the session bus. Ctrl-C to stop.\n", mpris.BusName)
+	err = mpris.Serve(ctx, conn, c.client, mpris.Options{
+		Interval: *interval,
+		Logf: func(format string, args ...any) {
+			fmt.Fprintf(stderr, format+"\n", args...)
+		},
+	})
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
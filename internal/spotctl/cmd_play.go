@@ -13,58 +13,66 @@ import (
 func (c *cli) cmdPlay(ctx context.Context, args []string, stdout, stderr io.Writer) error {
 	fs := flag.NewFlagSet("play", flag.ContinueOnError)
 	fs.SetOutput(io.Discard)
-	deviceSel := fs.String("device", "", "Device name or id (strict)")
+	deviceSel := fs.String("device", "", "Device name or id (strict; default: the profile's device)")
 	if err := parseFlags(fs, args, stderr); err != nil {
 		return err
 	}
 	if *deviceSel == "" {
-		return &exitError{code: 2, err: errors.New("missing --device")}
+		*deviceSel = c.defaultDevice()
+	}
+	if *deviceSel == "" {
+		return &exitError{code: 2, err: errors.New("missing --device (or set a profile default: spotctl profile add <name> --device <name|id>)")}
 	}
 	if fs.NArg() != 1 {
 		return &exitError{code: 2, err: errors.New("play requires exactly one argument: spotify URI or search query")}
 	}
 	q := fs.Arg(0)
 
-	if err := c.ensureClient(ctx); err != nil {
+	if err := c.ensureClient(ctx, scopesControl...); err != nil {
+		return err
+	}
+
+	dev, err := c.resolveDeviceStrict(ctx, *deviceSel)
+	if err != nil {
 		return err
 	}
 
-	dev, devs, err := c.client.ResolveDevice(ctx, *deviceSel)
+	uri, req, picked, err := c.resolvePlayTarget(ctx, q)
 	if err != nil {
 		return err
 	}
-	if dev == nil {
-		return &exitError{code: 3, err: errors.New(strictDeviceMessage(*deviceSel, devs))}
+	if picked != nil {
+		fmt.Fprintf(stderr, "Search: %q -> %s — %s (%s)\n", q, picked.Name, picked.DisplayArtists(), picked.URI)
+	}
+
+	if err := c.client.Play(ctx, dev.ID, req); err != nil {
+		return err
 	}
+	fmt.Fprintf(stdout, "Play on %s: %s\n", dev.Name, uri)
+	return nil
+}
 
+// resolvePlayTarget turns a URI/URL or free-text query into a play request.
+// picked is set when the target came from search (callers should report it).
+func (c *cli) resolvePlayTarget(ctx context.Context, q string) (uri string, req spotify.PlayRequest, picked *spotify.Track, err error) {
 	uri, kind, err := spotify.NormalizeURI(q)
 	if err != nil {
-		return &exitError{code: 2, err: err}
+		return "", req, nil, &exitError{code: 2, err: err}
 	}
 
 	if kind == spotify.URIKindUnknown {
 		track, err := c.client.SearchTopTrack(ctx, q)
 		if err != nil {
-			return err
+			return "", req, nil, err
 		}
 		uri = track.URI
 		kind = spotify.URIKindTrack
-		fmt.Fprintf(stderr, "Search: %q -> %s — %s (%s)\n", q, track.Name, track.DisplayArtists(), track.URI)
-	}
-
-	var req spotify.PlayRequest
-	switch kind {
-	case spotify.URIKindTrack, spotify.URIKindEpisode:
-		req = spotify.PlayRequest{URIs: []string{uri}}
-	case spotify.URIKindAlbum, spotify.URIKindPlaylist, spotify.URIKindArtist, spotify.URIKindShow:
-		req = spotify.PlayRequest{ContextURI: uri}
-	default:
-		return &exitError{code: 2, err: fmt.Errorf("unsupported URI kind for play: %s (%s)", kind, uri)}
+		picked = &track
 	}
 
-	if err := c.client.Play(ctx, dev.ID, req); err != nil {
-		return err
+	req, err = spotify.PlayRequestFor(uri, kind)
+	if err != nil {
+		return "", req, nil, &exitError{code: 2, err: err}
 	}
-	fmt.Fprintf(stdout, "Play on %s: %s\n", dev.Name, uri)
-	return nil
+	return uri, req, picked, nil
 }
@@ -33,6 +33,13 @@ func (c *cli) cmdPlaylist(ctx context.Context, args []string, stdout, stderr io.
 	}
 }
 
+func playlistCreateScope(public bool) string {
+	if public {
+		return "playlist-modify-public"
+	}
+	return "playlist-modify-private"
+}
+
 func (c *cli) cmdPlaylistCreate(ctx context.Context, args []string, stdout, stderr io.Writer) error {
 	jsonTrailing, args := popBoolFlag(args, "--json")
 	fs := flag.NewFlagSet("playlist create", flag.ContinueOnError)
@@ -58,7 +65,7 @@ func (c *cli) cmdPlaylistCreate(ctx context.Context, args []string, stdout, stde
 		return &exitError{code: 2, err: errors.New("playlist create takes no positional args")}
 	}
 
-	if err := c.ensureClient(ctx); err != nil {
+	if err := c.ensureClient(ctx, playlistCreateScope(*public)); err != nil {
 		return err
 	}
 
@@ -67,17 +74,8 @@ func (c *cli) cmdPlaylistCreate(ctx context.Context, args []string, stdout, stde
 		return err
 	}
 
-	// Best-effort: enforce private-by-default. Some Spotify accounts appear to ignore the
-	// create-request "public" field and default to public.
-	if !*public {
-		priv := false
-		_ = c.client.UpdatePlaylistDetails(ctx, pl.ID, &priv, nil, nil)
-
-		if det, err := c.client.PlaylistDetails(ctx, pl.ID); err == nil {
-			if det.Public != nil && *det.Public {
-				fmt.Fprintln(stderr, "WARN: Spotify reports this playlist as public. Note: the Spotify setting about ‘new playlists visible on your profile’ is separate from public/secret. To make it private/secret, use the playlist menu (⋯) → ‘Make secret’, or run: spotctl playlist privacy --playlist <id> --private")
-			}
-		}
+	if !*public && !c.enforcePrivate(ctx, pl.ID) {
+		fmt.Fprintln(stderr, "WARN: Spotify reports this playlist as public. Note: the Spotify setting about ‘new playlists visible on your profile’ is separate from public/secret. To make it private/secret, use the playlist menu (⋯) → ‘Make secret’, or run: spotctl playlist privacy --playlist <id> --private")
 	}
 
 	if *jsonOut {
@@ -120,7 +118,7 @@ func (c *cli) cmdPlaylistAdd(ctx context.Context, args []string, stdout, stderr
 		return &exitError{code: 2, err: errors.New("playlist add requires at least one track URI")}
 	}
 
-	if err := c.ensureClient(ctx); err != nil {
+	if err := c.ensureClient(ctx, scopesPlaylistModify...); err != nil {
 		return err
 	}
 
@@ -129,19 +127,55 @@ func (c *cli) cmdPlaylistAdd(ctx context.Context, args []string, stdout, stderr
 		return &exitError{code: 2, err: err}
 	}
 
-	uris := make([]string, 0, fs.NArg())
-	ids := make([]string, 0, fs.NArg())
-	for _, a := range fs.Args() {
+	uris, err := c.validateTrackURIs(ctx, fs.Args())
+	if err != nil {
+		return err
+	}
+
+	res, err := c.client.AddTracksToPlaylist(ctx, pid, uris)
+	if err != nil {
+		return err
+	}
+	if *jsonOut {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(res)
+	}
+	fmt.Fprintf(stdout, "Added %d track(s). Snapshot: %s\n", len(uris), res.SnapshotID)
+	return nil
+}
+
+// enforcePrivate is best-effort: some Spotify accounts appear to ignore the
+// create-request "public" field and default to public. Returns false only if
+// Spotify still reports the playlist as public afterwards.
+func (c *cli) enforcePrivate(ctx context.Context, playlistID string) bool {
+	priv := false
+	_ = c.client.UpdatePlaylistDetails(ctx, playlistID, &priv, nil, nil)
+
+	if det, err := c.client.PlaylistDetails(ctx, playlistID); err == nil {
+		if det.Public != nil && *det.Public {
+			return false
+		}
+	}
+	return true
+}
+
+// validateTrackURIs normalizes track URIs/URLs and checks each one exists, so
+// hallucinated IDs never reach a playlist.
+func (c *cli) validateTrackURIs(ctx context.Context, args []string) ([]string, error) {
+	uris := make([]string, 0, len(args))
+	ids := make([]string, 0, len(args))
+	for _, a := range args {
 		uri, kind, err := spotify.NormalizeURI(a)
 		if err != nil {
-			return &exitError{code: 2, err: err}
+			return nil, &exitError{code: 2, err: err}
 		}
 		if kind != spotify.URIKindTrack {
-			return &exitError{code: 2, err: fmt.Errorf("playlist add only supports track URIs in v1: %s", a)}
+			return nil, &exitError{code: 2, err: fmt.Errorf("playlist add only supports track URIs in v1: %s", a)}
 		}
 		id, err := spotify.TrackIDFromURI(uri)
 		if err != nil {
-			return &exitError{code: 2, err: err}
+			return nil, &exitError{code: 2, err: err}
 		}
 		uris = append(uris, uri)
 		ids = append(ids, id)
@@ -151,22 +185,11 @@ func (c *cli) cmdPlaylistAdd(ctx context.Context, args []string, stdout, stderr
 	for i, id := range ids {
 		t, err := c.client.GetTrack(ctx, id)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		if t.ID == "" {
-			return &exitError{code: 2, err: fmt.Errorf("invalid track uri (not found): %s", uris[i])}
+			return nil, &exitError{code: 2, err: fmt.Errorf("invalid track uri (not found): %s", uris[i])}
 		}
 	}
-
-	res, err := c.client.AddTracksToPlaylist(ctx, pid, uris)
-	if err != nil {
-		return err
-	}
-	if *jsonOut {
-		enc := json.NewEncoder(stdout)
-		enc.SetIndent("", "  ")
-		return enc.Encode(res)
-	}
-	fmt.Fprintf(stdout, "Added %d track(s). Snapshot: %s\n", len(uris), res.SnapshotID)
-	return nil
+	return uris, nil
 }
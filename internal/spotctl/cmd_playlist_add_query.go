@@ -39,6 +39,7 @@ func (c *cli) cmdPlaylistAddQuery(ctx context.Context, args []string, stdout, st
 	fromStdin := fs.Bool("stdin", false, "Read queries from stdin (one per line)")
 	tsv := fs.Bool("tsv", false, "Parse stdin as TSV: <artist>\\t<track>")
 	limit := fs.Int("limit", 3, "Spotify search limit per query (<=50)")
+	concurrency := fs.Int("concurrency", 1, "Queries resolved in parallel")
 	jsonOut := fs.Bool("json", false, "JSON output")
 	if err := parseFlags(fs, args, stderr); err != nil {
 		return err
@@ -49,6 +50,9 @@ func (c *cli) cmdPlaylistAddQuery(ctx context.Context, args []string, stdout, st
 	if *playlistSel == "" {
 		return &exitError{code: 2, err: errors.New("missing --playlist")}
 	}
+	if *concurrency < 1 {
+		return &exitError{code: 2, err: errors.New("--concurrency must be >= 1")}
+	}
 
 	queries := []string{}
 	if *fromStdin {
@@ -72,65 +76,32 @@ func (c *cli) cmdPlaylistAddQuery(ctx context.Context, args []string, stdout, st
 		return &exitError{code: 2, err: err}
 	}
 
-	if err := c.ensureClient(ctx); err != nil {
+	if err := c.ensureClient(ctx, scopesPlaylistModify...); err != nil {
 		return err
 	}
 
 	res := addQueryResult{Playlist: pid, TSV: *tsv, Total: len(queries)}
 
-	uris := []string{}
-	ids := []string{}
+	trimmed := []string{}
 	for _, q := range queries {
-		q = strings.TrimSpace(q)
-		if q == "" {
-			continue
-		}
-
-		items, err := c.client.SearchTracks(ctx, q, *limit)
-		if err != nil {
-			res.Resolved = append(res.Resolved, addQueryResolved{Query: q, Error: err.Error()})
-			continue
-		}
-		if len(items) == 0 {
-			res.Resolved = append(res.Resolved, addQueryResolved{Query: q, Error: "no results"})
-			continue
+		if q = strings.TrimSpace(q); q != "" {
+			trimmed = append(trimmed, q)
 		}
-
-		picked := items[0]
-		res.Resolved = append(res.Resolved, addQueryResolved{Query: q, Track: &picked})
-
-		uri, kind, err := spotify.NormalizeURI(picked.URI)
-		if err != nil {
-			res.Resolved[len(res.Resolved)-1].Error = err.Error()
-			res.Resolved[len(res.Resolved)-1].Track = nil
-			continue
-		}
-		if kind != spotify.URIKindTrack {
-			res.Resolved[len(res.Resolved)-1].Error = fmt.Sprintf("non-track uri: %s", picked.URI)
-			res.Resolved[len(res.Resolved)-1].Track = nil
-			continue
-		}
-		id, err := spotify.TrackIDFromURI(uri)
-		if err != nil {
-			res.Resolved[len(res.Resolved)-1].Error = err.Error()
-			res.Resolved[len(res.Resolved)-1].Track = nil
-			continue
-		}
-		uris = append(uris, uri)
-		ids = append(ids, id)
 	}
 
-	// Validate tracks exist to prevent hallucinated IDs.
+	// Searches + validations run in parallel; results keep query order.
 	validURIs := []string{}
-	for i, id := range ids {
-		t, err := c.client.GetTrack(ctx, id)
-		if err != nil {
-			continue
+	for i, r := range spotify.Batch(ctx, *concurrency, trimmed, func(ctx context.Context, q string) (addQueryResolved, error) {
+		return c.resolveQuery(ctx, q, *limit), nil
+	}) {
+		rr := r.Value
+		if r.Err != nil {
+			rr = addQueryResolved{Query: trimmed[i], Error: r.Err.Error()}
 		}
-		if t.ID == "" {
-			continue
+		res.Resolved = append(res.Resolved, rr)
+		if rr.Track != nil {
+			validURIs = append(validURIs, rr.Track.URI)
 		}
-		validURIs = append(validURIs, uris[i])
 	}
 
 	res.AddedURIs = validURIs
@@ -158,6 +129,42 @@ func (c *cli) cmdPlaylistAddQuery(ctx context.Context, args []string, stdout, st
 	return nil
 }
 
+// resolveQuery searches for q and validates the top hit. Track is only set
+// for a track that exists; otherwise Error says why.
+func (c *cli) resolveQuery(ctx context.Context, q string, limit int) addQueryResolved {
+	items, err := c.client.SearchTracks(ctx, q, limit)
+	if err != nil {
+		return addQueryResolved{Query: q, Error: err.Error()}
+	}
+	if len(items) == 0 {
+		return addQueryResolved{Query: q, Error: "no results"}
+	}
+
+	picked := items[0]
+	uri, kind, err := spotify.NormalizeURI(picked.URI)
+	if err != nil {
+		return addQueryResolved{Query: q, Error: err.Error()}
+	}
+	if kind != spotify.URIKindTrack {
+		return addQueryResolved{Query: q, Error: fmt.Sprintf("non-track uri: %s", picked.URI)}
+	}
+	id, err := spotify.TrackIDFromURI(uri)
+	if err != nil {
+		return addQueryResolved{Query: q, Error: err.Error()}
+	}
+
+	// Validate tracks exist to prevent hallucinated IDs.
+	t, err := c.client.GetTrack(ctx, id)
+	if err != nil {
+		return addQueryResolved{Query: q, Error: fmt.Sprintf("validate %s: %v", uri, err)}
+	}
+	if t.ID == "" {
+		return addQueryResolved{Query: q, Error: fmt.Sprintf("validate %s: track not found", uri)}
+	}
+	picked.URI = uri
+	return addQueryResolved{Query: q, Track: &picked}
+}
+
 func countMisses(rs []addQueryResolved) int {
 	m := 0
 	for _, r := range rs {
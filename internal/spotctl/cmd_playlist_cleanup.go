@@ -19,6 +19,11 @@ type cleanupMatch struct {
 	URI  string `json:"uri"`
 }
 
+type cleanupError struct {
+	ID    string `json:"id"`
+	Error string `json:"error"`
+}
+
 type cleanupResult struct {
 	Prefix       string         `json:"prefix,omitempty"`
 	Regex        string         `json:"regex,omitempty"`
@@ -27,6 +32,7 @@ type cleanupResult struct {
 	DeletedIDs   []string       `json:"deleted_ids,omitempty"`
 	SkippedIDs   []string       `json:"skipped_ids,omitempty"`
 	ErrorDeleted []string       `json:"error_deleted,omitempty"`
+	Errors       []cleanupError `json:"errors,omitempty"`
 }
 
 func (c *cli) cmdPlaylistCleanup(ctx context.Context, args []string, stdout, stderr io.Writer) error {
@@ -37,6 +43,7 @@ func (c *cli) cmdPlaylistCleanup(ctx context.Context, args []string, stdout, std
 	re := fs.String("regex", "", "Match playlist names by regex (in addition to prefix)")
 	apply := fs.Bool("apply", false, "Actually delete/unfollow matched playlists")
 	yes := fs.Bool("yes", false, "Confirm deletion (required with --apply)")
+	concurrency := fs.Int("concurrency", 1, "Playlists unfollowed in parallel")
 	jsonOut := fs.Bool("json", false, "JSON output")
 	if err := parseFlags(fs, args, stderr); err != nil {
 		return err
@@ -47,6 +54,9 @@ func (c *cli) cmdPlaylistCleanup(ctx context.Context, args []string, stdout, std
 	if fs.NArg() != 0 {
 		return &exitError{code: 2, err: errors.New("playlist cleanup takes no positional args")}
 	}
+	if *concurrency < 1 {
+		return &exitError{code: 2, err: errors.New("--concurrency must be >= 1")}
+	}
 	if *apply && !*yes {
 		return &exitError{code: 2, err: errors.New("refusing to delete without --yes (use --apply --yes)")}
 	}
@@ -60,7 +70,12 @@ func (c *cli) cmdPlaylistCleanup(ctx context.Context, args []string, stdout, std
 		}
 	}
 
-	if err := c.ensureClient(ctx); err != nil {
+	// Unfollowing needs the modify scopes; a dry run only lists.
+	scopes := []string{"playlist-read-private"}
+	if *apply {
+		scopes = append(scopes, scopesPlaylistModify...)
+	}
+	if err := c.ensureClient(ctx, scopes...); err != nil {
 		return err
 	}
 
@@ -85,18 +100,24 @@ func (c *cli) cmdPlaylistCleanup(ctx context.Context, args []string, stdout, std
 	}
 
 	if *apply {
+		var ids []string
 		for _, m := range res.Matched {
-			pid, err := spotify.NormalizePlaylistID(m.ID)
-			if err != nil {
+			if _, err := spotify.NormalizePlaylistID(m.ID); err != nil {
 				res.SkippedIDs = append(res.SkippedIDs, m.ID)
 				continue
 			}
-			if err := c.client.UnfollowPlaylist(ctx, pid); err != nil {
-				res.ErrorDeleted = append(res.ErrorDeleted, m.ID)
-				// keep going
+			ids = append(ids, m.ID)
+		}
+		// Failures don't stop the batch; each is reported with its error.
+		for i, r := range spotify.Batch(ctx, *concurrency, ids, func(ctx context.Context, id string) (struct{}, error) {
+			return struct{}{}, c.client.UnfollowPlaylist(ctx, id)
+		}) {
+			if r.Err != nil {
+				res.ErrorDeleted = append(res.ErrorDeleted, ids[i])
+				res.Errors = append(res.Errors, cleanupError{ID: ids[i], Error: r.Err.Error()})
 				continue
 			}
-			res.DeletedIDs = append(res.DeletedIDs, m.ID)
+			res.DeletedIDs = append(res.DeletedIDs, ids[i])
 		}
 	}
 
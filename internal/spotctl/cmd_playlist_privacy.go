@@ -35,7 +35,7 @@ func (c *cli) cmdPlaylistPrivacy(ctx context.Context, args []string, stdout, std
 		return &exitError{code: 2, err: errors.New("playlist privacy takes no positional args")}
 	}
 
-	if err := c.ensureClient(ctx); err != nil {
+	if err := c.ensureClient(ctx, scopesPlaylistModify...); err != nil {
 		return err
 	}
 
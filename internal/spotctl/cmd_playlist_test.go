@@ -0,0 +1,40 @@
+package spotctl
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestPlaylistWritesCheckScopes(t *testing.T) {
+	tests := []struct {
+		args     []string
+		scope    string
+		wantCode int
+	}{
+		{[]string{"playlist", "add", "--playlist", "37i9dQZF1DXcBWIGoYBM5M", "spotify:track:4uLU6hMCjMI75M1A2tKUQC"}, "playlist-read-private", 5},
+		{[]string{"playlist", "add-query", "--playlist", "37i9dQZF1DXcBWIGoYBM5M", "mr brightside"}, "playlist-modify-public", 5},
+		{[]string{"playlist", "privacy", "--playlist", "37i9dQZF1DXcBWIGoYBM5M", "--private"}, "playlist-modify-private", 5},
+		{[]string{"playlist", "cleanup", "--apply", "--yes"}, "playlist-read-private", 5},
+	}
+	for _, tt := range tests {
+		calls := fakeAPI(t, tt.scope)
+		var out, errOut bytes.Buffer
+		code := newCLI().main(context.Background(), tt.args, &out, &errOut)
+		if code != tt.wantCode || !strings.Contains(errOut.String(), "missing required scope") {
+			t.Errorf("%q: exit %d (%s), want %d", tt.args, code, strings.TrimSpace(errOut.String()), tt.wantCode)
+		}
+		if calls.Load() != 0 {
+			t.Errorf("%q: %d API calls before the scope check", tt.args, calls.Load())
+		}
+	}
+
+	// A dry run only lists, so it gets past the check.
+	calls := fakeAPI(t, "playlist-read-private")
+	var out, errOut bytes.Buffer
+	newCLI().main(context.Background(), []string{"playlist", "cleanup"}, &out, &errOut)
+	if calls.Load() == 0 || strings.Contains(errOut.String(), "missing required scope") {
+		t.Errorf("cleanup dry run: calls=%d stderr=%s", calls.Load(), errOut.String())
+	}
+}
@@ -0,0 +1,226 @@
+package spotctl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/joshp123/spotctl/internal/spotify"
+)
+
+func (c *cli) cmdProfile(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		return &exitError{code: 2, err: errors.New("missing subcommand for profile (list, add, remove, use)")}
+	}
+	sub := args[0]
+	args = args[1:]
+	switch sub {
+	case "list", "ls":
+		return c.cmdProfileList(args, stdout, stderr)
+	case "add":
+		return c.cmdProfileAdd(args, stdout, stderr)
+	case "remove", "rm":
+		return c.cmdProfileRemove(args, stdout, stderr)
+	case "use":
+		return c.cmdProfileUse(args, stdout, stderr)
+	default:
+		return &exitError{code: 2, err: fmt.Errorf("unknown profile subcommand: %s", sub)}
+	}
+}
+
+type profileListEntry struct {
+	Name   string `json:"name"`
+	Active bool   `json:"active"`
+	profile
+}
+
+func (c *cli) cmdProfileList(args []string, stdout, stderr io.Writer) error {
+	jsonOut, args := popBoolFlag(args, "--json")
+	if len(args) != 0 {
+		return &exitError{code: 2, err: errors.New("profile list takes no positional args")}
+	}
+	st, err := loadProfiles()
+	if err != nil {
+		return err
+	}
+	active := activeProfileName(c.profileFlag)
+
+	names := make([]string, 0, len(st.Profiles))
+	for n := range st.Profiles {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	entries := make([]profileListEntry, 0, len(names))
+	for _, n := range names {
+		entries = append(entries, profileListEntry{Name: n, Active: n == active, profile: st.Profiles[n]})
+	}
+
+	if jsonOut {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	}
+	if len(entries) == 0 {
+		fmt.Fprintln(stderr, "No profiles. Add one with `spotctl profile add <name>`.")
+		return nil
+	}
+	for _, e := range entries {
+		mark := " "
+		if e.Active {
+			mark = "*"
+		}
+		creds := e.Credentials
+		if creds == "" {
+			creds = "env"
+		}
+		fmt.Fprintf(stdout, "%s %s credentials=%s", mark, e.Name, creds)
+		if e.PublicClient {
+			fmt.Fprint(stdout, " public_client")
+		}
+		if e.Device != "" {
+			fmt.Fprintf(stdout, " device=%q", e.Device)
+		}
+		if e.TokenCache != "" {
+			fmt.Fprintf(stdout, " token_cache=%s", e.TokenCache)
+		}
+		if e.APIBase != "" {
+			fmt.Fprintf(stdout, " api_base=%s", e.APIBase)
+		}
+		if e.AccountsBase != "" {
+			fmt.Fprintf(stdout, " accounts_base=%s", e.AccountsBase)
+		}
+		fmt.Fprintln(stdout)
+	}
+	return nil
+}
+
+// cmdProfileAdd creates a profile, or updates the given fields of an
+// existing one.
+func (c *cli) cmdProfileAdd(args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 || len(args[0]) == 0 || args[0][0] == '-' {
+		return &exitError{code: 2, err: errors.New("usage: spotctl profile add <name> [flags]")}
+	}
+	name := args[0]
+	if err := validProfileName(name); err != nil {
+		return &exitError{code: 2, err: err}
+	}
+	fs := flag.NewFlagSet("profile add", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	creds := fs.String("credentials", "", "Credential source: env, secret-service[:<service>], pass[:<prefix>], gopass[:<prefix>], command:<cmd>")
+	publicClient := fs.Bool("public-client", false, "The credentials are a PKCE-only app without a client secret")
+	tokenCache := fs.String("token-cache", "", "Token cache file (default: $XDG_CACHE_HOME/spotctl/profiles/<name>/token.json)")
+	device := fs.String("device", "", "Default device name or id for play/transfer")
+	apiBase := fs.String("api-base", "", "Web API base URL")
+	accountsBase := fs.String("accounts-base", "", "Accounts (token) base URL")
+	if err := parseFlags(fs, args[1:], stderr); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return &exitError{code: 2, err: errors.New("profile add takes one name")}
+	}
+	if *creds != "" {
+		if _, err := spotify.ParseCredentialSource(*creds); err != nil {
+			return &exitError{code: 2, err: err}
+		}
+	}
+
+	st, err := loadProfiles()
+	if err != nil {
+		return err
+	}
+	p, exists := st.Profiles[name]
+	// Only flags given on this call change an existing profile.
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	if set["credentials"] {
+		p.Credentials = *creds
+	}
+	if set["public-client"] {
+		p.PublicClient = *publicClient
+	}
+	if set["token-cache"] {
+		p.TokenCache = *tokenCache
+	}
+	if set["device"] {
+		p.Device = *device
+	}
+	if set["api-base"] {
+		p.APIBase = *apiBase
+	}
+	if set["accounts-base"] {
+		p.AccountsBase = *accountsBase
+	}
+	if p.TokenCache == "" {
+		p.TokenCache = defaultProfileTokenCache(name)
+	}
+	st.Profiles[name] = p
+	if err := saveProfiles(st); err != nil {
+		return err
+	}
+	if exists {
+		fmt.Fprintf(stderr, "Updated profile %q.\n", name)
+	} else {
+		fmt.Fprintf(stderr, "Added profile %q. Select it with --profile %s, SPOTCTL_PROFILE or `spotctl profile use %s`.\n", name, name, name)
+	}
+	return nil
+}
+
+func (c *cli) cmdProfileRemove(args []string, stdout, stderr io.Writer) error {
+	if len(args) != 1 {
+		return &exitError{code: 2, err: errors.New("usage: spotctl profile remove <name>")}
+	}
+	name := args[0]
+	st, err := loadProfiles()
+	if err != nil {
+		return err
+	}
+	p, ok := st.Profiles[name]
+	if !ok {
+		return &exitError{code: 2, err: fmt.Errorf("unknown profile %q", name)}
+	}
+	delete(st.Profiles, name)
+	if st.Current == name {
+		st.Current = ""
+	}
+	if err := saveProfiles(st); err != nil {
+		return err
+	}
+	fmt.Fprintf(stderr, "Removed profile %q.", name)
+	if p.TokenCache != "" {
+		fmt.Fprintf(stderr, " Its token cache (%s) was left in place.", p.TokenCache)
+	}
+	fmt.Fprintln(stderr)
+	return nil
+}
+
+func (c *cli) cmdProfileUse(args []string, stdout, stderr io.Writer) error {
+	none, args := popBoolFlag(args, "--none")
+	if none == (len(args) == 1) || len(args) > 1 {
+		return &exitError{code: 2, err: errors.New("usage: spotctl profile use (<name> | --none)")}
+	}
+	st, err := loadProfiles()
+	if err != nil {
+		return err
+	}
+	if none {
+		st.Current = ""
+	} else {
+		if _, ok := st.Profiles[args[0]]; !ok {
+			return &exitError{code: 2, err: fmt.Errorf("unknown profile %q (see `spotctl profile list`)", args[0])}
+		}
+		st.Current = args[0]
+	}
+	if err := saveProfiles(st); err != nil {
+		return err
+	}
+	if none {
+		fmt.Fprintln(stderr, "No default profile; spotctl uses the SPOTIFY_*/SPOTCTL_* env vars.")
+	} else {
+		fmt.Fprintf(stderr, "Default profile is now %q.\n", st.Current)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package spotctl
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/joshp123/spotctl/internal/scrobble"
+	"github.com/joshp123/spotctl/internal/spotify"
+)
+
+func (c *cli) cmdScrobble(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("scrobble", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	baseURL := fs.String("listenbrainz-url", "https://api.listenbrainz.org", "ListenBrainz-compatible API base URL")
+	tokenFile := fs.String("token-file", "", "File containing the ListenBrainz user token")
+	interval := fs.Duration("interval", 5*time.Second, "Playback poll interval")
+	stateDir := fs.String("state-dir", "", "Directory for the offline spool + dedup log (default: $XDG_STATE_HOME/spotctl/scrobble)")
+	if err := parseFlags(fs, args, stderr); err != nil {
+		return err
+	}
+	if *tokenFile == "" {
+		return &exitError{code: 2, err: errors.New("missing --token-file")}
+	}
+	if fs.NArg() != 0 {
+		return &exitError{code: 2, err: errors.New("scrobble takes no positional args")}
+	}
+
+	b, err := os.ReadFile(expandPath(*tokenFile))
+	if err != nil {
+		return fmt.Errorf("read --token-file: %w", err)
+	}
+	token := strings.TrimSpace(string(b))
+	if token == "" {
+		return fmt.Errorf("--token-file is empty: %s", *tokenFile)
+	}
+
+	dir := *stateDir
+	if dir == "" {
+		dir = filepath.Join(defaultStateDir(), "scrobble")
+	}
+	store, err := scrobble.OpenStore(expandPath(dir))
+	if err != nil {
+		return fmt.Errorf("open scrobble state: %w", err)
+	}
+
+	if err := c.ensureClient(ctx, scopesReadPlayback...); err != nil {
+		return err
+	}
+
+	s := scrobble.New(scrobble.NewClient(scrobble.ClientOptions{HTTP: c.hc, BaseURL: *baseURL, Token: token}), store)
+	s.Logf = func(format string, args ...any) {
+		fmt.Fprintf(stdout, format+"\n", args...)
+	}
+	if n := len(store.Pending()); n > 0 {
+		fmt.Fprintf(stderr, "Retrying %d spooled listen(s)...\n", n)
+		if err := s.Flush(ctx, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(stderr, "Scrobbling to %s (poll every %s). Ctrl-C to stop.\n", *baseURL, *interval)
+	err = c.client.WatchPlayback(ctx, spotify.WatchOptions{Interval: *interval}, func(up spotify.PlaybackUpdate) error {
+		if up.Err != nil {
+			fmt.Fprintf(stderr, "WARN: %v\n", humanizeError(up.Err))
+			return nil
+		}
+		return s.Observe(ctx, up)
+	})
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
+
+func defaultStateDir() string {
+	if d := strings.TrimSpace(os.Getenv("XDG_STATE_HOME")); d != "" {
+		return filepath.Join(d, "spotctl")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "spotctl")
+	}
+	return filepath.Join(home, ".local", "state", "spotctl")
+}
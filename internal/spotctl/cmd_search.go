@@ -42,6 +42,9 @@ func (c *cli) cmdSearchTracks(ctx context.Context, args []string, stdout, stderr
 		return &exitError{code: 2, err: errors.New("search requires a query")}
 	}
 	query := strings.Join(fs.Args(), " ")
+	if err := checkSearchLimit(*limit); err != nil {
+		return err
+	}
 
 	if err := c.ensureClient(ctx); err != nil {
 		return err
@@ -72,3 +75,11 @@ func (c *cli) cmdSearchTracks(ctx context.Context, args []string, stdout, stderr
 	}
 	return nil
 }
+
+// checkSearchLimit enforces the Web API's page size for search.
+func checkSearchLimit(n int) error {
+	if n < 1 || n > 50 {
+		return &exitError{code: 2, err: fmt.Errorf("limit must be 1-50, got %d", n)}
+	}
+	return nil
+}
@@ -0,0 +1,380 @@
+package spotctl
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joshp123/spotctl/internal/spotify"
+)
+
+// spotctl serve exposes the agent tools as a small local REST API (Home
+// Assistant, Stream Deck, curl). Response bodies match the CLI's --json
+// output; errors are {"error":{"code","message"}}.
+//
+// Every request needs the bearer token, so web pages in a local browser
+// can't drive playback. On loopback the Host and Origin headers must name
+// loopback too (DNS rebinding), and request bodies must be JSON.
+
+func (c *cli) cmdServe(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	listen := fs.String("listen", "127.0.0.1:8787", "Listen address")
+	tokenFile := fs.String("auth-token-file", "", "Bearer token file (default: generated in $XDG_RUNTIME_DIR/spotctl/http-token[-<profile>])")
+	eventsInterval := fs.Duration("events-interval", 2*time.Second, "Playback poll interval for /events")
+	if err := parseFlags(fs, args, stderr); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return &exitError{code: 2, err: errors.New("serve takes no positional args")}
+	}
+
+	loopback := isLoopbackAddr(*listen)
+	var token string
+	if *tokenFile != "" {
+		b, err := os.ReadFile(expandPath(*tokenFile))
+		if err != nil {
+			return fmt.Errorf("read --auth-token-file: %w", err)
+		}
+		token = strings.TrimSpace(string(b))
+		if token == "" {
+			return fmt.Errorf("--auth-token-file is empty: %s", *tokenFile)
+		}
+	} else if !loopback {
+		return &exitError{code: 2, err: fmt.Errorf("refusing to listen on non-loopback %s without --auth-token-file", *listen)}
+	} else {
+		path := filepath.Join(runtimeDir(), "http-token")
+		if c.profName != "" {
+			path += "-" + c.profName
+		}
+		var err error
+		if token, err = loadOrCreateServeToken(path); err != nil {
+			return err
+		}
+		fmt.Fprintf(stderr, "spotctl serve: bearer token in %s\n", path)
+	}
+
+	if err := c.ensureClient(ctx); err != nil {
+		return err
+	}
+
+	var h http.Handler = c.restMux(&eventHub{c: c, interval: *eventsInterval})
+	if loopback {
+		h = requireLoopbackHost(h)
+	}
+	srv := &http.Server{
+		Addr:              *listen,
+		Handler:           requireBearer(token, h),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+	ln, err := net.Listen("tcp", *listen)
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		sctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(sctx)
+	}()
+
+	fmt.Fprintf(stderr, "spotctl serve listening on http://%s\n", ln.Addr())
+	if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (c *cli) restMux(hub *eventHub) *http.ServeMux {
+	tools := toolsByName()
+	mux := http.NewServeMux()
+	mux.Handle("GET /status", c.restTool(tools["status"], nil))
+	mux.Handle("GET /devices", c.restTool(tools["devices"], nil))
+	mux.Handle("GET /search", c.restTool(tools["search"], func(r *http.Request, a map[string]any) {
+		a["query"] = r.URL.Query().Get("q")
+		if l := r.URL.Query().Get("limit"); l != "" {
+			a["limit"] = json.Number(l)
+		}
+	}))
+	mux.Handle("POST /play", c.restTool(tools["play"], nil))
+	mux.Handle("POST /pause", c.restTool(tools["pause"], nil))
+	mux.Handle("POST /next", c.restTool(tools["next"], nil))
+	mux.Handle("POST /previous", c.restTool(tools["previous"], nil))
+	mux.Handle("PUT /volume", c.restTool(tools["volume"], nil))
+	mux.Handle("POST /playlists", c.restTool(tools["playlist_create"], nil))
+	mux.Handle("POST /playlists/{id}/items", c.restTool(tools["playlist_add"], func(r *http.Request, a map[string]any) {
+		a["playlist"] = r.PathValue("id")
+	}))
+	mux.HandleFunc("GET /events", hub.serveSSE)
+	return mux
+}
+
+// restTool adapts a tool to HTTP: the JSON body (if any) becomes the tool
+// arguments, and extra() can add path/query parameters on top. Anything but
+// GET must be sent as application/json, which browsers can't do
+// cross-origin without a preflight we never answer.
+func (c *cli) restTool(t toolSpec, extra func(r *http.Request, a map[string]any)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt != "application/json" {
+				writeJSON(w, http.StatusUnsupportedMediaType, map[string]any{"error": errorBody{Code: "invalid_argument", Message: "Content-Type must be application/json"}})
+				return
+			}
+		}
+		a := map[string]any{}
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			writeRESTError(w, &exitError{code: 2, err: err})
+			return
+		}
+		if len(strings.TrimSpace(string(body))) > 0 {
+			if err := json.Unmarshal(body, &a); err != nil {
+				writeRESTError(w, &exitError{code: 2, err: fmt.Errorf("invalid JSON body: %w", err)})
+				return
+			}
+		}
+		if extra != nil {
+			extra(r, a)
+		}
+		raw, _ := json.Marshal(a)
+		res, err := t.run(r.Context(), c, raw)
+		if err != nil {
+			writeRESTError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, res)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+func writeRESTError(w http.ResponseWriter, err error) {
+	e := newErrorBody(err)
+	status := http.StatusInternalServerError
+	switch e.Code {
+	case "invalid_argument":
+		status = http.StatusBadRequest
+	case "device_not_available":
+		status = http.StatusConflict
+	case "scope_missing":
+		// Spotify itself answers 403 when a token lacks a scope.
+		status = http.StatusForbidden
+	default:
+		// Spotify errors: pass client errors through, report the rest as
+		// upstream failures.
+		if e.HTTPStatus != 0 {
+			status = http.StatusBadGateway
+			if e.HTTPStatus >= 400 && e.HTTPStatus < 500 {
+				status = e.HTTPStatus
+			}
+		}
+	}
+	writeJSON(w, status, map[string]any{"error": e})
+}
+
+func requireBearer(token string, next http.Handler) http.Handler {
+	want := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := []byte(r.Header.Get("Authorization"))
+		if subtle.ConstantTimeCompare(got, want) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeJSON(w, http.StatusUnauthorized, map[string]any{"error": errorBody{Code: "unauthorized", Message: "missing or invalid bearer token"}})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireLoopbackHost rejects requests whose Host or Origin isn't loopback:
+// a DNS-rebound name resolving to 127.0.0.1 still carries its own Host.
+func requireLoopbackHost(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ok := isLoopbackHost(r.Host)
+		if o := r.Header.Get("Origin"); ok && o != "" {
+			u, err := url.Parse(o)
+			ok = err == nil && (u.Scheme == "http" || u.Scheme == "https") && isLoopbackHost(u.Host)
+		}
+		if !ok {
+			writeJSON(w, http.StatusForbidden, map[string]any{"error": errorBody{Code: "forbidden", Message: "Host and Origin must be loopback"}})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	return isLoopbackName(host)
+}
+
+// isLoopbackHost is isLoopbackAddr for Host headers, where the port is
+// optional.
+func isLoopbackHost(hostport string) bool {
+	if host, _, err := net.SplitHostPort(hostport); err == nil {
+		return isLoopbackName(host)
+	}
+	return isLoopbackName(strings.Trim(hostport, "[]"))
+}
+
+func isLoopbackName(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// loadOrCreateServeToken reuses the generated token across restarts so
+// configured clients keep working.
+func loadOrCreateServeToken(path string) (string, error) {
+	if b, err := os.ReadFile(path); err == nil {
+		if tok := strings.TrimSpace(string(b)); tok != "" {
+			return tok, nil
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	tok := hex.EncodeToString(buf)
+	if err := spotify.WriteFileAtomic(path, []byte(tok+"\n")); err != nil {
+		return "", fmt.Errorf("write serve token: %w", err)
+	}
+	return tok, nil
+}
+
+// eventHub shares one playback watcher between all /events subscribers. It
+// only polls Spotify while at least one client is connected.
+type eventHub struct {
+	c        *cli
+	interval time.Duration
+
+	mu     sync.Mutex
+	subs   map[chan []byte]struct{}
+	cancel context.CancelFunc
+	last   []byte
+}
+
+func (h *eventHub) subscribe() (chan []byte, []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subs == nil {
+		h.subs = map[chan []byte]struct{}{}
+	}
+	ch := make(chan []byte, 16)
+	h.subs[ch] = struct{}{}
+	if h.cancel == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		h.cancel = cancel
+		h.last = nil
+		go h.watch(ctx)
+	}
+	return ch, h.last
+}
+
+func (h *eventHub) unsubscribe(ch chan []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.subs, ch)
+	if len(h.subs) == 0 && h.cancel != nil {
+		h.cancel()
+		h.cancel = nil
+	}
+}
+
+func (h *eventHub) watch(ctx context.Context) {
+	_ = h.c.client.WatchPlayback(ctx, spotify.WatchOptions{Interval: h.interval}, func(up spotify.PlaybackUpdate) error {
+		var msg []byte
+		switch {
+		case up.Err != nil:
+			b, _ := json.Marshal(map[string]any{"error": newErrorBody(up.Err)})
+			msg = sseMessage("error", b)
+		case up.Changed:
+			b, _ := json.Marshal(newStatusOutput(up.State))
+			msg = sseMessage("playback", b)
+		default:
+			return nil
+		}
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if up.Err == nil {
+			h.last = msg
+		}
+		for ch := range h.subs {
+			select {
+			case ch <- msg:
+			default: // slow client; it will catch up on the next change
+			}
+		}
+		return nil
+	})
+}
+
+func sseMessage(event string, data []byte) []byte {
+	return []byte("event: " + event + "\ndata: " + string(data) + "\n\n")
+}
+
+func (h *eventHub) serveSSE(w http.ResponseWriter, r *http.Request) {
+	fl, ok := w.(http.Flusher)
+	if !ok {
+		writeRESTError(w, errors.New("streaming unsupported"))
+		return
+	}
+	ch, last := h.subscribe()
+	defer h.unsubscribe(ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	if last != nil {
+		_, _ = w.Write(last)
+	}
+	fl.Flush()
+
+	keepalive := time.NewTicker(15 * time.Second)
+	defer keepalive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case msg := <-ch:
+			if _, err := w.Write(msg); err != nil {
+				return
+			}
+			fl.Flush()
+		case <-keepalive.C:
+			if _, err := io.WriteString(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+			fl.Flush()
+		}
+	}
+}
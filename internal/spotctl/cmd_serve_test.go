@@ -0,0 +1,60 @@
+package spotctl
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServeGuards(t *testing.T) {
+	c := &cli{}
+	h := requireBearer("s3cret", requireLoopbackHost(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			return
+		}
+		// Only rejected POSTs get here; the tool itself never runs.
+		c.restTool(toolSpec{}, nil).ServeHTTP(w, r)
+	})))
+
+	tests := []struct {
+		name   string
+		method string
+		host   string
+		header map[string]string
+		want   int
+	}{
+		{"no token", "GET", "127.0.0.1:8787", nil, http.StatusUnauthorized},
+		{"ok", "GET", "127.0.0.1:8787", map[string]string{"Authorization": "Bearer s3cret"}, http.StatusOK},
+		{"localhost", "GET", "localhost:8787", map[string]string{"Authorization": "Bearer s3cret"}, http.StatusOK},
+		{"rebound host", "GET", "evil.example:8787", map[string]string{"Authorization": "Bearer s3cret"}, http.StatusForbidden},
+		{"foreign origin", "GET", "127.0.0.1:8787", map[string]string{"Authorization": "Bearer s3cret", "Origin": "https://evil.example"}, http.StatusForbidden},
+		{"loopback origin", "GET", "127.0.0.1:8787", map[string]string{"Authorization": "Bearer s3cret", "Origin": "http://localhost:3000"}, http.StatusOK},
+		{"text/plain body", "POST", "127.0.0.1:8787", map[string]string{"Authorization": "Bearer s3cret", "Content-Type": "text/plain"}, http.StatusUnsupportedMediaType},
+		{"no content type", "POST", "127.0.0.1:8787", map[string]string{"Authorization": "Bearer s3cret"}, http.StatusUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, "/pause", strings.NewReader(`{}`))
+		r.Host = tt.host
+		for k, v := range tt.header {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != tt.want {
+			t.Errorf("%s: status %d, want %d", tt.name, w.Code, tt.want)
+		}
+	}
+}
+
+func TestLoadOrCreateServeToken(t *testing.T) {
+	path := t.TempDir() + "/http-token"
+	a, err := loadOrCreateServeToken(path)
+	if err != nil || len(a) != 64 {
+		t.Fatalf("token %q, err %v", a, err)
+	}
+	b, err := loadOrCreateServeToken(path)
+	if err != nil || b != a {
+		t.Fatalf("second load %q, want %q (err %v)", b, a, err)
+	}
+}
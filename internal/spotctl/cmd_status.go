@@ -35,7 +35,7 @@ func (c *cli) cmdStatus(ctx context.Context, args []string, stdout, stderr io.Wr
 		return &exitError{code: 2, err: errors.New("status takes no positional args")}
 	}
 
-	if err := c.ensureClient(ctx); err != nil {
+	if err := c.ensureClient(ctx, scopesReadPlayback...); err != nil {
 		return err
 	}
 
@@ -44,17 +44,7 @@ func (c *cli) cmdStatus(ctx context.Context, args []string, stdout, stderr io.Wr
 		return err
 	}
 
-	out := statusOutput{}
-	if st != nil {
-		out.Active = true
-		out.IsPlaying = st.IsPlaying
-		out.Progress = st.ProgressMs
-		out.Device = &st.Device
-		if st.Item.URI != "" {
-			item := st.Item
-			out.Item = &item
-		}
-	}
+	out := newStatusOutput(st)
 
 	if *jsonOut {
 		enc := json.NewEncoder(stdout)
@@ -79,6 +69,21 @@ func (c *cli) cmdStatus(ctx context.Context, args []string, stdout, stderr io.Wr
 	return nil
 }
 
+func newStatusOutput(st *spotify.PlaybackState) statusOutput {
+	out := statusOutput{}
+	if st != nil {
+		out.Active = true
+		out.IsPlaying = st.IsPlaying
+		out.Progress = st.ProgressMs
+		out.Device = &st.Device
+		if st.Item.URI != "" {
+			item := st.Item
+			out.Item = &item
+		}
+	}
+	return out
+}
+
 func stringsTitle(s string) string {
 	if s == "" {
 		return s
@@ -11,29 +11,29 @@ import (
 func (c *cli) cmdTransfer(ctx context.Context, args []string, stdout, stderr io.Writer) error {
 	fs := flag.NewFlagSet("transfer", flag.ContinueOnError)
 	fs.SetOutput(io.Discard)
-	deviceSel := fs.String("device", "", "Device name or id (strict)")
+	deviceSel := fs.String("device", "", "Device name or id (strict; default: the profile's device)")
 	play := fs.Bool("play", true, "Start playback after transfer")
 	if err := parseFlags(fs, args, stderr); err != nil {
 		return err
 	}
 	if *deviceSel == "" {
-		return &exitError{code: 2, err: errors.New("missing --device")}
+		*deviceSel = c.defaultDevice()
+	}
+	if *deviceSel == "" {
+		return &exitError{code: 2, err: errors.New("missing --device (or set a profile default: spotctl profile add <name> --device <name|id>)")}
 	}
 	if fs.NArg() != 0 {
 		return &exitError{code: 2, err: errors.New("transfer takes no positional args")}
 	}
 
-	if err := c.ensureClient(ctx); err != nil {
+	if err := c.ensureClient(ctx, scopesControl...); err != nil {
 		return err
 	}
 
-	dev, devs, err := c.client.ResolveDevice(ctx, *deviceSel)
+	dev, err := c.resolveDeviceStrict(ctx, *deviceSel)
 	if err != nil {
 		return err
 	}
-	if dev == nil {
-		return &exitError{code: 3, err: errors.New(strictDeviceMessage(*deviceSel, devs))}
-	}
 
 	if err := c.client.TransferPlayback(ctx, dev.ID, *play); err != nil {
 		return err
@@ -0,0 +1,358 @@
+package spotctl
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/joshp123/spotctl/internal/spotify"
+	"github.com/joshp123/spotctl/internal/toml"
+)
+
+// fileConfig is config.toml. Every key has an env var (and some a flag) that
+// wins over it; see settings.
+type fileConfig struct {
+	Credentials       string            `toml:"credentials"`
+	PublicClient      bool              `toml:"public_client"`
+	Device            string            `toml:"device"`
+	TokenCache        string            `toml:"token_cache"`
+	TokenCacheKey     string            `toml:"token_cache_key"`
+	APIBase           string            `toml:"api_base"`
+	AccountsBase      string            `toml:"accounts_base"`
+	MaxAttempts       int               `toml:"max_attempts"`
+	MaxRetryAfterSecs int               `toml:"max_retry_after_secs"`
+	MaxResponseBytes  int64             `toml:"max_response_bytes"`
+	Output            string            `toml:"output"`
+	Market            string            `toml:"market"`
+	Aliases           map[string]string `toml:"aliases"`
+}
+
+// setting is one config key. The effective value is the first set of:
+// --flag (handled by the command), the active profile, the env var, the
+// config file.
+type setting struct {
+	key     string
+	env     string
+	doc     string
+	isInt   bool
+	isBool  bool
+	isPath  bool
+	profile func(*profile) string
+	file    func(*fileConfig) string
+}
+
+func fileBool(v bool) string {
+	if !v {
+		return ""
+	}
+	return "true"
+}
+
+func fileInt[T int | int64](v T) string {
+	if v == 0 {
+		return ""
+	}
+	return strconv.FormatInt(int64(v), 10)
+}
+
+var settings = []setting{
+	{key: "credentials", env: "SPOTCTL_CREDENTIALS", doc: "credential source (env, secret-service, pass, gopass, command:<cmd>)",
+		profile: func(p *profile) string { return p.Credentials }, file: func(f *fileConfig) string { return f.Credentials }},
+	{key: "public_client", env: "SPOTCTL_PUBLIC_CLIENT", doc: "PKCE-only app: no client secret (true/false)", isBool: true,
+		profile: func(p *profile) string { return fileBool(p.PublicClient) }, file: func(f *fileConfig) string { return fileBool(f.PublicClient) }},
+	{key: "device", env: "SPOTCTL_DEVICE", doc: "default device for play/transfer",
+		profile: func(p *profile) string { return p.Device }, file: func(f *fileConfig) string { return f.Device }},
+	{key: "token_cache", env: "SPOTCTL_TOKEN_CACHE", isPath: true, doc: "access token cache file (default $XDG_CACHE_HOME/spotctl/token.json; off disables)",
+		profile: func(p *profile) string { return p.TokenCache }, file: func(f *fileConfig) string { return f.TokenCache }},
+	{key: "token_cache_key", env: "SPOTCTL_TOKEN_CACHE_KEY", isPath: true, doc: "keyfile or age identity that encrypts the token cache",
+		file: func(f *fileConfig) string { return f.TokenCacheKey }},
+	{key: "api_base", env: "SPOTIFY_API_BASE", doc: "Web API base URL",
+		profile: func(p *profile) string { return p.APIBase }, file: func(f *fileConfig) string { return f.APIBase }},
+	{key: "accounts_base", env: "SPOTIFY_ACCOUNTS_BASE", doc: "accounts (token) base URL",
+		profile: func(p *profile) string { return p.AccountsBase }, file: func(f *fileConfig) string { return f.AccountsBase }},
+	{key: "max_attempts", env: "SPOTCTL_MAX_ATTEMPTS", doc: "tries per request, including the first (default 3)", isInt: true,
+		file: func(f *fileConfig) string { return fileInt(f.MaxAttempts) }},
+	{key: "max_retry_after_secs", env: "SPOTCTL_MAX_RETRY_AFTER_SECS", doc: "longest 429 Retry-After to wait out (default 15)", isInt: true,
+		file: func(f *fileConfig) string { return fileInt(f.MaxRetryAfterSecs) }},
+	{key: "max_response_bytes", env: "SPOTCTL_MAX_RESPONSE_BYTES", doc: "largest response body to read (default 2 MiB)", isInt: true,
+		file: func(f *fileConfig) string { return fileInt(f.MaxResponseBytes) }},
+	{key: "output", env: "SPOTCTL_OUTPUT", doc: "default output format: text or json",
+		file: func(f *fileConfig) string { return f.Output }},
+	{key: "market", env: "SPOTCTL_MARKET", doc: "search market (ISO country code, or from_token)",
+		file: func(f *fileConfig) string { return f.Market }},
+}
+
+func lookupSetting(key string) (setting, bool) {
+	for _, s := range settings {
+		if s.key == key {
+			return s, true
+		}
+	}
+	return setting{}, false
+}
+
+// setting returns the effective value of key and where it came from ("" when
+// unset).
+func (c *cli) setting(key string) (value, source string) {
+	s, ok := lookupSetting(key)
+	if !ok {
+		panic("spotctl: unknown setting " + key)
+	}
+	if v, ok := c.forced[key]; ok {
+		return v, "client"
+	}
+	if c.prof != nil && s.profile != nil {
+		if v := strings.TrimSpace(s.profile(c.prof)); v != "" {
+			return v, "profile " + c.profName
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv(s.env)); v != "" {
+		return v, "env " + s.env
+	}
+	if v := strings.TrimSpace(s.file(&c.cfg)); v != "" {
+		return v, "config"
+	}
+	return "", ""
+}
+
+func (c *cli) settingValue(key string) string {
+	v, _ := c.setting(key)
+	return v
+}
+
+// settingInt is a positive int setting, or 0 (the default) when unset or
+// invalid.
+func (c *cli) settingInt(key string) int64 {
+	v, err := strconv.ParseInt(c.settingValue(key), 10, 64)
+	if err != nil || v <= 0 {
+		return 0
+	}
+	return v
+}
+
+// settingBool is a boolean setting; unset or invalid is false.
+func (c *cli) settingBool(key string) bool {
+	v, _ := strconv.ParseBool(c.settingValue(key))
+	return v
+}
+
+// defaultDevice is used when a command that needs a device gets no --device.
+func (c *cli) defaultDevice() string {
+	return c.settingValue("device")
+}
+
+// validateSetting checks a value before `config set` writes it.
+func validateSetting(s setting, v string) (any, error) {
+	switch {
+	case s.isInt:
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("%s must be a positive integer", s.key)
+		}
+		return n, nil
+	case s.isBool:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be true or false", s.key)
+		}
+		return b, nil
+	case s.key == "output":
+		if v != "text" && v != "json" {
+			return nil, errors.New("output must be text or json")
+		}
+	case s.key == "credentials":
+		if _, err := spotify.ParseCredentialSource(v); err != nil {
+			return nil, err
+		}
+	case s.key == "api_base" || s.key == "accounts_base":
+		if u, err := url.Parse(v); err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("%s must be an absolute URL", s.key)
+		}
+	}
+	return v, nil
+}
+
+// configPath is $SPOTCTL_CONFIG, else $XDG_CONFIG_HOME/spotctl/config.toml.
+func configPath() (string, error) {
+	if p := strings.TrimSpace(os.Getenv("SPOTCTL_CONFIG")); p != "" {
+		return expandPath(p), nil
+	}
+	d, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(d, "config.toml"), nil
+}
+
+// readConfig returns the raw file (nil if missing) and its decoded form.
+func readConfig() (path string, raw []byte, cfg fileConfig, err error) {
+	path, err = configPath()
+	if err != nil {
+		return "", nil, cfg, err
+	}
+	raw, err = os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return path, nil, cfg, nil
+	}
+	if err != nil {
+		return path, nil, cfg, err
+	}
+	if err := toml.Decode(raw, &cfg); err != nil {
+		return path, raw, cfg, fmt.Errorf("%s: %w", path, err)
+	}
+	return path, raw, cfg, nil
+}
+
+// redactSetting hides secrets in `config get/show` output: passwords in URLs
+// and the body of command: credential sources (which may carry tokens).
+func redactSetting(key, v string) string {
+	if key == "credentials" {
+		if kind, _, ok := strings.Cut(v, ":"); ok && kind == "command" {
+			return "command:<redacted>"
+		}
+		return v
+	}
+	if u, err := url.Parse(v); err == nil && u.User != nil {
+		return u.Redacted()
+	}
+	return v
+}
+
+// Global flags that take a value, for finding the command word.
+var globalValueFlags = map[string]bool{"--profile": true, "--record": true, "--output": true}
+
+// expandAlias replaces the command word with its [aliases] expansion. Aliases
+// can't shadow built-in commands and don't expand recursively.
+func (c *cli) expandAlias(args []string) []string {
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if globalValueFlags[a] {
+			i++
+			continue
+		}
+		if strings.HasPrefix(a, "-") {
+			continue
+		}
+		exp, ok := c.cfg.Aliases[a]
+		if !ok || builtinCommands[a] {
+			return args
+		}
+		words, err := splitWords(exp)
+		if err != nil || len(words) == 0 {
+			return args
+		}
+		out := append([]string{}, args[:i]...)
+		out = append(out, words...)
+		return append(out, args[i+1:]...)
+	}
+	return args
+}
+
+var builtinCommands = map[string]bool{
+	"device": true, "status": true, "transfer": true, "play": true, "pause": true,
+	"next": true, "previous": true, "prev": true, "volume": true, "playlist": true,
+	"search": true, "auth": true, "scrobble": true, "daemon": true, "mcp": true,
+	"serve": true, "mpris": true, "profile": true, "config": true, "help": true,
+}
+
+// splitWords splits an alias like a shell would for simple cases: spaces
+// separate words, single or double quotes group them.
+func splitWords(s string) ([]string, error) {
+	var words []string
+	var cur strings.Builder
+	inWord := false
+	var quote rune
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, cur.String())
+				cur.Reset()
+				inWord = false
+			}
+		default:
+			cur.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New("unterminated quote")
+	}
+	if inWord {
+		words = append(words, cur.String())
+	}
+	return words, nil
+}
+
+// prepareArgs loads the config and applies it to the command line: alias
+// expansion and the default output format. It runs before daemon forwarding
+// so the daemon sees the final command. Config errors surface when the
+// command runs (config commands still work, to fix them).
+func (c *cli) prepareArgs(args []string) []string {
+	_, _, cfg, err := readConfig()
+	c.cfg, c.cfgErr = cfg, err
+	args = c.expandAlias(args)
+
+	output, _, args, err := popStringFlag(args, "--output")
+	if err != nil {
+		c.cfgErr = err
+		return args
+	}
+	if output == "" {
+		output = c.settingValue("output")
+	}
+	switch output {
+	case "", "text":
+	case "json":
+		if supportsJSON(args) && !wantsJSON(args) {
+			args = append(args, "--json")
+		}
+	default:
+		c.cfgErr = fmt.Errorf("output must be text or json, got %q", output)
+	}
+	return args
+}
+
+// supportsJSON reports whether the command takes --json, so `output = "json"`
+// can turn it on.
+func supportsJSON(args []string) bool {
+	cmd, sub := "", ""
+	for i := 0; i < len(args); i++ {
+		if globalValueFlags[args[i]] {
+			i++
+			continue
+		}
+		if strings.HasPrefix(args[i], "-") {
+			continue
+		}
+		if cmd == "" {
+			cmd = args[i]
+			continue
+		}
+		sub = args[i]
+		break
+	}
+	switch cmd {
+	case "status", "search", "device", "playlist":
+		return true
+	case "auth":
+		return sub == "status"
+	case "profile":
+		return sub == "list" || sub == "ls"
+	case "config":
+		return sub == "show"
+	}
+	return false
+}
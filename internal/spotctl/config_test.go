@@ -0,0 +1,148 @@
+package spotctl
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitWords(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []string
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"status", []string{"status"}, false},
+		{"  play  --device\tKitchen ", []string{"play", "--device", "Kitchen"}, false},
+		{`play --device "Living Room"`, []string{"play", "--device", "Living Room"}, false},
+		{`search 'it''s'`, []string{"search", "its"}, false},
+		{`a "" b`, []string{"a", "", "b"}, false},
+		{`pre"fix suf"x`, []string{"prefix sufx"}, false},
+		{`play "Kitchen`, nil, true},
+	}
+	for _, tt := range tests {
+		got, err := splitWords(tt.in)
+		if (err != nil) != tt.wantErr || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitWords(%q) = %q, %v; want %q, err=%v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestExpandAlias(t *testing.T) {
+	c := &cli{cfg: fileConfig{Aliases: map[string]string{
+		"np":      "status",
+		"kitchen": `play --device "Kitchen Speaker"`,
+		"status":  "pause", // can't shadow a built-in
+		"broken":  `play "x`,
+		"loop":    "np",
+	}}}
+	tests := []struct {
+		in, want []string
+	}{
+		{[]string{"np", "--json"}, []string{"status", "--json"}},
+		{[]string{"kitchen", "spotify:track:x"}, []string{"play", "--device", "Kitchen Speaker", "spotify:track:x"}},
+		{[]string{"--profile", "np", "np"}, []string{"--profile", "np", "status"}},
+		{[]string{"--trace", "np"}, []string{"--trace", "status"}},
+		{[]string{"status"}, []string{"status"}},
+		{[]string{"broken"}, []string{"broken"}},
+		{[]string{"loop"}, []string{"np"}},
+		{[]string{"unknown"}, []string{"unknown"}},
+		{nil, nil},
+	}
+	for _, tt := range tests {
+		if got := c.expandAlias(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("expandAlias(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSupportsJSON(t *testing.T) {
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{[]string{"status"}, true},
+		{[]string{"--profile", "work", "search", "tracks", "x"}, true},
+		{[]string{"--output", "json", "device", "list"}, true},
+		{[]string{"auth", "status"}, true},
+		{[]string{"auth", "login"}, false},
+		{[]string{"profile", "ls"}, true},
+		{[]string{"profile", "add", "x"}, false},
+		{[]string{"config", "show"}, true},
+		{[]string{"config", "get", "device"}, false},
+		{[]string{"play", "x"}, false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := supportsJSON(tt.args); got != tt.want {
+			t.Errorf("supportsJSON(%q) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestSettingPrecedence(t *testing.T) {
+	cfg := fileConfig{Device: "FromConfig"}
+	prof := &profile{Device: "FromProfile"}
+	tests := []struct {
+		name       string
+		env        string
+		prof       *profile
+		forced     map[string]string
+		want, from string
+	}{
+		{"config", "", nil, nil, "FromConfig", "config"},
+		{"env over config", "FromEnv", nil, nil, "FromEnv", "env SPOTCTL_DEVICE"},
+		{"profile over env", "FromEnv", prof, nil, "FromProfile", "profile work"},
+		{"client over profile", "FromEnv", prof, map[string]string{"device": "FromClient"}, "FromClient", "client"},
+		{"empty profile field", "FromEnv", &profile{}, nil, "FromEnv", "env SPOTCTL_DEVICE"},
+	}
+	for _, tt := range tests {
+		t.Setenv("SPOTCTL_DEVICE", tt.env)
+		c := &cli{cfg: cfg, prof: tt.prof, profName: "work", forced: tt.forced}
+		if got, from := c.setting("device"); got != tt.want || from != tt.from {
+			t.Errorf("%s: setting(device) = %q from %q, want %q from %q", tt.name, got, from, tt.want, tt.from)
+		}
+	}
+
+	// A profile beats the env var for every key it can set.
+	full := &profile{Credentials: "env", PublicClient: true, TokenCache: "/p/token.json", Device: "FromProfile", APIBase: "https://p.example", AccountsBase: "https://pa.example"}
+	for _, s := range settings {
+		if s.profile == nil {
+			continue
+		}
+		want := s.profile(full)
+		if s.env != "" {
+			t.Setenv(s.env, "from-env")
+		}
+		c := &cli{prof: full, profName: "work"}
+		if got, from := c.setting(s.key); got != want || from != "profile work" {
+			t.Errorf("setting(%s) = %q from %q, want %q from profile work", s.key, got, from, want)
+		}
+	}
+
+	// Keys without a profile field skip the profile.
+	t.Setenv("SPOTCTL_OUTPUT", "")
+	c := &cli{cfg: fileConfig{Output: "json"}, prof: prof, profName: "work"}
+	if got, from := c.setting("output"); got != "json" || from != "config" {
+		t.Errorf("setting(output) = %q from %q", got, from)
+	}
+}
+
+func TestConfigHelpListsProfileKeys(t *testing.T) {
+	var out bytes.Buffer
+	if err := (&cli{}).cmdConfig(context.Background(), []string{"--help"}, &out, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range settings {
+		if s.profile != nil && !strings.Contains(out.String(), s.key) {
+			t.Errorf("config --help doesn't list profile key %s:\n%s", s.key, out.String())
+		}
+	}
+	if !strings.Contains(out.String(), "replaces the env vars") {
+		t.Errorf("config --help doesn't explain profile vs env:\n%s", out.String())
+	}
+}
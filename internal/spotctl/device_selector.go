@@ -6,6 +6,8 @@ import (
 	"flag"
 	"fmt"
 	"io"
+
+	"github.com/joshp123/spotctl/internal/spotify"
 )
 
 // parseOptionalDeviceSelector parses --device for commands like pause/next/previous.
@@ -27,17 +29,27 @@ func parseOptionalDeviceSelector(name string, args []string, stderr io.Writer) (
 	return &out, nil
 }
 
+// resolveDeviceStrict resolves a required device selector. Unknown or
+// ambiguous selectors fail with exit code 3 and the list of available devices.
+func (c *cli) resolveDeviceStrict(ctx context.Context, selector string) (*spotify.Device, error) {
+	dev, devs, err := c.client.ResolveDevice(ctx, selector)
+	if err != nil {
+		return nil, err
+	}
+	if dev == nil {
+		return nil, &exitError{code: 3, err: errors.New(strictDeviceMessage(selector, devs))}
+	}
+	return dev, nil
+}
+
 func (c *cli) resolveOptionalDeviceID(ctx context.Context, selector *string) (*string, error) {
 	if selector == nil {
 		return nil, nil
 	}
-	dev, devs, err := c.client.ResolveDevice(ctx, *selector)
+	dev, err := c.resolveDeviceStrict(ctx, *selector)
 	if err != nil {
 		return nil, err
 	}
-	if dev == nil {
-		return nil, &exitError{code: 3, err: errors.New(strictDeviceMessage(*selector, devs))}
-	}
 	id := dev.ID
 	return &id, nil
 }
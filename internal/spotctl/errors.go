@@ -1,25 +1,122 @@
 package spotctl
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"strings"
 
 	"github.com/joshp123/spotctl/internal/spotify"
 )
 
+// Exit codes. These are part of the CLI contract (see usage and README);
+// don't renumber them.
+const (
+	exitFailure      = 1 // anything not listed below
+	exitUsage        = 2
+	exitDevice       = 3 // requested device not available, or no active device
+	exitUnauthorized = 4
+	exitScope        = 5
+	exitPremium      = 6
+	exitRestricted   = 7
+	exitNotFound     = 8
+	exitRateLimited  = 9
+)
+
+// errorClass is how an error is reported: exit code, stable code string for
+// JSON output, and an optional hint for the user.
+type errorClass struct {
+	exit int
+	code string
+	hint string
+}
+
+func classifyError(err error) errorClass {
+	var ee *exitError
+	if errors.As(err, &ee) {
+		switch ee.code {
+		case exitUsage:
+			return errorClass{exit: exitUsage, code: "invalid_argument"}
+		case exitDevice:
+			return errorClass{exit: exitDevice, code: "device_not_available"}
+		}
+	}
+	switch {
+	case errors.Is(err, spotify.ErrUnauthorized):
+		return errorClass{exit: exitUnauthorized, code: "unauthorized", hint: "Spotify rejected the credentials. Check SPOTIFY_CLIENT_ID/SECRET, or mint a new refresh token with `spotctl auth login`"}
+	case errors.Is(err, spotify.ErrScopeMissing):
+		return errorClass{exit: exitScope, code: "scope_missing", hint: "The refresh token lacks a scope this command needs. Re-run `spotctl auth login` to grant the default scopes"}
+	case errors.Is(err, spotify.ErrPremiumRequired):
+		return errorClass{exit: exitPremium, code: "premium_required", hint: "Playback control via the Web API needs Spotify Premium"}
+	case errors.Is(err, spotify.ErrNoActiveDevice):
+		return errorClass{exit: exitDevice, code: "no_active_device", hint: "No active Spotify device. Open Spotify on the target device, then retry"}
+	case errors.Is(err, spotify.ErrRestricted):
+		var apiErr *spotify.APIError
+		if errors.As(err, &apiErr) && (apiErr.Reason == "VOLUME_CONTROL_DISALLOW" || strings.Contains(apiErr.Message, "Cannot control device volume")) {
+			return errorClass{exit: exitRestricted, code: "restricted", hint: "Spotify won't let us change volume for that device via Web API. Adjust volume on the device and retry"}
+		}
+		return errorClass{exit: exitRestricted, code: "restricted", hint: "Spotify refused that command (restriction). Try again on a different device (Desktop usually works) or start playback in-app first"}
+	case errors.Is(err, spotify.ErrNotFound):
+		return errorClass{exit: exitNotFound, code: "not_found"}
+	case errors.Is(err, spotify.ErrRateLimited):
+		c := errorClass{exit: exitRateLimited, code: "rate_limited", hint: "Rate limited by Spotify; wait a bit, then retry"}
+		var apiErr *spotify.APIError
+		if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+			c.hint = fmt.Sprintf("Rate limited by Spotify; wait %ds, then retry", int(apiErr.RetryAfter.Seconds()+0.5))
+		}
+		return c
+	}
+	var apiErr *spotify.APIError
+	if errors.As(err, &apiErr) {
+		return errorClass{exit: exitFailure, code: "spotify_api_error"}
+	}
+	return errorClass{exit: exitFailure, code: "internal"}
+}
+
 func humanizeError(err error) error {
+	c := classifyError(err)
+	switch {
+	case c.hint == "":
+		return err
+	case c.code == "restricted" || c.code == "no_active_device":
+		// Lead with the hint; Spotify's terse message still says which
+		// restriction it was.
+		var apiErr *spotify.APIError
+		if errors.As(err, &apiErr) && apiErr.Message != "" {
+			return fmt.Errorf("%s (Spotify: %s)", c.hint, apiErr.Message)
+		}
+		return errors.New(c.hint)
+	}
+	return fmt.Errorf("%w\n%s", err, c.hint)
+}
+
+// errorBody is the machine-readable form of an error, used by --json and the
+// MCP and HTTP servers.
+type errorBody struct {
+	Code       string `json:"code"`
+	Reason     string `json:"reason,omitempty"`
+	Message    string `json:"message"`
+	Hint       string `json:"hint,omitempty"`
+	HTTPStatus int    `json:"http_status,omitempty"`
+}
+
+func newErrorBody(err error) errorBody {
+	c := classifyError(err)
+	e := errorBody{Code: c.code, Message: err.Error(), Hint: c.hint}
 	var apiErr *spotify.APIError
 	if errors.As(err, &apiErr) {
-		m := apiErr.Message
-		switch {
-		case apiErr.StatusCode == 403 && strings.Contains(m, "Cannot control device volume"):
-			return fmt.Errorf("Spotify won't let us change volume for that device via Web API. Adjust volume on the device and retry")
-		case apiErr.StatusCode == 403 && strings.Contains(m, "Restriction violated"):
-			return fmt.Errorf("Spotify refused that command (restriction). Try again on a different device (Desktop usually works) or start playback in-app first")
-		case apiErr.StatusCode == 404 && strings.Contains(m, "No active device"):
-			return fmt.Errorf("No active Spotify device. Open Spotify on the target device, then retry")
+		e.HTTPStatus = apiErr.StatusCode
+		e.Reason = apiErr.Reason
+		if apiErr.Message != "" {
+			e.Message = apiErr.Message
 		}
 	}
-	return err
+	return e
+}
+
+// writeJSONError is the --json form of an error on stderr.
+func writeJSONError(w io.Writer, err error) {
+	b, _ := json.Marshal(map[string]any{"error": newErrorBody(err)})
+	fmt.Fprintln(w, string(b))
 }
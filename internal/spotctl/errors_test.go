@@ -0,0 +1,33 @@
+package spotctl
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/joshp123/spotctl/internal/spotify"
+)
+
+func TestClassifyAPIErrors(t *testing.T) {
+	cases := []struct {
+		err        *spotify.APIError
+		exit       int
+		code, want string
+	}{
+		{&spotify.APIError{StatusCode: 403, Message: "Player command failed: Restriction violated", Reason: "UNKNOWN"}, exitRestricted, "restricted",
+			"Spotify refused that command (restriction). Try again on a different device (Desktop usually works) or start playback in-app first (Spotify: Player command failed: Restriction violated)"},
+		{&spotify.APIError{StatusCode: 404, Message: "Player command failed: No active device found", Reason: "NO_ACTIVE_DEVICE"}, exitDevice, "no_active_device",
+			"No active Spotify device. Open Spotify on the target device, then retry (Spotify: Player command failed: No active device found)"},
+		// A 403 that isn't a player restriction keeps Spotify's message.
+		{&spotify.APIError{StatusCode: 403, Message: "You cannot add tracks to a playlist you don't own.", Reason: "SOME_NEW_REASON"}, exitFailure, "spotify_api_error",
+			"wrapped: spotify api error (403): You cannot add tracks to a playlist you don't own."},
+	}
+	for _, tc := range cases {
+		err := fmt.Errorf("wrapped: %w", tc.err)
+		if c := classifyError(err); c.exit != tc.exit || c.code != tc.code {
+			t.Errorf("%v: classified as %d %s, want %d %s", tc.err, c.exit, c.code, tc.exit, tc.code)
+		}
+		if got := humanizeError(err).Error(); got != tc.want {
+			t.Errorf("humanizeError = %q, want %q", got, tc.want)
+		}
+	}
+}
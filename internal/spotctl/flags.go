@@ -1,5 +1,10 @@
 package spotctl
 
+import (
+	"fmt"
+	"strings"
+)
+
 // Small helpers because Go's stdlib flag package stops parsing at the first
 // non-flag argument. For agent / automation usage we want "--json" etc to work
 // even if it appears at the end.
@@ -16,3 +21,25 @@ func popBoolFlag(args []string, name string) (bool, []string) {
 	}
 	return set, out
 }
+
+// popStringFlag is popBoolFlag for a flag with a value ("--name v" or
+// "--name=v").
+func popStringFlag(args []string, name string) (value string, found bool, out []string, err error) {
+	out = make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == name:
+			if i+1 >= len(args) {
+				return "", true, nil, fmt.Errorf("%s requires a value", name)
+			}
+			value, found = args[i+1], true
+			i++
+		case strings.HasPrefix(a, name+"="):
+			value, found = strings.TrimPrefix(a, name+"="), true
+		default:
+			out = append(out, a)
+		}
+	}
+	return value, found, out, nil
+}
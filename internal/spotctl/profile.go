@@ -0,0 +1,134 @@
+package spotctl
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/joshp123/spotctl/internal/spotify"
+)
+
+// profile is a named account setup. While it is active, set fields take
+// precedence over the matching env vars and config keys (see settings).
+type profile struct {
+	Credentials  string `json:"credentials,omitempty"` // ParseCredentialSource spec
+	PublicClient bool   `json:"public_client,omitempty"`
+	TokenCache   string `json:"token_cache,omitempty"`
+	Device       string `json:"device,omitempty"` // default for play/transfer
+	APIBase      string `json:"api_base,omitempty"`
+	AccountsBase string `json:"accounts_base,omitempty"`
+}
+
+// profileStore is profiles.json in the config dir.
+type profileStore struct {
+	Current  string             `json:"current,omitempty"`
+	Profiles map[string]profile `json:"profiles"`
+}
+
+var profileNameRE = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+func validProfileName(name string) error {
+	if !profileNameRE.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q (letters, digits, '.', '_', '-')", name)
+	}
+	return nil
+}
+
+// configDir is $XDG_CONFIG_HOME/spotctl (or the OS equivalent).
+func configDir() (string, error) {
+	d, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(d, "spotctl"), nil
+}
+
+func profilesPath() (string, error) {
+	d, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(d, "profiles.json"), nil
+}
+
+// loadProfiles returns an empty store when the file doesn't exist yet.
+func loadProfiles() (profileStore, error) {
+	st := profileStore{Profiles: map[string]profile{}}
+	p, err := profilesPath()
+	if err != nil {
+		return st, err
+	}
+	b, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return st, nil
+	}
+	if err != nil {
+		return st, err
+	}
+	if err := json.Unmarshal(b, &st); err != nil {
+		return st, fmt.Errorf("%s: %w", p, err)
+	}
+	if st.Profiles == nil {
+		st.Profiles = map[string]profile{}
+	}
+	return st, nil
+}
+
+func saveProfiles(st profileStore) error {
+	p, err := profilesPath()
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	return spotify.WriteFileAtomic(p, append(b, '\n'))
+}
+
+// activeProfileName is --profile, else $SPOTCTL_PROFILE, else the profile
+// chosen with `spotctl profile use`. Empty means no profile: plain env vars.
+func activeProfileName(flagValue string) string {
+	if v := strings.TrimSpace(flagValue); v != "" {
+		return v
+	}
+	if v := strings.TrimSpace(os.Getenv("SPOTCTL_PROFILE")); v != "" {
+		return v
+	}
+	st, err := loadProfiles()
+	if err != nil {
+		return ""
+	}
+	return st.Current
+}
+
+// loadActiveProfile resolves and loads the active profile; nil when none is
+// selected. Naming a profile that doesn't exist is a usage error.
+func loadActiveProfile(flagValue string) (string, *profile, error) {
+	name := activeProfileName(flagValue)
+	if name == "" {
+		return "", nil, nil
+	}
+	st, err := loadProfiles()
+	if err != nil {
+		return "", nil, err
+	}
+	p, ok := st.Profiles[name]
+	if !ok {
+		return "", nil, &exitError{code: 2, err: fmt.Errorf("unknown profile %q (see `spotctl profile list`)", name)}
+	}
+	return name, &p, nil
+}
+
+// defaultProfileTokenCache keeps each profile's tokens apart.
+func defaultProfileTokenCache(name string) string {
+	d, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(d, "spotctl", "profiles", name, "token.json")
+}
@@ -0,0 +1,290 @@
+package spotctl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/joshp123/spotctl/internal/spotify"
+)
+
+// toolSpec is one agent/automation-facing operation. The MCP server exposes
+// them as tools and the HTTP server as REST endpoints; both share the CLI's
+// guard rails (strict device targeting, verified track URIs).
+type toolSpec struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"inputSchema"`
+
+	scopes []string // checked up front, like the matching CLI command
+	call   func(ctx context.Context, c *cli, args json.RawMessage) (any, error)
+}
+
+// run sets up the client, checks scopes and calls the tool. Auth problems
+// come back as errors, not as a dead server.
+func (t toolSpec) run(ctx context.Context, c *cli, args json.RawMessage) (any, error) {
+	if err := c.ensureClient(ctx, t.scopes...); err != nil {
+		return nil, err
+	}
+	return t.call(ctx, c, args)
+}
+
+func toolsByName() map[string]toolSpec {
+	m := map[string]toolSpec{}
+	for _, t := range toolSpecs() {
+		m[t.Name] = t
+	}
+	return m
+}
+
+func decodeToolArgs(raw json.RawMessage, v any) error {
+	if err := json.Unmarshal(raw, v); err != nil {
+		return &exitError{code: 2, err: fmt.Errorf("invalid arguments: %w", err)}
+	}
+	return nil
+}
+
+func toolSchema(required []string, props map[string]any) map[string]any {
+	s := map[string]any{"type": "object", "properties": props, "additionalProperties": false}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+var toolDeviceProp = map[string]any{"type": "string", "description": "Exact device name or id from the devices tool"}
+
+func toolSpecs() []toolSpec {
+	return []toolSpec{
+		{
+			Name:        "devices",
+			Description: "List Spotify Connect devices visible to the user.",
+			InputSchema: toolSchema(nil, map[string]any{}),
+			scopes:      scopesReadPlayback,
+			call: func(ctx context.Context, c *cli, _ json.RawMessage) (any, error) {
+				devs, err := c.client.Devices(ctx)
+				if err != nil {
+					return nil, err
+				}
+				if devs == nil {
+					devs = []spotify.Device{}
+				}
+				return map[string]any{"devices": devs}, nil
+			},
+		},
+		{
+			Name:        "status",
+			Description: "Current playback: device, track, playing/paused, progress.",
+			InputSchema: toolSchema(nil, map[string]any{}),
+			scopes:      scopesReadPlayback,
+			call: func(ctx context.Context, c *cli, _ json.RawMessage) (any, error) {
+				st, err := c.client.PlaybackState(ctx)
+				if err != nil {
+					return nil, err
+				}
+				return newStatusOutput(st), nil
+			},
+		},
+		{
+			Name:        "play",
+			Description: "Play a Spotify URI/URL (track, album, playlist, artist, show, episode) or the top search result for a query on a device.",
+			InputSchema: toolSchema([]string{"device", "target"}, map[string]any{
+				"device": toolDeviceProp,
+				"target": map[string]any{"type": "string", "description": "spotify: URI, open.spotify.com URL, or search query"},
+			}),
+			scopes: scopesControl,
+			call: func(ctx context.Context, c *cli, raw json.RawMessage) (any, error) {
+				var a struct {
+					Device string `json:"device"`
+					Target string `json:"target"`
+				}
+				if err := decodeToolArgs(raw, &a); err != nil {
+					return nil, err
+				}
+				if a.Device == "" || a.Target == "" {
+					return nil, &exitError{code: 2, err: errors.New("device and target are required")}
+				}
+				dev, err := c.resolveDeviceStrict(ctx, a.Device)
+				if err != nil {
+					return nil, err
+				}
+				uri, req, picked, err := c.resolvePlayTarget(ctx, a.Target)
+				if err != nil {
+					return nil, err
+				}
+				if err := c.client.Play(ctx, dev.ID, req); err != nil {
+					return nil, err
+				}
+				return map[string]any{"device": dev, "uri": uri, "search_result": picked}, nil
+			},
+		},
+		controlTool("pause", "Pause playback.", func(ctx context.Context, c *cli, id *string) error { return c.client.Pause(ctx, id) }),
+		controlTool("next", "Skip to the next track.", func(ctx context.Context, c *cli, id *string) error { return c.client.Next(ctx, id) }),
+		controlTool("previous", "Skip to the previous track.", func(ctx context.Context, c *cli, id *string) error { return c.client.Previous(ctx, id) }),
+		{
+			Name:        "volume",
+			Description: "Set volume (0-100) on the active or given device.",
+			InputSchema: toolSchema([]string{"percent"}, map[string]any{
+				"percent": map[string]any{"type": "integer", "minimum": 0, "maximum": 100},
+				"device":  toolDeviceProp,
+			}),
+			scopes: scopesControl,
+			call: func(ctx context.Context, c *cli, raw json.RawMessage) (any, error) {
+				var a struct {
+					Percent *int   `json:"percent"`
+					Device  string `json:"device"`
+				}
+				if err := decodeToolArgs(raw, &a); err != nil {
+					return nil, err
+				}
+				if a.Percent == nil || *a.Percent < 0 || *a.Percent > 100 {
+					return nil, &exitError{code: 2, err: errors.New("percent must be an int 0-100")}
+				}
+				id, err := c.resolveOptionalDeviceID(ctx, optionalString(a.Device))
+				if err != nil {
+					return nil, err
+				}
+				if err := c.client.Volume(ctx, id, *a.Percent); err != nil {
+					return nil, err
+				}
+				return map[string]any{"ok": true, "volume_percent": *a.Percent, "device_id": id}, nil
+			},
+		},
+		{
+			Name:        "search",
+			Description: "Search tracks. Use the returned uri values; never invent URIs.",
+			InputSchema: toolSchema([]string{"query"}, map[string]any{
+				"query": map[string]any{"type": "string"},
+				"limit": map[string]any{"type": "integer", "minimum": 1, "maximum": 50, "default": 10},
+			}),
+			call: func(ctx context.Context, c *cli, raw json.RawMessage) (any, error) {
+				var a struct {
+					Query string `json:"query"`
+					Limit int    `json:"limit"`
+				}
+				if err := decodeToolArgs(raw, &a); err != nil {
+					return nil, err
+				}
+				if strings.TrimSpace(a.Query) == "" {
+					return nil, &exitError{code: 2, err: errors.New("query is required")}
+				}
+				if a.Limit == 0 {
+					a.Limit = 10
+				}
+				if err := checkSearchLimit(a.Limit); err != nil {
+					return nil, err
+				}
+				items, err := c.client.SearchTracks(ctx, a.Query, a.Limit)
+				if err != nil {
+					return nil, err
+				}
+				if items == nil {
+					items = []spotify.Track{}
+				}
+				return map[string]any{"query": a.Query, "items": items, "limit": a.Limit, "count": len(items)}, nil
+			},
+		},
+		{
+			Name:        "playlist_create",
+			Description: "Create a playlist (private unless public=true).",
+			InputSchema: toolSchema([]string{"name"}, map[string]any{
+				"name":        map[string]any{"type": "string"},
+				"public":      map[string]any{"type": "boolean", "default": false},
+				"description": map[string]any{"type": "string"},
+			}),
+			call: func(ctx context.Context, c *cli, raw json.RawMessage) (any, error) {
+				var a struct {
+					Name        string `json:"name"`
+					Public      bool   `json:"public"`
+					Description string `json:"description"`
+				}
+				if err := decodeToolArgs(raw, &a); err != nil {
+					return nil, err
+				}
+				if a.Name == "" {
+					return nil, &exitError{code: 2, err: errors.New("name is required")}
+				}
+				if err := c.ensureClient(ctx, playlistCreateScope(a.Public)); err != nil {
+					return nil, err
+				}
+				pl, err := c.client.CreatePlaylist(ctx, a.Name, a.Public, a.Description)
+				if err != nil {
+					return nil, err
+				}
+				out := map[string]any{"id": pl.ID, "name": pl.Name, "uri": pl.URI}
+				if !a.Public && !c.enforcePrivate(ctx, pl.ID) {
+					out["warning"] = "Spotify reports this playlist as public; make it secret from the playlist menu in the Spotify app."
+				}
+				return out, nil
+			},
+		},
+		{
+			Name:        "playlist_add",
+			Description: "Add tracks to a playlist. Every URI is checked against Spotify first; unknown tracks are rejected.",
+			InputSchema: toolSchema([]string{"playlist", "uris"}, map[string]any{
+				"playlist": map[string]any{"type": "string", "description": "Playlist id, spotify:playlist: URI or URL"},
+				"uris":     map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "minItems": 1},
+			}),
+			scopes: scopesPlaylistModify,
+			call: func(ctx context.Context, c *cli, raw json.RawMessage) (any, error) {
+				var a struct {
+					Playlist string   `json:"playlist"`
+					URIs     []string `json:"uris"`
+				}
+				if err := decodeToolArgs(raw, &a); err != nil {
+					return nil, err
+				}
+				if a.Playlist == "" || len(a.URIs) == 0 {
+					return nil, &exitError{code: 2, err: errors.New("playlist and uris are required")}
+				}
+				pid, err := spotify.NormalizePlaylistID(a.Playlist)
+				if err != nil {
+					return nil, &exitError{code: 2, err: err}
+				}
+				uris, err := c.validateTrackURIs(ctx, a.URIs)
+				if err != nil {
+					return nil, err
+				}
+				res, err := c.client.AddTracksToPlaylist(ctx, pid, uris)
+				if err != nil {
+					return nil, err
+				}
+				return map[string]any{"playlist": pid, "added_uris": uris, "snapshot_id": res.SnapshotID}, nil
+			},
+		},
+	}
+}
+
+func controlTool(name, desc string, fn func(ctx context.Context, c *cli, deviceID *string) error) toolSpec {
+	return toolSpec{
+		Name:        name,
+		Description: desc + " Targets the active device unless device is given.",
+		InputSchema: toolSchema(nil, map[string]any{"device": toolDeviceProp}),
+		scopes:      scopesControl,
+		call: func(ctx context.Context, c *cli, raw json.RawMessage) (any, error) {
+			var a struct {
+				Device string `json:"device"`
+			}
+			if err := decodeToolArgs(raw, &a); err != nil {
+				return nil, err
+			}
+			id, err := c.resolveOptionalDeviceID(ctx, optionalString(a.Device))
+			if err != nil {
+				return nil, err
+			}
+			if err := fn(ctx, c, id); err != nil {
+				return nil, err
+			}
+			return map[string]any{"ok": true, "device_id": id}, nil
+		},
+	}
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,87 @@
+package spotctl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/joshp123/spotctl/internal/spotify"
+)
+
+// fakeAPI points the env at a test server whose tokens carry scope, with
+// nothing cached or configured, and counts Web API requests.
+func fakeAPI(t *testing.T, scope string) *atomic.Int32 {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/api/token" {
+			fmt.Fprintf(w, `{"access_token":"at","token_type":"Bearer","expires_in":3600,"scope":%q}`, scope)
+			return
+		}
+		calls.Add(1)
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"error":{"status":403,"message":"Insufficient client scope"}}`)
+	}))
+	t.Cleanup(srv.Close)
+
+	dir := t.TempDir()
+	for k, v := range map[string]string{
+		"SPOTIFY_CLIENT_ID": "cid", "SPOTIFY_CLIENT_SECRET": "sec", "SPOTIFY_REFRESH_TOKEN": "rt",
+		"SPOTIFY_CLIENT_ID_FILE": "", "SPOTIFY_CLIENT_SECRET_FILE": "", "SPOTIFY_REFRESH_TOKEN_FILE": "",
+		"SPOTIFY_API_BASE": srv.URL, "SPOTIFY_ACCOUNTS_BASE": srv.URL,
+		"SPOTCTL_TOKEN_CACHE": "off", "SPOTCTL_CACHE_DIR": "off", "SPOTCTL_CREDENTIALS": "",
+		"SPOTCTL_PUBLIC_CLIENT": "", "SPOTCTL_REFRESH_TOKEN_HOOK": "", "SPOTCTL_PROFILE": "",
+		"SPOTCTL_CONFIG": filepath.Join(dir, "config.toml"), "XDG_CONFIG_HOME": dir,
+	} {
+		t.Setenv(k, v)
+	}
+	return &calls
+}
+
+func TestToolScopes(t *testing.T) {
+	tests := []struct {
+		tool, args string
+		scope      string
+	}{
+		{"playlist_add", `{"playlist":"37i9dQZF1DXcBWIGoYBM5M","uris":["spotify:track:4uLU6hMCjMI75M1A2tKUQC"]}`, "playlist-read-private"},
+		{"playlist_create", `{"name":"x"}`, "playlist-modify-public"},
+		{"pause", `{}`, "user-read-playback-state"},
+	}
+	for _, tt := range tests {
+		calls := fakeAPI(t, tt.scope)
+		c := newCLI()
+		_, err := toolsByName()[tt.tool].run(context.Background(), c, json.RawMessage(tt.args))
+		var se *spotify.ScopeError
+		if !errors.As(err, &se) {
+			t.Errorf("%s: err=%v, want a scope error", tt.tool, err)
+		}
+		if calls.Load() != 0 {
+			t.Errorf("%s: %d API calls before the scope check", tt.tool, calls.Load())
+		}
+	}
+}
+
+func TestRESTMissingScope(t *testing.T) {
+	calls := fakeAPI(t, "playlist-read-private")
+	c := newCLI()
+	r := httptest.NewRequest("POST", "/playlists/37i9dQZF1DXcBWIGoYBM5M/items", strings.NewReader(`{"uris":["spotify:track:4uLU6hMCjMI75M1A2tKUQC"]}`))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	c.restMux(&eventHub{c: c}).ServeHTTP(w, r)
+	body, _ := io.ReadAll(w.Body)
+	if w.Code != http.StatusForbidden || !strings.Contains(string(body), "scope_missing") {
+		t.Fatalf("status=%d body=%s", w.Code, body)
+	}
+	if calls.Load() != 0 {
+		t.Fatalf("%d API calls before the scope check", calls.Load())
+	}
+}
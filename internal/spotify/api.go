@@ -8,12 +8,16 @@ import (
 
 // Devices returns the full list of Spotify Connect devices visible to the user.
 func (c *Client) Devices(ctx context.Context) ([]Device, error) {
+	if devs, ok := c.player.getDevices(); ok {
+		return devs, nil
+	}
 	var res struct {
 		Devices []Device `json:"devices"`
 	}
 	if err := c.do(ctx, "GET", "/v1/me/player/devices", nil, nil, &res, 200); err != nil {
 		return nil, err
 	}
+	c.player.putDevices(res.Devices)
 	return res.Devices, nil
 }
 
@@ -51,14 +55,19 @@ func (c *Client) ResolveDevice(ctx context.Context, selector string) (*Device, [
 }
 
 func (c *Client) PlaybackState(ctx context.Context) (*PlaybackState, error) {
+	if st, ok := c.player.getState(); ok {
+		return st, nil
+	}
 	var st PlaybackState
 	if err := c.do(ctx, "GET", "/v1/me/player", nil, nil, &st, 200, 204); err != nil {
 		return nil, err
 	}
 	// If Spotify returned 204, st will be the zero value.
 	if st.Device.ID == "" && st.Device.Name == "" {
+		c.player.putState(nil)
 		return nil, nil
 	}
+	c.player.putState(&st)
 	return &st, nil
 }
 
@@ -67,6 +76,7 @@ func (c *Client) TransferPlayback(ctx context.Context, deviceID string, play boo
 		"device_ids": []string{deviceID},
 		"play":       play,
 	}
+	defer c.player.invalidate()
 	return c.do(ctx, "PUT", "/v1/me/player", nil, body, nil, 200, 202, 204)
 }
 
@@ -75,17 +85,41 @@ type PlayRequest struct {
 	URIs       []string `json:"uris,omitempty"`
 }
 
+// PlayRequestFor builds the play body for a normalized URI: tracks and
+// episodes play as a single item, everything else as a context.
+func PlayRequestFor(uri string, kind URIKind) (PlayRequest, error) {
+	switch kind {
+	case URIKindTrack, URIKindEpisode:
+		return PlayRequest{URIs: []string{uri}}, nil
+	case URIKindAlbum, URIKindPlaylist, URIKindArtist, URIKindShow:
+		return PlayRequest{ContextURI: uri}, nil
+	}
+	return PlayRequest{}, fmt.Errorf("unsupported URI kind for play: %s (%s)", kind, uri)
+}
+
 func (c *Client) Play(ctx context.Context, deviceID string, req PlayRequest) error {
 	q := url.Values{}
 	q.Set("device_id", deviceID)
+	defer c.player.invalidate()
 	return c.do(ctx, "PUT", "/v1/me/player/play", q, req, nil, 200, 202, 204)
 }
 
+// Resume continues the current playback (no new context).
+func (c *Client) Resume(ctx context.Context, deviceID *string) error {
+	q := url.Values{}
+	if deviceID != nil {
+		q.Set("device_id", *deviceID)
+	}
+	defer c.player.invalidate()
+	return c.do(ctx, "PUT", "/v1/me/player/play", q, nil, nil, 200, 202, 204)
+}
+
 func (c *Client) Pause(ctx context.Context, deviceID *string) error {
 	q := url.Values{}
 	if deviceID != nil {
 		q.Set("device_id", *deviceID)
 	}
+	defer c.player.invalidate()
 	return c.do(ctx, "PUT", "/v1/me/player/pause", q, nil, nil, 200, 202, 204)
 }
 
@@ -94,6 +128,7 @@ func (c *Client) Next(ctx context.Context, deviceID *string) error {
 	if deviceID != nil {
 		q.Set("device_id", *deviceID)
 	}
+	defer c.player.invalidate()
 	return c.do(ctx, "POST", "/v1/me/player/next", q, nil, nil, 200, 202, 204)
 }
 
@@ -102,6 +137,7 @@ func (c *Client) Previous(ctx context.Context, deviceID *string) error {
 	if deviceID != nil {
 		q.Set("device_id", *deviceID)
 	}
+	defer c.player.invalidate()
 	return c.do(ctx, "POST", "/v1/me/player/previous", q, nil, nil, 200, 202, 204)
 }
 
@@ -111,9 +147,41 @@ func (c *Client) Volume(ctx context.Context, deviceID *string, pct int) error {
 	if deviceID != nil {
 		q.Set("device_id", *deviceID)
 	}
+	defer c.player.invalidate()
 	return c.do(ctx, "PUT", "/v1/me/player/volume", q, nil, nil, 200, 202, 204)
 }
 
+func (c *Client) Seek(ctx context.Context, deviceID *string, positionMs int) error {
+	q := url.Values{}
+	q.Set("position_ms", fmt.Sprintf("%d", positionMs))
+	if deviceID != nil {
+		q.Set("device_id", *deviceID)
+	}
+	defer c.player.invalidate()
+	return c.do(ctx, "PUT", "/v1/me/player/seek", q, nil, nil, 200, 202, 204)
+}
+
+func (c *Client) SetShuffle(ctx context.Context, deviceID *string, on bool) error {
+	q := url.Values{}
+	q.Set("state", fmt.Sprintf("%t", on))
+	if deviceID != nil {
+		q.Set("device_id", *deviceID)
+	}
+	defer c.player.invalidate()
+	return c.do(ctx, "PUT", "/v1/me/player/shuffle", q, nil, nil, 200, 202, 204)
+}
+
+// SetRepeat sets the repeat mode: "track", "context" or "off".
+func (c *Client) SetRepeat(ctx context.Context, deviceID *string, state string) error {
+	q := url.Values{}
+	q.Set("state", state)
+	if deviceID != nil {
+		q.Set("device_id", *deviceID)
+	}
+	defer c.player.invalidate()
+	return c.do(ctx, "PUT", "/v1/me/player/repeat", q, nil, nil, 200, 202, 204)
+}
+
 func (c *Client) SearchTracks(ctx context.Context, query string, limit int) ([]Track, error) {
 	if limit <= 0 {
 		limit = 10
@@ -125,6 +193,9 @@ func (c *Client) SearchTracks(ctx context.Context, query string, limit int) ([]T
 	q.Set("q", query)
 	q.Set("type", "track")
 	q.Set("limit", fmt.Sprintf("%d", limit))
+	if c.market != "" {
+		q.Set("market", c.market)
+	}
 	var res struct {
 		Tracks struct {
 			Items []Track `json:"items"`
@@ -161,6 +232,7 @@ func (c *Client) GetTrack(ctx context.Context, id string) (Track, error) {
 type User struct {
 	ID          string `json:"id"`
 	DisplayName string `json:"display_name"`
+	Product     string `json:"product,omitempty"` // "premium", "free"; needs user-read-private
 }
 
 func (c *Client) Me(ctx context.Context) (User, error) {
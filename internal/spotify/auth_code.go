@@ -68,7 +68,7 @@ func ExchangeAuthorizationCode(ctx context.Context, hc *http.Client, creds Crede
 	if opt.CodeVerifier != "" {
 		form.Set("code_verifier", opt.CodeVerifier)
 	}
-	if creds.ClientSecret == "" {
+	if creds.Public() {
 		// PKCE / public client
 		form.Set("client_id", creds.ClientID)
 	}
@@ -80,7 +80,7 @@ func ExchangeAuthorizationCode(ctx context.Context, hc *http.Client, creds Crede
 		return AuthCodeExchangeResult{}, err
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	if creds.ClientSecret != "" {
+	if !creds.Public() {
 		req.SetBasicAuth(creds.ClientID, creds.ClientSecret)
 	}
 
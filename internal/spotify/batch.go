@@ -0,0 +1,48 @@
+package spotify
+
+import (
+	"context"
+	"sync"
+)
+
+// BatchResult is the outcome of one Batch item.
+type BatchResult[R any] struct {
+	Value R
+	Err   error
+}
+
+// Batch calls fn for every item with at most concurrency calls in flight and
+// returns the results in input order. Failures are reported per item; once
+// ctx is done the remaining items fail with ctx.Err() without calling fn.
+//
+// Client methods called from fn share the client's rate limiter, so raising
+// concurrency only helps until the limiter (or a 429) paces the batch.
+func Batch[T, R any](ctx context.Context, concurrency int, items []T, fn func(context.Context, T) (R, error)) []BatchResult[R] {
+	out := make([]BatchResult[R], len(items))
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	concurrency = min(concurrency, len(items))
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				if err := ctx.Err(); err != nil {
+					out[i].Err = err
+					continue
+				}
+				out[i].Value, out[i].Err = fn(ctx, items[i])
+			}
+		}()
+	}
+	for i := range items {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return out
+}
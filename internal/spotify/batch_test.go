@@ -0,0 +1,43 @@
+package spotify
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBatchKeepsOrderAndBoundsConcurrency(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	items := []int{5, 1, 4, 2, 3, 0}
+	res := Batch(context.Background(), 2, items, func(_ context.Context, n int) (int, error) {
+		cur := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if cur <= p || peak.CompareAndSwap(p, cur) {
+				break
+			}
+		}
+		time.Sleep(time.Duration(n) * time.Millisecond)
+		if n == 4 {
+			return 0, errors.New("boom")
+		}
+		return n * 10, nil
+	})
+	if peak.Load() > 2 {
+		t.Fatalf("peak concurrency=%d", peak.Load())
+	}
+	for i, r := range res {
+		if items[i] == 4 {
+			if r.Err == nil {
+				t.Fatalf("item %d: want error", i)
+			}
+			continue
+		}
+		if r.Err != nil || r.Value != items[i]*10 {
+			t.Fatalf("item %d: %+v", i, r)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package spotify
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// errCacheDecrypt means the token cache is encrypted with another key (or
+// not encrypted while a key is set); the token manager just refreshes.
+var errCacheDecrypt = errors.New("token cache can't be decrypted with this key")
+
+const cacheKeyLabel = "spotctl token cache v1"
+
+// CacheKeyFromFile derives a token cache key from a local secret file: an age
+// identity (the AGE-SECRET-KEY-1… line; comments are ignored), e.g. the one
+// agenix decrypts with, or any other keyfile, such as 32 random bytes or an
+// SSH private key. The file itself is never written.
+func CacheKeyFromFile(path string) ([]byte, error) {
+	b, err := os.ReadFile(expandHome(path))
+	if err != nil {
+		return nil, fmt.Errorf("token cache key: %w", err)
+	}
+	secret := ageIdentity(b)
+	if secret == nil {
+		secret = bytes.TrimSpace(b)
+	}
+	if len(secret) < 16 {
+		return nil, fmt.Errorf("token cache key: %s is too short (want at least 16 bytes of secret)", path)
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(cacheKeyLabel))
+	return mac.Sum(nil), nil
+}
+
+// ageIdentity returns the first age secret key in an identity file, or nil.
+func ageIdentity(b []byte) []byte {
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if strings.HasPrefix(line, "AGE-SECRET-KEY-1") {
+			return []byte(line)
+		}
+	}
+	return nil
+}
+
+// encryptedCache is the on-disk form of an encrypted token cache:
+// base64(nonce || AES-256-GCM(tokenCache JSON)).
+type encryptedCache struct {
+	Alg  string `json:"alg"`
+	Data []byte `json:"data"`
+}
+
+func sealCache(key, plain []byte) ([]byte, error) {
+	aead, err := cacheAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plain)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	data := aead.Seal(nonce, nonce, plain, []byte(cacheKeyLabel))
+	return json.MarshalIndent(encryptedCache{Alg: "A256GCM", Data: data}, "", "  ")
+}
+
+func openCache(key, b []byte) ([]byte, error) {
+	var e encryptedCache
+	if err := json.Unmarshal(b, &e); err != nil || e.Alg != "A256GCM" {
+		return nil, errCacheDecrypt
+	}
+	aead, err := cacheAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(e.Data) < aead.NonceSize() {
+		return nil, errCacheDecrypt
+	}
+	nonce, sealed := e.Data[:aead.NonceSize()], e.Data[aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, sealed, []byte(cacheKeyLabel))
+	if err != nil {
+		return nil, errCacheDecrypt
+	}
+	return plain, nil
+}
+
+func cacheAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, errors.New("token cache key must be 32 bytes")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,160 @@
+package spotify
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// selfSignedCert is a throwaway cert for one login.
+func selfSignedCert(host string) (tls.Certificate, error) {
+	certPEM, keyPEM, err := newCert(leafTemplate(host, 2*time.Hour), nil, nil)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.X509KeyPair(certPEM, keyPEM)
+}
+
+// persistentCert loads (or creates) dir/ca.pem and a cert for host signed by
+// it, so the callback presents the same cert every login. It returns the CA
+// path for the user to trust.
+func persistentCert(dir, host string) (tls.Certificate, string, error) {
+	caFile := filepath.Join(dir, "ca.pem")
+	ca, caKey, err := loadOrCreate(caFile, filepath.Join(dir, "ca-key.pem"), func(*x509.Certificate) bool { return true }, func() ([]byte, []byte, error) {
+		tmpl := leafTemplate("spotctl local callback CA", 10*365*24*time.Hour)
+		tmpl.DNSNames, tmpl.IPAddresses, tmpl.ExtKeyUsage = nil, nil, nil
+		tmpl.IsCA = true
+		tmpl.MaxPathLenZero = true
+		tmpl.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+		return newCert(tmpl, nil, nil)
+	})
+	if err != nil {
+		return tls.Certificate{}, "", err
+	}
+
+	name := strings.NewReplacer(":", "_", "/", "_").Replace(host)
+	signedByCA := func(c *x509.Certificate) bool {
+		return c.CheckSignatureFrom(ca) == nil && c.VerifyHostname(host) == nil
+	}
+	leaf, leafKey, err := loadOrCreate(filepath.Join(dir, name+".pem"), filepath.Join(dir, name+"-key.pem"), signedByCA, func() ([]byte, []byte, error) {
+		return newCert(leafTemplate(host, 365*24*time.Hour), ca, caKey)
+	})
+	if err != nil {
+		return tls.Certificate{}, "", err
+	}
+	return tls.Certificate{Certificate: [][]byte{leaf.Raw}, PrivateKey: leafKey, Leaf: leaf}, caFile, nil
+}
+
+// loadOrCreate returns the cert and key in certFile/keyFile, replacing them
+// via create when missing, unparsable, expiring within a day, or not ok.
+func loadOrCreate(certFile, keyFile string, ok func(*x509.Certificate) bool, create func() ([]byte, []byte, error)) (*x509.Certificate, crypto.Signer, error) {
+	if cert, key, err := readCertPair(certFile, keyFile); err == nil && time.Until(cert.NotAfter) > 24*time.Hour && ok(cert) {
+		return cert, key, nil
+	}
+	certPEM, keyPEM, err := create()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := WriteFileAtomic(keyFile, keyPEM); err != nil {
+		return nil, nil, fmt.Errorf("callback cert: %w", err)
+	}
+	if err := WriteFileAtomic(certFile, certPEM); err != nil {
+		return nil, nil, fmt.Errorf("callback cert: %w", err)
+	}
+	return readCertPair(certFile, keyFile)
+}
+
+func readCertPair(certFile, keyFile string) (*x509.Certificate, crypto.Signer, error) {
+	certPEM, err := os.ReadFile(certFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyPEM, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	cb, _ := pem.Decode(certPEM)
+	kb, _ := pem.Decode(keyPEM)
+	if cb == nil || kb == nil {
+		return nil, nil, errors.New("callback cert: bad PEM")
+	}
+	cert, err := x509.ParseCertificate(cb.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(kb.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, nil, errors.New("callback cert: unsupported key")
+	}
+	return cert, signer, nil
+}
+
+func leafTemplate(host string, validity time.Duration) *x509.Certificate {
+	tmpl := &x509.Certificate{
+		Subject: pkix.Name{
+			CommonName: host,
+		},
+		NotBefore: time.Now().Add(-1 * time.Minute),
+		NotAfter:  time.Now().Add(validity),
+
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+
+	// SANs
+	if ip := net.ParseIP(host); ip != nil {
+		tmpl.IPAddresses = []net.IP{ip}
+	} else {
+		tmpl.DNSNames = []string{host}
+		// Convenience: if user uses localhost, include 127.0.0.1 too.
+		if strings.EqualFold(host, "localhost") {
+			tmpl.IPAddresses = []net.IP{net.ParseIP("127.0.0.1")}
+		}
+	}
+	return tmpl
+}
+
+// newCert signs tmpl with parent/parentKey, or self-signs when parent is nil.
+func newCert(tmpl, parent *x509.Certificate, parentKey crypto.Signer) (certPEM, keyPEM []byte, err error) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	tmpl.SerialNumber, err = rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		return nil, nil, err
+	}
+	if parent == nil {
+		parent, parentKey = tmpl, priv
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &priv.PublicKey, parentKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyBytes, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, nil, err
+	}
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyBytes})
+	return certPEM, keyPEM, nil
+}
@@ -0,0 +1,234 @@
+package spotify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/joshp123/spotctl/internal/dbus"
+	"github.com/joshp123/spotctl/internal/secretservice"
+)
+
+// CredentialSource supplies the app credentials and refresh token, so they
+// don't have to sit in env vars.
+type CredentialSource interface {
+	Credentials(ctx context.Context) (Credentials, error)
+}
+
+// ParseCredentialSource maps a setting (SPOTCTL_CREDENTIALS) to a source:
+//
+//	env (default)               SPOTIFY_* env vars, values or files
+//	secret-service[:<service>]  freedesktop Secret Service (see SecretServiceSource)
+//	pass[:<prefix>]             pass show <prefix>/client_id etc. (default prefix "spotify")
+//	gopass[:<prefix>]           same with gopass
+//	command:<shell command>     prints {"client_id":...,"client_secret":...,"refresh_token":...}
+func ParseCredentialSource(spec string) (CredentialSource, error) {
+	kind, arg, _ := strings.Cut(strings.TrimSpace(spec), ":")
+	switch kind {
+	case "", "env":
+		return EnvSource{}, nil
+	case "secret-service":
+		return SecretServiceSource{Service: arg}, nil
+	case "pass", "gopass":
+		return PassSource{Command: kind, Prefix: arg}, nil
+	case "command":
+		if strings.TrimSpace(arg) == "" {
+			return nil, errors.New("credentials: command: needs a command")
+		}
+		return CommandSource{Command: arg}, nil
+	}
+	return nil, fmt.Errorf("credentials: unknown source %q (want env, secret-service, pass, gopass or command:<cmd>)", kind)
+}
+
+// credentialKeys are the names sources store values under. client_secret
+// may be missing (public client; see Credentials.Check).
+var credentialKeys = []string{"client_id", "client_secret", "refresh_token"}
+
+func credentialsFromMap(src string, m map[string]string) (Credentials, error) {
+	for _, k := range []string{"client_id", "refresh_token"} {
+		if strings.TrimSpace(m[k]) == "" {
+			return Credentials{}, fmt.Errorf("credentials (%s): missing %s", src, k)
+		}
+	}
+	return Credentials{
+		ClientID:     strings.TrimSpace(m["client_id"]),
+		ClientSecret: strings.TrimSpace(m["client_secret"]),
+		RefreshToken: strings.TrimSpace(m["refresh_token"]),
+	}, nil
+}
+
+// EnvSource is LoadCredentialsFromEnv.
+type EnvSource struct{}
+
+func (EnvSource) Credentials(context.Context) (Credentials, error) {
+	return LoadCredentialsFromEnv()
+}
+
+// SecretServiceSource reads items with attributes service=<Service> and
+// key=client_id|client_secret|refresh_token from the session keyring, e.g.
+// as stored by:
+//
+//	secret-tool store --label 'spotctl refresh token' service spotctl key refresh_token
+//
+// It also stores rotated refresh tokens back (RefreshTokenWriter).
+type SecretServiceSource struct {
+	Service string // default "spotctl"
+	Address string // bus address; default the session bus
+}
+
+func (s SecretServiceSource) service() string {
+	if s.Service == "" {
+		return "spotctl"
+	}
+	return s.Service
+}
+
+func (s SecretServiceSource) open(ctx context.Context) (*secretservice.Client, func(), error) {
+	var conn *dbus.Conn
+	var err error
+	if s.Address != "" {
+		conn, err = dbus.Dial(s.Address)
+	} else {
+		conn, err = dbus.SessionBus()
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	ss, err := secretservice.Open(ctx, conn)
+	if err != nil {
+		_ = conn.Close()
+		return nil, nil, err
+	}
+	return ss, func() {
+		_ = ss.Close(ctx)
+		_ = conn.Close()
+	}, nil
+}
+
+func (s SecretServiceSource) Credentials(ctx context.Context) (Credentials, error) {
+	ss, done, err := s.open(ctx)
+	if err != nil {
+		return Credentials{}, err
+	}
+	defer done()
+	m := map[string]string{}
+	for _, k := range credentialKeys {
+		v, err := ss.Lookup(ctx, map[string]string{"service": s.service(), "key": k})
+		if err != nil && !errors.Is(err, secretservice.ErrNotFound) {
+			return Credentials{}, err
+		}
+		m[k] = string(v)
+	}
+	return credentialsFromMap("secret-service", m)
+}
+
+func (s SecretServiceSource) WriteRefreshToken(ctx context.Context, token string) error {
+	ss, done, err := s.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+	return ss.Store(ctx, s.service()+" refresh token", map[string]string{"service": s.service(), "key": "refresh_token"}, []byte(token))
+}
+
+// PassSource reads <Prefix>/client_id, <Prefix>/client_secret and
+// <Prefix>/refresh_token with pass (or gopass), using the first line of
+// each. Rotated refresh tokens are inserted back.
+type PassSource struct {
+	Command string // "pass" (default) or "gopass"
+	Prefix  string // default "spotify"
+}
+
+func (p PassSource) cmd() string {
+	if p.Command == "" {
+		return "pass"
+	}
+	return p.Command
+}
+
+func (p PassSource) entry(k string) string {
+	prefix := strings.Trim(p.Prefix, "/")
+	if prefix == "" {
+		prefix = "spotify"
+	}
+	return prefix + "/" + k
+}
+
+func (p PassSource) Credentials(ctx context.Context) (Credentials, error) {
+	m := map[string]string{}
+	for _, k := range credentialKeys {
+		out, err := runSecretCommand(ctx, nil, p.cmd(), "show", p.entry(k))
+		if err != nil {
+			if k == "client_secret" && notInStore(err) {
+				continue // public client
+			}
+			return Credentials{}, err
+		}
+		m[k], _, _ = strings.Cut(out, "\n")
+	}
+	return credentialsFromMap(p.cmd(), m)
+}
+
+func notInStore(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "not in the password store") || strings.Contains(msg, "not found")
+}
+
+func (p PassSource) WriteRefreshToken(ctx context.Context, token string) error {
+	args := []string{"insert", "-m", "-f", p.entry("refresh_token")}
+	if p.cmd() == "gopass" {
+		args = []string{"insert", "-f", p.entry("refresh_token")}
+	}
+	_, err := runSecretCommand(ctx, strings.NewReader(token+"\n"), p.cmd(), args...)
+	return err
+}
+
+// CommandSource runs Command with sh -c, like AWS's credential_process, and
+// reads JSON from its stdout:
+//
+//	{"client_id":"...","client_secret":"...","refresh_token":"..."}
+type CommandSource struct {
+	Command string
+}
+
+func (c CommandSource) Credentials(ctx context.Context) (Credentials, error) {
+	out, err := runSecretCommand(ctx, nil, "sh", "-c", c.Command)
+	if err != nil {
+		return Credentials{}, err
+	}
+	var m map[string]string
+	if err := json.Unmarshal([]byte(out), &m); err != nil {
+		return Credentials{}, fmt.Errorf("credentials (command): output is not a JSON object of strings: %w", err)
+	}
+	return credentialsFromMap("command", m)
+}
+
+// runSecretCommand returns stdout; on failure the error carries stderr (but
+// never stdout, which may hold secrets).
+func runSecretCommand(ctx context.Context, stdin *strings.Reader, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	return runCaptured(cmd)
+}
+
+// runCaptured runs cmd and returns its stdout; on failure the error carries
+// its stderr, since that's where these tools explain themselves.
+func runCaptured(cmd *exec.Cmd) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	name := filepath.Base(cmd.Path)
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s: %w: %s", name, err, msg)
+		}
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,91 @@
+package spotify
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseCredentialSource(t *testing.T) {
+	cases := map[string]CredentialSource{
+		"":                     EnvSource{},
+		"env":                  EnvSource{},
+		"secret-service":       SecretServiceSource{},
+		"secret-service:work":  SecretServiceSource{Service: "work"},
+		"pass":                 PassSource{Command: "pass"},
+		"gopass:me/spotify":    PassSource{Command: "gopass", Prefix: "me/spotify"},
+		"command:cat creds.js": CommandSource{Command: "cat creds.js"},
+	}
+	for spec, want := range cases {
+		got, err := ParseCredentialSource(spec)
+		if err != nil || got != want {
+			t.Errorf("%q: got %#v, %v", spec, got, err)
+		}
+	}
+	for _, bad := range []string{"vault", "command:"} {
+		if _, err := ParseCredentialSource(bad); err == nil {
+			t.Errorf("%q: want error", bad)
+		}
+	}
+}
+
+func TestCommandSource(t *testing.T) {
+	ctx := context.Background()
+	c, err := CommandSource{Command: `echo '{"client_id":"cid","client_secret":"sec","refresh_token":"rt"}'`}.Credentials(ctx)
+	if err != nil || c != (Credentials{ClientID: "cid", ClientSecret: "sec", RefreshToken: "rt"}) {
+		t.Fatalf("creds=%+v err=%v", c, err)
+	}
+	_, err = CommandSource{Command: `echo '{"client_id":"cid","client_secret":"sec"}'`}.Credentials(ctx)
+	if err == nil || !strings.Contains(err.Error(), "missing refresh_token") {
+		t.Fatalf("err=%v", err)
+	}
+	// No secret (public client): allowed here, public mode is the caller's call.
+	c, err = CommandSource{Command: `echo '{"client_id":"cid","refresh_token":"rt"}'`}.Credentials(ctx)
+	if err != nil || c.Public() || c.ClientSecret != "" {
+		t.Fatalf("creds=%+v err=%v", c, err)
+	}
+	_, err = CommandSource{Command: `echo nope >&2; exit 3`}.Credentials(ctx)
+	if err == nil || !strings.Contains(err.Error(), "nope") {
+		t.Fatalf("err=%v", err)
+	}
+}
+
+func TestPassSource(t *testing.T) {
+	// A fake pass keeping entries as files under $STORE.
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "fakepass")
+	script := `#!/bin/sh
+case "$1" in
+show) cat "$STORE/$2" ;;
+insert) shift; while [ "$#" -gt 1 ]; do shift; done; mkdir -p "$(dirname "$STORE/$1")"; cat > "$STORE/$1" ;;
+esac
+`
+	if err := os.WriteFile(bin, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	store := filepath.Join(dir, "store")
+	t.Setenv("STORE", store)
+	for k, v := range map[string]string{"client_id": "cid", "client_secret": "sec", "refresh_token": "rt\nextra: notes"} {
+		if err := os.MkdirAll(filepath.Join(store, "spotify"), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(store, "spotify", k), []byte(v+"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := context.Background()
+	p := PassSource{Command: bin}
+	c, err := p.Credentials(ctx)
+	if err != nil || c != (Credentials{ClientID: "cid", ClientSecret: "sec", RefreshToken: "rt"}) {
+		t.Fatalf("creds=%+v err=%v", c, err)
+	}
+	if err := p.WriteRefreshToken(ctx, "rt2"); err != nil {
+		t.Fatal(err)
+	}
+	if c, err := p.Credentials(ctx); err != nil || c.RefreshToken != "rt2" {
+		t.Fatalf("after rotation: %+v %v", c, err)
+	}
+}
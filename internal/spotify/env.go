@@ -10,18 +10,49 @@ import (
 
 type Credentials struct {
 	ClientID     string
-	ClientSecret string
+	ClientSecret string // unused by a public client
 	RefreshToken string
+	PublicClient bool // see Public
 }
 
+// Public reports whether these are public-client (PKCE) credentials: no
+// secret, client_id goes in the token request body instead of basic auth.
+// Refresh tokens must then come from a PKCE login. It has to be asked for;
+// a missing secret alone is an error (see Check).
+func (c Credentials) Public() bool {
+	return c.PublicClient
+}
+
+// Fingerprint identifies the app and account (client ID and refresh token)
+// without revealing either; caches are keyed by it.
+func (c Credentials) Fingerprint() string {
+	return hashHex(c.ClientID + "\n" + c.RefreshToken)
+}
+
+// Check reports a missing client secret for a confidential client.
+func (c Credentials) Check() error {
+	if !c.PublicClient && c.ClientSecret == "" {
+		return ErrMissingClientSecret
+	}
+	return nil
+}
+
+var ErrMissingClientSecret = errors.New("missing client secret")
+
+// LoadCredentialsFromEnv reads SPOTIFY_CLIENT_ID, SPOTIFY_CLIENT_SECRET and
+// SPOTIFY_REFRESH_TOKEN. The secret may be unset; whether that's allowed is
+// up to PublicClient, which the caller sets.
 func LoadCredentialsFromEnv() (Credentials, error) {
 	cid, err := ReadSecretEnvOrFile("SPOTIFY_CLIENT_ID")
 	if err != nil {
 		return Credentials{}, err
 	}
-	sec, err := ReadSecretEnvOrFile("SPOTIFY_CLIENT_SECRET")
-	if err != nil {
-		return Credentials{}, err
+	var sec string
+	if EnvSet("SPOTIFY_CLIENT_SECRET") {
+		sec, err = ReadSecretEnvOrFile("SPOTIFY_CLIENT_SECRET")
+		if err != nil {
+			return Credentials{}, err
+		}
 	}
 	rt, err := ReadSecretEnvOrFile("SPOTIFY_REFRESH_TOKEN")
 	if err != nil {
@@ -30,6 +61,11 @@ func LoadCredentialsFromEnv() (Credentials, error) {
 	return Credentials{ClientID: cid, ClientSecret: sec, RefreshToken: rt}, nil
 }
 
+// EnvSet reports whether key or key_FILE is set to something non-blank.
+func EnvSet(key string) bool {
+	return strings.TrimSpace(os.Getenv(key)) != "" || os.Getenv(key+"_FILE") != ""
+}
+
 func ReadSecretEnvOrFile(key string) (string, error) {
 	// _FILE override is handy in some environments.
 	if fp := os.Getenv(key + "_FILE"); fp != "" {
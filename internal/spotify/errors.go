@@ -1,18 +1,54 @@
 package spotify
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Error kinds. An *APIError matches at most one of them with errors.Is,
+// based on its status code and error.reason (or, for endpoints that send no
+// reason, its message).
+var (
+	// ErrUnauthorized: the access token was rejected, or the token endpoint
+	// refused the refresh token / client credentials.
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrScopeMissing: the token is valid but wasn't granted a scope the
+	// endpoint needs.
+	ErrScopeMissing = errors.New("missing scope")
+	// ErrPremiumRequired: playback control needs a Premium account.
+	ErrPremiumRequired = errors.New("premium required")
+	// ErrRateLimited: a 429 that wasn't retried; APIError.RetryAfter says how
+	// long to wait.
+	ErrRateLimited = errors.New("rate limited")
+	// ErrNoActiveDevice: a player command with no device to act on.
+	ErrNoActiveDevice = errors.New("no active device")
+	// ErrRestricted: the player refused the command (restriction violated,
+	// device not controllable, already paused, ...).
+	ErrRestricted = errors.New("restricted")
+	// ErrNotFound: the resource doesn't exist (or isn't visible to the user).
+	ErrNotFound = errors.New("not found")
+)
 
 // APIError is a structured error returned by Spotify Web API.
 //
 // Most endpoints respond with:
 //
-//	{"error":{"status":403,"message":"..."}}
+//	{"error":{"status":403,"message":"...","reason":"PREMIUM_REQUIRED"}}
 //
-// We keep the original HTTP status code plus the parsed message.
+// We keep the original HTTP status code plus the parsed message and reason.
 type APIError struct {
 	StatusCode int
 	Message    string
+	Reason     string
 	Body       string
+
+	// RetryAfter is the server's requested wait on a 429, if it sent one.
+	RetryAfter time.Duration
+
+	// Attempts is the retry history for the request, oldest first.
+	Attempts []Attempt
 }
 
 func (e *APIError) Error() string {
@@ -23,5 +59,101 @@ func (e *APIError) Error() string {
 	if msg == "" {
 		msg = "spotify api error"
 	}
+	if n := len(e.Attempts); n > 1 {
+		return fmt.Sprintf("spotify api error (%d): %s (after %d attempts)", e.StatusCode, msg, n)
+	}
 	return fmt.Sprintf("spotify api error (%d): %s", e.StatusCode, msg)
 }
+
+// Is matches the error kind (ErrNotFound etc.) this error belongs to.
+func (e *APIError) Is(target error) bool {
+	k := e.kind()
+	return k != nil && k == target
+}
+
+// restrictionReasons are the player's error.reason values for a command the
+// current playback state or device doesn't allow. UNKNOWN is left out: it
+// only counts when the message says the player refused the command.
+var restrictionReasons = map[string]bool{
+	"NO_PREV_TRACK":           true,
+	"NO_NEXT_TRACK":           true,
+	"NO_SPECIFIC_TRACK":       true,
+	"ALREADY_PAUSED":          true,
+	"NOT_PAUSED":              true,
+	"NOT_PLAYING_LOCALLY":     true,
+	"NOT_PLAYING_TRACK":       true,
+	"NOT_PLAYING_CONTEXT":     true,
+	"ENDLESS_CONTEXT":         true,
+	"CONTEXT_DISALLOW":        true,
+	"ALREADY_PLAYING":         true,
+	"REMOTE_CONTROL_DISALLOW": true,
+	"DEVICE_NOT_CONTROLLABLE": true,
+	"VOLUME_CONTROL_DISALLOW": true,
+}
+
+func (e *APIError) kind() error {
+	m := strings.ToLower(e.Message)
+	switch e.StatusCode {
+	case 401:
+		if strings.Contains(m, "permissions missing") {
+			return ErrScopeMissing
+		}
+		return ErrUnauthorized
+	case 403:
+		switch {
+		case e.Reason == "PREMIUM_REQUIRED" || strings.Contains(m, "premium required"):
+			return ErrPremiumRequired
+		case strings.Contains(m, "insufficient client scope") || strings.Contains(m, "permissions missing"):
+			return ErrScopeMissing
+		case restrictionReasons[e.Reason] || strings.Contains(m, "restriction violated") || strings.Contains(m, "cannot control device"):
+			return ErrRestricted
+		case e.Reason == "UNKNOWN" && strings.HasPrefix(m, "player command failed"):
+			return ErrRestricted
+		}
+	case 404:
+		if e.Reason == "NO_ACTIVE_DEVICE" || strings.Contains(m, "no active device") {
+			return ErrNoActiveDevice
+		}
+		return ErrNotFound
+	case 429:
+		return ErrRateLimited
+	}
+	return nil
+}
+
+// ScopeError reports scopes a command needs that the token wasn't granted.
+type ScopeError struct {
+	Missing []string
+}
+
+func (e *ScopeError) Error() string {
+	return "access token is missing required scope(s): " + strings.Join(e.Missing, " ")
+}
+
+func (e *ScopeError) Is(target error) bool { return target == ErrScopeMissing }
+
+// tokenError is a token endpoint failure; rejected credentials match
+// ErrUnauthorized.
+type tokenError struct {
+	msg          string
+	unauthorized bool
+}
+
+func (e *tokenError) Error() string { return e.msg }
+
+func (e *tokenError) Is(target error) bool {
+	return e.unauthorized && target == ErrUnauthorized
+}
+
+// ResponseTooLargeError is returned when a response body exceeds
+// ClientOptions.MaxResponseBytes. Most large reads (playlists, albums) take a
+// fields= parameter that trims the response to what the caller needs.
+type ResponseTooLargeError struct {
+	Method string
+	Path   string
+	Limit  int64
+}
+
+func (e *ResponseTooLargeError) Error() string {
+	return fmt.Sprintf("%s %s: response exceeds %d bytes; request fewer fields (e.g. fields=items(track(uri,name))) or raise the response size limit", e.Method, e.Path, e.Limit)
+}
@@ -0,0 +1,45 @@
+package spotify
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestAPIErrorKinds(t *testing.T) {
+	cases := []struct {
+		err  *APIError
+		want error
+	}{
+		{&APIError{StatusCode: 401, Message: "The access token expired"}, ErrUnauthorized},
+		{&APIError{StatusCode: 401, Message: "Permissions missing"}, ErrScopeMissing},
+		{&APIError{StatusCode: 403, Message: "Insufficient client scope"}, ErrScopeMissing},
+		{&APIError{StatusCode: 403, Message: "Player command failed: Premium required", Reason: "PREMIUM_REQUIRED"}, ErrPremiumRequired},
+		{&APIError{StatusCode: 403, Message: "Player command failed: Restriction violated", Reason: "UNKNOWN"}, ErrRestricted},
+		{&APIError{StatusCode: 403, Message: "Player command failed: Already paused", Reason: "ALREADY_PAUSED"}, ErrRestricted},
+		{&APIError{StatusCode: 403, Message: "Player command failed: Something went wrong", Reason: "UNKNOWN"}, ErrRestricted},
+		{&APIError{StatusCode: 403, Message: "Something went wrong", Reason: "UNKNOWN"}, nil},
+		{&APIError{StatusCode: 403, Message: "You cannot add tracks to a playlist you don't own.", Reason: "SOME_NEW_REASON"}, nil},
+		{&APIError{StatusCode: 404, Message: "Player command failed: No active device found", Reason: "NO_ACTIVE_DEVICE"}, ErrNoActiveDevice},
+		{&APIError{StatusCode: 404, Message: "Resource not found"}, ErrNotFound},
+		{&APIError{StatusCode: 429}, ErrRateLimited},
+		{&APIError{StatusCode: 403, Message: "User not registered in the Developer Dashboard"}, nil},
+		{&APIError{StatusCode: 502}, nil},
+	}
+	all := []error{ErrUnauthorized, ErrScopeMissing, ErrPremiumRequired, ErrRateLimited, ErrNoActiveDevice, ErrRestricted, ErrNotFound}
+	for _, tc := range cases {
+		err := fmt.Errorf("wrapped: %w", tc.err)
+		for _, k := range all {
+			if got := errors.Is(err, k); got != (k == tc.want) {
+				t.Errorf("%v: errors.Is(%v)=%v", tc.err, k, got)
+			}
+		}
+	}
+}
+
+func TestDecodeAPIErrorReason(t *testing.T) {
+	e := decodeAPIError(403, "403 Forbidden", []byte(`{"error":{"status":403,"message":"Player command failed: Premium required","reason":"PREMIUM_REQUIRED"}}`))
+	if e.Reason != "PREMIUM_REQUIRED" || !errors.Is(e, ErrPremiumRequired) {
+		t.Fatalf("%+v", e)
+	}
+}
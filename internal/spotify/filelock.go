@@ -0,0 +1,50 @@
+package spotify
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// fileLock is an advisory lock on a file next to some shared state (e.g.
+// token.json.lock), held across processes while one of them updates it.
+type fileLock struct {
+	f *os.File
+}
+
+// lockFile blocks until it holds the lock on path or ctx ends. The lock is
+// released when the process exits, so a crashed holder can't wedge others.
+func lockFile(ctx context.Context, path string) (*fileLock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		ok, err := tryLockFile(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		if ok {
+			return &fileLock{f: f}, nil
+		}
+		select {
+		case <-ctx.Done():
+			f.Close()
+			return nil, ctx.Err()
+		case <-time.After(25 * time.Millisecond):
+		}
+	}
+}
+
+func (l *fileLock) Unlock() error {
+	err := unlockFile(l.f)
+	if cerr := l.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
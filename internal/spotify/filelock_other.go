@@ -0,0 +1,11 @@
+//go:build !unix
+
+package spotify
+
+import "os"
+
+// Without flock, processes sharing a token cache may each refresh; the
+// cache itself is still replaced atomically.
+func tryLockFile(*os.File) (bool, error) { return true, nil }
+
+func unlockFile(*os.File) error { return nil }
@@ -3,15 +3,15 @@ package spotify
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
-	"os"
-	"strconv"
 	"strings"
 	"time"
 )
@@ -20,14 +20,53 @@ type ClientOptions struct {
 	HTTP      *http.Client
 	APIBase   string
 	UserAgent string
+
+	// PlayerCacheTTL caches device list + playback state reads; 0 disables.
+	PlayerCacheTTL time.Duration
+
+	// Retry is the retry/backoff policy; the zero value uses DefaultRetryPolicy.
+	Retry RetryPolicy
+
+	// Limiter paces requests (and may be shared between clients); nil sends
+	// as fast as callers ask.
+	Limiter *RateLimiter
+
+	// Cache stores GET responses on disk (see HTTPCache); nil disables.
+	Cache *HTTPCache
+
+	// Logger receives debug-level traces of every request (IDs, timing,
+	// retries, redacted headers); nil disables tracing.
+	Logger *slog.Logger
+
+	// MaxResponseBytes caps how much of a response body is read; 0 uses
+	// DefaultMaxResponseBytes. Larger responses fail with
+	// *ResponseTooLargeError.
+	MaxResponseBytes int64
+
+	// Market is sent with searches (ISO 3166-1 alpha-2, or "from_token");
+	// empty leaves it to Spotify.
+	Market string
 }
 
+// DefaultMaxResponseBytes is enough for a full page of any list endpoint;
+// whole-playlist reads of very long playlists may need fields= filtering.
+const DefaultMaxResponseBytes = 2 << 20
+
 type Client struct {
 	tok *TokenManager
 	hc  *http.Client
 
 	apiBase   string
 	userAgent string
+
+	player *playerCache
+	retry  RetryPolicy
+	limit  *RateLimiter
+	cache  *HTTPCache
+	log    *slog.Logger
+
+	maxBody int64
+	market  string
 }
 
 func NewClient(tok *TokenManager, opt ClientOptions) *Client {
@@ -43,7 +82,15 @@ func NewClient(tok *TokenManager, opt ClientOptions) *Client {
 	if hc == nil {
 		hc = http.DefaultClient
 	}
-	return &Client{tok: tok, hc: hc, apiBase: base, userAgent: ua}
+	maxBody := opt.MaxResponseBytes
+	if maxBody <= 0 {
+		maxBody = DefaultMaxResponseBytes
+	}
+	c := &Client{tok: tok, hc: hc, apiBase: base, userAgent: ua, retry: opt.Retry.withDefaults(), limit: opt.Limiter, cache: opt.Cache, log: opt.Logger, maxBody: maxBody, market: opt.Market}
+	if opt.PlayerCacheTTL > 0 {
+		c.player = &playerCache{ttl: opt.PlayerCacheTTL}
+	}
+	return c
 }
 
 type DefaultHTTPClientOptions struct {
@@ -82,8 +129,19 @@ func (c *Client) do(ctx context.Context, method, path string, q url.Values, body
 		bodyBytes = bb
 	}
 
-	debug := os.Getenv("SPOTCTL_DEBUG") != "" || os.Getenv("SPOTCTL_DEBUG_HTTP") != ""
-	try := func(forceRefresh bool) (*http.Response, []byte, error) {
+	rid := newRequestID()
+	var cached *cacheEntry
+	useCache := c.cache.cacheable(ctx, method, path) && c.cacheReady(ctx)
+	if useCache {
+		if cached = c.cache.get(path, u); cached != nil && cached.fresh(time.Now()) {
+			c.trace(ctx, "http cache hit", "req_id", rid, "method", method, "url", u)
+			return decodeBody(cached.Body, out)
+		}
+	}
+
+	// try leaves the body of a 2xx response open for decodeStream; other
+	// bodies are small and read into bb by the loop below.
+	try := func(forceRefresh bool, attempt int) (*http.Response, error) {
 		var token string
 		var err error
 		if forceRefresh {
@@ -92,7 +150,7 @@ func (c *Client) do(ctx context.Context, method, path string, q url.Values, body
 			token, err = c.tok.AccessToken(ctx)
 		}
 		if err != nil {
-			return nil, nil, err
+			return nil, &permanentError{err}
 		}
 
 		var r io.Reader
@@ -101,88 +159,234 @@ func (c *Client) do(ctx context.Context, method, path string, q url.Values, body
 		}
 		req, err := http.NewRequestWithContext(ctx, method, u, r)
 		if err != nil {
-			return nil, nil, err
+			return nil, &permanentError{err}
 		}
 		req.Header.Set("Authorization", "Bearer "+token)
 		req.Header.Set("User-Agent", c.userAgent)
 		if bodyBytes != nil {
 			req.Header.Set("Content-Type", "application/json")
 		}
+		if cached != nil && cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		t0 := time.Now()
 		resp, err := c.hc.Do(req)
 		if err != nil {
-			return nil, nil, err
-		}
-		bb, _ := ioReadAllLimit(resp.Body, 2<<20)
-		_ = resp.Body.Close()
-		if debug {
-			log.Printf("spotctl http: %s %s -> %d", method, u, resp.StatusCode)
+			c.trace(ctx, "http request failed", "req_id", rid, "attempt", attempt, "method", method, "url", u,
+				"duration_ms", time.Since(t0).Milliseconds(), "error", err.Error(), "request_headers", redactHeaders(req.Header))
+			return nil, err
 		}
-		return resp, bb, nil
+		c.trace(ctx, "http request", "req_id", rid, "attempt", attempt, "method", method, "url", u,
+			"status", resp.StatusCode, "duration_ms", time.Since(t0).Milliseconds(),
+			"request_headers", redactHeaders(req.Header), "response_headers", redactHeaders(resp.Header))
+		return resp, nil
 	}
 
-	// Retry policy:
-	// - 401 -> refresh and retry once
-	// - 429 -> wait Retry-After and retry once
-	// - 5xx -> small backoff and retry twice
-	resp, bb, err := try(false)
-	if err != nil {
-		return err
-	}
-	if resp.StatusCode == 401 {
-		resp, bb, err = try(true)
-		if err != nil {
+	// See RetryPolicy for what is retried.
+	pol := c.retry
+	start := time.Now()
+	var (
+		resp      *http.Response
+		bb        []byte
+		attempts  []Attempt
+		refreshed bool
+		force     bool
+		tries     int
+	)
+	for {
+		// Don't silently sleep through a long cooldown (possibly inherited
+		// from a previous run); report it like the 429 that caused it.
+		if d := time.Until(c.limit.CooldownUntil()); d > pol.MaxRetryAfter {
+			return &APIError{
+				StatusCode: 429,
+				Message:    fmt.Sprintf("rate limited (cooling down for %ds); wait then retry", int(d.Seconds())),
+				RetryAfter: d,
+				Attempts:   attempts,
+			}
+		}
+		w0 := time.Now()
+		if err := c.limit.Wait(ctx); err != nil {
 			return err
 		}
-	}
-	if resp.StatusCode == 429 {
-		ra := resp.Header.Get("Retry-After")
-		if ra != "" {
-			if secs, err := strconv.Atoi(strings.TrimSpace(ra)); err == nil && secs > 0 {
-				maxWait := 15
-				if s := strings.TrimSpace(os.Getenv("SPOTCTL_MAX_RETRY_AFTER_SECS")); s != "" {
-					if v, err := strconv.Atoi(s); err == nil && v > 0 {
-						maxWait = v
-					}
-				}
-				if secs > maxWait {
-					return &APIError{StatusCode: 429, Message: fmt.Sprintf("rate limited (Retry-After=%ds); wait then retry", secs), Body: strings.TrimSpace(string(bb))}
-				}
-				select {
-				case <-ctx.Done():
-					return ctx.Err()
-				case <-time.After(time.Duration(secs) * time.Second):
-				}
-				resp, bb, err = try(false)
-				if err != nil {
-					return err
+		if d := time.Since(w0); d > time.Millisecond {
+			c.trace(ctx, "rate limiter wait", "req_id", rid, "wait_ms", d.Milliseconds())
+		}
+		t0 := time.Now()
+		var err error
+		resp, err = try(force, len(attempts)+1)
+		force = false
+		a := Attempt{Duration: time.Since(t0)}
+		if err == nil && !success(resp.StatusCode) {
+			bb, _ = ioReadAllLimit(resp.Body, c.maxBody)
+			_ = resp.Body.Close()
+		}
+		if err != nil {
+			var pe *permanentError
+			if errors.As(err, &pe) {
+				return pe.err
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			a.Err = err.Error()
+		} else {
+			a.StatusCode = resp.StatusCode
+		}
+		attempts = append(attempts, a)
+
+		var wait time.Duration
+		retry := false
+		switch {
+		case err != nil:
+			tries++
+			retry = idempotent(method)
+			wait = pol.backoff(tries)
+		case resp.StatusCode == 401 && !refreshed:
+			refreshed, force, retry = true, true, true
+		case resp.StatusCode == 429:
+			tries++
+			retry = true
+			ra, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+			if !ok {
+				ra = pol.backoff(tries)
+			}
+			c.limit.Throttled(ra)
+			switch {
+			case !ok:
+				wait = ra
+			case ra > pol.MaxRetryAfter:
+				return &APIError{
+					StatusCode: 429,
+					Message:    fmt.Sprintf("rate limited (Retry-After=%ds); wait then retry", int(ra.Seconds())),
+					Body:       strings.TrimSpace(string(bb)),
+					RetryAfter: ra,
+					Attempts:   attempts,
 				}
+			default:
+				wait = ra
 			}
+		case resp.StatusCode >= 500 && resp.StatusCode <= 599:
+			tries++
+			retry = true
+			wait = pol.backoff(tries)
+		case resp.StatusCode < 400:
+			c.limit.Succeeded()
 		}
-	}
-	for attempt := 0; attempt < 2 && resp.StatusCode >= 500 && resp.StatusCode <= 599; attempt++ {
-		d := time.Duration(attempt+1) * time.Second
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-time.After(d):
+		if retry && !force {
+			if tries >= pol.MaxAttempts || time.Since(start)+wait > pol.Budget {
+				retry = false
+			}
 		}
-		resp, bb, err = try(false)
-		if err != nil {
+		if !retry {
+			if err != nil {
+				if len(attempts) > 1 {
+					return fmt.Errorf("%s %s: giving up after %d attempts: %w", method, path, len(attempts), err)
+				}
+				return err
+			}
+			break
+		}
+		attempts[len(attempts)-1].Wait = wait
+		c.trace(ctx, "http retry", "req_id", rid, "attempt", len(attempts), "status", a.StatusCode, "error", a.Err,
+			"wait_ms", wait.Milliseconds(), "token_refresh", force)
+		if err := sleepCtx(ctx, wait); err != nil {
 			return err
 		}
 	}
 
+	status := resp.StatusCode
+	open := success(status)
+	if open {
+		defer resp.Body.Close()
+	}
+	switch {
+	case status == http.StatusNotModified && cached != nil:
+		bb, status = cached.Body, http.StatusOK
+		h := resp.Header.Clone()
+		if h.Get("ETag") == "" {
+			h.Set("ETag", cached.ETag)
+		}
+		c.cache.put(path, u, h, bb)
+	case method != http.MethodGet && open && c.cacheReady(ctx):
+		c.cache.invalidate(path)
+	}
+
 	ok := false
 	for _, s := range expectedStatus {
-		if resp.StatusCode == s {
+		if status == s {
 			ok = true
 			break
 		}
 	}
 	if !ok {
-		return decodeAPIError(resp.StatusCode, resp.Status, bb)
+		if open {
+			bb, _ = ioReadAllLimit(resp.Body, c.maxBody)
+		}
+		err := decodeAPIError(resp.StatusCode, resp.Status, bb)
+		err.Attempts = attempts
+		if ra, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok && status == 429 {
+			err.RetryAfter = ra
+		}
+		return err
+	}
+	if !open {
+		return decodeBody(bb, out)
+	}
+
+	var tee *bytes.Buffer
+	if useCache && status == http.StatusOK && out != nil {
+		tee = &bytes.Buffer{}
+	}
+	if err := c.decodeStream(method, path, resp.Body, out, tee); err != nil {
+		return err
+	}
+	if tee != nil {
+		c.cache.put(path, u, resp.Header, tee.Bytes())
+	}
+	return nil
+}
+
+func success(status int) bool {
+	return status >= 200 && status <= 299
+}
+
+// decodeStream decodes a 2xx body into out as it arrives instead of
+// buffering it first, failing with *ResponseTooLargeError once more than
+// maxBody bytes have been read. If tee is set it receives a copy of the whole
+// body (for the HTTP cache).
+func (c *Client) decodeStream(method, path string, body io.Reader, out any, tee *bytes.Buffer) error {
+	lr := &io.LimitedReader{R: body, N: c.maxBody + 1}
+	tooLarge := &ResponseTooLargeError{Method: method, Path: path, Limit: c.maxBody}
+	if out == nil {
+		// Drain (up to the limit) so the connection can be reused.
+		_, _ = io.Copy(io.Discard, lr)
+		return nil
+	}
+	var r io.Reader = lr
+	if tee != nil {
+		r = io.TeeReader(lr, tee)
+	}
+	err := json.NewDecoder(r).Decode(out)
+	if lr.N <= 0 {
+		return tooLarge
+	}
+	switch {
+	case errors.Is(err, io.EOF):
+		// Empty body, e.g. 204 No Content (callers list it in expectedStatus).
+		return nil
+	case err != nil:
+		return fmt.Errorf("decode response: %w", err)
+	}
+	if tee != nil {
+		_, _ = io.Copy(io.Discard, r)
+		if lr.N <= 0 {
+			return tooLarge
+		}
 	}
+	return nil
+}
 
+func decodeBody(bb []byte, out any) error {
 	if out != nil {
 		// Some endpoints legitimately return 204 No Content.
 		// Callers can include 204 in expectedStatus and then interpret an empty body.
@@ -196,17 +400,18 @@ func (c *Client) do(ctx context.Context, method, path string, q url.Values, body
 	return nil
 }
 
-func decodeAPIError(code int, status string, body []byte) error {
-	// Spotify: {"error":{"status":401,"message":"..."}}
+func decodeAPIError(code int, status string, body []byte) *APIError {
+	// Spotify: {"error":{"status":401,"message":"...","reason":"..."}}
 	var e struct {
 		Error struct {
 			Status  int    `json:"status"`
 			Message string `json:"message"`
+			Reason  string `json:"reason"`
 		} `json:"error"`
 	}
 	if err := json.Unmarshal(body, &e); err == nil {
 		if e.Error.Message != "" {
-			return &APIError{StatusCode: code, Message: e.Error.Message, Body: strings.TrimSpace(string(body))}
+			return &APIError{StatusCode: code, Message: e.Error.Message, Reason: e.Error.Reason, Body: strings.TrimSpace(string(body))}
 		}
 	}
 	msg := strings.TrimSpace(string(body))
@@ -216,6 +421,27 @@ func decodeAPIError(code int, status string, body []byte) error {
 	return &APIError{StatusCode: code, Message: msg, Body: msg}
 }
 
+func (c *Client) trace(ctx context.Context, msg string, args ...any) {
+	if c.log != nil {
+		c.log.DebugContext(ctx, msg, args...)
+	}
+}
+
+// newRequestID tags the trace lines of one logical request (all attempts).
+func newRequestID() string {
+	var b [6]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// permanentError marks failures inside do that happen before anything is sent
+// (token refresh, request construction); they are returned as-is instead of
+// being retried as network errors.
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
 func ioReadAllLimit(r io.Reader, limit int64) ([]byte, error) {
 	lr := &io.LimitedReader{R: r, N: limit + 1}
 	b, err := io.ReadAll(lr)
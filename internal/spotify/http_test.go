@@ -0,0 +1,38 @@
+package spotify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestResponseTooLarge(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/token" {
+			tokenHandler(w)
+			return
+		}
+		fmt.Fprintf(w, `{"id":"u1","display_name":%q}`, strings.Repeat("x", 200))
+	}))
+	defer srv.Close()
+
+	c := newTestClient(t, srv, ClientOptions{MaxResponseBytes: 100})
+	_, err := c.Me(context.Background())
+	var tl *ResponseTooLargeError
+	if !errors.As(err, &tl) {
+		t.Fatalf("err=%v", err)
+	}
+	if tl.Path != "/v1/me" || tl.Limit != 100 || !strings.Contains(err.Error(), "fields=") {
+		t.Fatalf("err=%+v (%v)", tl, err)
+	}
+
+	c = newTestClient(t, srv, ClientOptions{MaxResponseBytes: 1000})
+	u, err := c.Me(context.Background())
+	if err != nil || u.ID != "u1" {
+		t.Fatalf("user=%+v err=%v", u, err)
+	}
+}
@@ -0,0 +1,194 @@
+package spotify
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HTTPCache is an on-disk cache for GET responses. Entries are revalidated
+// with If-None-Match when Spotify sent an ETag, and served without a request
+// while Cache-Control max-age says they are fresh. Player endpoints are never
+// cached: they change without the URL changing.
+//
+// Successful writes drop cached reads of the same resource (and its parents)
+// so e.g. a playlist rename is visible on the next read.
+//
+// Entries are keyed by URL only, so a cache must not be shared between
+// accounts. A per-account cache keeps entries in a subdirectory named after
+// the client ID and Spotify user id, which stays put when the refresh token
+// rotates (see Client.cacheReady); a shared one doesn't store
+// Cache-Control: private responses.
+type HTTPCache struct {
+	base       string
+	perAccount bool
+	now        func() time.Time
+
+	mu  sync.Mutex
+	dir string // base, or its account subdirectory once known
+}
+
+func NewHTTPCache(dir string, perAccount bool) *HTTPCache {
+	hc := &HTTPCache{base: expandHome(dir), perAccount: perAccount, now: time.Now}
+	if !perAccount {
+		hc.dir = hc.base
+	}
+	return hc
+}
+
+// root is the entries' directory, or "" while the account is unknown.
+func (hc *HTTPCache) root() string {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+	return hc.dir
+}
+
+type noCacheKey struct{}
+
+// WithoutCache returns a context whose requests bypass the HTTP cache
+// (neither read nor written), e.g. for --no-cache.
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noCacheKey{}, true)
+}
+
+type cacheEntry struct {
+	URL     string    `json:"url"`
+	ETag    string    `json:"etag,omitempty"`
+	Expires time.Time `json:"expires"`
+	Body    []byte    `json:"body"`
+}
+
+func (e *cacheEntry) fresh(now time.Time) bool {
+	return now.Before(e.Expires)
+}
+
+// cacheable reports whether a request may use the cache at all.
+func (hc *HTTPCache) cacheable(ctx context.Context, method, p string) bool {
+	if hc == nil || method != http.MethodGet {
+		return false
+	}
+	if ctx.Value(noCacheKey{}) != nil {
+		return false
+	}
+	return p != "/v1/me/player" && !strings.HasPrefix(p, "/v1/me/player/")
+}
+
+// Files are <sha(path)>-<sha(url)>.json so all variants of a resource can be
+// dropped together.
+func (hc *HTTPCache) file(p, u string) string {
+	return filepath.Join(hc.root(), hashHex(p)+"-"+hashHex(u)+".json")
+}
+
+func hashHex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:12])
+}
+
+func (hc *HTTPCache) get(p, u string) *cacheEntry {
+	b, err := os.ReadFile(hc.file(p, u))
+	if err != nil {
+		return nil
+	}
+	var e cacheEntry
+	if json.Unmarshal(b, &e) != nil || e.URL != u {
+		return nil
+	}
+	return &e
+}
+
+// put stores a 200 (or refreshes an entry after a 304) according to the
+// response's ETag and Cache-Control. Responses that are neither
+// revalidatable nor fresh for a while aren't worth keeping.
+func (hc *HTTPCache) put(p, u string, h http.Header, body []byte) {
+	cc := parseCacheControl(h.Get("Cache-Control"))
+	if _, ok := cc["no-store"]; ok {
+		return
+	}
+	if _, ok := cc["private"]; ok && !hc.perAccount {
+		return
+	}
+	e := cacheEntry{URL: u, ETag: h.Get("ETag"), Body: body}
+	if _, ok := cc["no-cache"]; !ok {
+		if secs, err := strconv.Atoi(cc["max-age"]); err == nil && secs > 0 {
+			e.Expires = hc.now().Add(time.Duration(secs) * time.Second)
+		}
+	}
+	if e.ETag == "" && e.Expires.IsZero() {
+		return
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	// Best effort: a failed write only costs a refetch.
+	_ = WriteFileAtomic(hc.file(p, u), b)
+}
+
+// invalidate drops cached reads of p and its parent resources after a write.
+func (hc *HTTPCache) invalidate(p string) {
+	if hc == nil {
+		return
+	}
+	paths := []string{}
+	for q := p; q != "/" && q != "/v1" && q != "."; q = path.Dir(q) {
+		paths = append(paths, q)
+	}
+	// Playlist writes also change the user's playlist listing.
+	if strings.HasPrefix(p, "/v1/playlists/") || strings.HasPrefix(p, "/v1/users/") {
+		paths = append(paths, "/v1/me/playlists")
+	}
+	dir := hc.root()
+	for _, q := range paths {
+		matches, _ := filepath.Glob(filepath.Join(dir, hashHex(q)+"-*.json"))
+		for _, m := range matches {
+			_ = os.Remove(m)
+		}
+	}
+}
+
+func parseCacheControl(v string) map[string]string {
+	out := map[string]string{}
+	for _, part := range strings.Split(v, ",") {
+		k, val, _ := strings.Cut(strings.TrimSpace(part), "=")
+		if k == "" {
+			continue
+		}
+		out[strings.ToLower(k)] = strings.Trim(val, `"`)
+	}
+	return out
+}
+
+// cacheReady reports whether the cache can be used, first finding out whose
+// it is for a per-account cache: the user id comes from the token cache or,
+// once, from /v1/me. Without it the cache is skipped.
+func (c *Client) cacheReady(ctx context.Context) bool {
+	hc := c.cache
+	if hc == nil || !hc.perAccount {
+		return hc != nil
+	}
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+	if hc.dir != "" {
+		return true
+	}
+	uid := c.tok.UserID()
+	if uid == "" {
+		me, err := c.Me(WithoutCache(ctx))
+		if err != nil || me.ID == "" {
+			return false
+		}
+		uid = me.ID
+		c.tok.SetUserID(ctx, uid)
+	}
+	hc.dir = filepath.Join(hc.base, hashHex(c.tok.ClientID()+"\n"+uid))
+	return true
+}
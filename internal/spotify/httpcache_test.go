@@ -0,0 +1,187 @@
+package spotify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+func TestHTTPCacheRevalidatesWithETag(t *testing.T) {
+	var full, notModified, player atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/token":
+			tokenHandler(w)
+		case "/v1/me":
+			fmt.Fprint(w, `{"id":"u1"}`)
+		case "/v1/tracks/t1":
+			w.Header().Set("ETag", `"v1"`)
+			w.Header().Set("Cache-Control", "private, max-age=0")
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				notModified.Add(1)
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			full.Add(1)
+			fmt.Fprint(w, `{"id":"t1","name":"One"}`)
+		case "/v1/me/player":
+			player.Add(1)
+			w.Header().Set("ETag", `"p"`)
+			fmt.Fprint(w, `{"device":{"id":"d1"},"is_playing":true}`)
+		}
+	}))
+	defer srv.Close()
+
+	c := newTestClient(t, srv, ClientOptions{Cache: NewHTTPCache(t.TempDir(), true)})
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		tr, err := c.GetTrack(ctx, "t1")
+		if err != nil || tr.Name != "One" {
+			t.Fatalf("track=%+v err=%v", tr, err)
+		}
+		if _, err := c.PlaybackState(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if full.Load() != 1 || notModified.Load() != 2 {
+		t.Fatalf("full=%d 304s=%d", full.Load(), notModified.Load())
+	}
+	if player.Load() != 3 {
+		t.Fatalf("player endpoint was cached: %d requests", player.Load())
+	}
+
+	if _, err := c.GetTrack(WithoutCache(ctx), "t1"); err != nil {
+		t.Fatal(err)
+	}
+	if full.Load() != 2 {
+		t.Fatalf("WithoutCache should refetch: full=%d", full.Load())
+	}
+}
+
+func TestHTTPCacheFreshAndInvalidate(t *testing.T) {
+	var gets atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/token":
+			tokenHandler(w)
+		case r.Method == http.MethodGet:
+			gets.Add(1)
+			w.Header().Set("Cache-Control", "max-age=300")
+			fmt.Fprint(w, `{"id":"p1","name":"Old","public":false}`)
+		default:
+			w.WriteHeader(200)
+		}
+	}))
+	defer srv.Close()
+
+	c := newTestClient(t, srv, ClientOptions{Cache: NewHTTPCache(t.TempDir(), false)})
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if _, err := c.PlaylistDetails(ctx, "p1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if gets.Load() != 1 {
+		t.Fatalf("fresh entry not served locally: gets=%d", gets.Load())
+	}
+	name := "New"
+	if err := c.UpdatePlaylistDetails(ctx, "p1", nil, &name, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.PlaylistDetails(ctx, "p1"); err != nil {
+		t.Fatal(err)
+	}
+	if gets.Load() != 2 {
+		t.Fatalf("write did not invalidate: gets=%d", gets.Load())
+	}
+}
+
+func TestHTTPCacheSharedDirSkipsPrivate(t *testing.T) {
+	var gets atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/token":
+			tokenHandler(w)
+		case "/v1/me":
+			fmt.Fprint(w, `{"id":"u1"}`)
+		default:
+			gets.Add(1)
+			w.Header().Set("Cache-Control", "private, max-age=300")
+			fmt.Fprint(w, `{"id":"t1"}`)
+		}
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	for _, tt := range []struct {
+		perAccount bool
+		want       int32
+	}{{false, 2}, {true, 1}} {
+		gets.Store(0)
+		c := newTestClient(t, srv, ClientOptions{Cache: NewHTTPCache(dir, tt.perAccount)})
+		for i := 0; i < 2; i++ {
+			if _, err := c.GetTrack(context.Background(), "t1"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if gets.Load() != tt.want {
+			t.Errorf("perAccount=%v: %d requests, want %d", tt.perAccount, gets.Load(), tt.want)
+		}
+	}
+}
+
+// The account directory is keyed by client ID and user id, so it survives
+// refresh token rotation, and the user id is looked up once per token cache.
+func TestHTTPCacheAccountSurvivesRotation(t *testing.T) {
+	var gets, mes, refreshes atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/token":
+			n := refreshes.Add(1)
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"access_token":"at%d","token_type":"Bearer","expires_in":3600,"refresh_token":"rt%d"}`, n, n+1)
+		case "/v1/me":
+			mes.Add(1)
+			fmt.Fprint(w, `{"id":"u1"}`)
+		default:
+			gets.Add(1)
+			w.Header().Set("Cache-Control", "private, max-age=300")
+			fmt.Fprint(w, `{"id":"t1"}`)
+		}
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	tokCache := filepath.Join(dir, "token.json")
+	rtFile := filepath.Join(dir, "refresh_token")
+	ctx := context.Background()
+	for i, rt := range []string{"rt1", "rt2"} {
+		m, err := NewTokenManager(Credentials{ClientID: "cid", ClientSecret: "sec", RefreshToken: rt}, TokenManagerOptions{
+			HTTP: srv.Client(), AccountsBase: srv.URL, CachePath: tokCache, RefreshTokenWriter: RefreshTokenFile{Path: rtFile},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := NewClient(m, ClientOptions{HTTP: srv.Client(), APIBase: srv.URL, Cache: NewHTTPCache(filepath.Join(dir, "http"), true)})
+		if i == 1 {
+			// A new process after the rotation was stored.
+			if _, err := m.ForceRefresh(ctx); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := c.GetTrack(ctx, "t1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if gets.Load() != 1 || mes.Load() != 1 {
+		t.Fatalf("track fetches=%d /me lookups=%d, want 1 each", gets.Load(), mes.Load())
+	}
+	if entries, _ := os.ReadDir(filepath.Join(dir, "http")); len(entries) != 1 {
+		t.Fatalf("%d account dirs, want 1", len(entries))
+	}
+}
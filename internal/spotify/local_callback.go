@@ -2,19 +2,15 @@ package spotify
 
 import (
 	"context"
-	"crypto/ecdsa"
-	"crypto/elliptic"
-	"crypto/rand"
 	"crypto/tls"
-	"crypto/x509"
-	"crypto/x509/pkix"
-	"encoding/pem"
+	"errors"
 	"fmt"
-	"math/big"
+	"html"
 	"net"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -24,38 +20,68 @@ type CallbackResult struct {
 	Error string
 }
 
+// CallbackServerOptions configures StartLocalCallbackServer.
+type CallbackServerOptions struct {
+	// State, if set, must come back in the callback. Requests with another
+	// state get an error page and don't end the wait (a stray or forged
+	// redirect can't abort the login).
+	State string
+	// CertDir, if set, keeps the https callback's certificate in that
+	// directory, signed by a local CA (ca.pem) created there once. Browsers
+	// then warn only the first time, or never once ca.pem is trusted.
+	CertDir string
+}
+
 type LocalCallbackServer struct {
 	RedirectURL string
+	// CAFile is the CA certificate to trust, with CertDir set.
+	CAFile string
 
 	ln     net.Listener
 	srv    *http.Server
 	result chan CallbackResult
 }
 
-// StartLocalCallbackServer starts a local HTTPS server for a redirect_uri like:
+// StartLocalCallbackServer starts a local server for a redirect_uri like:
 //
 //	https://localhost:8899/callback
+//	http://127.0.0.1:8899/callback
+//	http://127.0.0.1/callback
+//
+// https uses a self-signed cert; browsers will show a warning you must
+// accept. Plain http is only allowed on the loopback addresses 127.0.0.1 and
+// [::1], which Spotify accepts as-is; there, a missing (or 0) port picks a
+// free one (RFC 8252) and RedirectURL carries the actual port.
 //
-// Note: uses a self-signed cert; browsers will show a warning you must accept.
-func StartLocalCallbackServer(redirectURI string) (*LocalCallbackServer, error) {
+// Only the first valid callback counts; later ones (a reload, a replayed
+// URL) are refused.
+func StartLocalCallbackServer(redirectURI string, opt CallbackServerOptions) (*LocalCallbackServer, error) {
 	u, err := url.Parse(redirectURI)
 	if err != nil {
 		return nil, fmt.Errorf("invalid redirect uri: %w", err)
 	}
-	if u.Scheme != "https" {
-		return nil, fmt.Errorf("redirect uri must be https (Spotify requires secure redirect URIs): %s", redirectURI)
-	}
 	if u.Host == "" {
 		return nil, fmt.Errorf("redirect uri missing host: %s", redirectURI)
 	}
-	if u.Path == "" {
-		u.Path = "/callback"
-	}
-
 	host := u.Hostname()
 	port := u.Port()
-	if port == "" {
-		return nil, fmt.Errorf("redirect uri must include an explicit port (e.g. https://localhost:8899/callback)")
+	switch u.Scheme {
+	case "https":
+		if port == "" {
+			return nil, fmt.Errorf("redirect uri must include an explicit port (e.g. https://localhost:8899/callback)")
+		}
+	case "http":
+		if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+			return nil, fmt.Errorf("plain http redirect uris must use a loopback IP (http://127.0.0.1:8899/callback or http://[::1]:8899/callback); Spotify rejects http://%s: %s", host, redirectURI)
+		}
+		if port == "" {
+			port = "0"
+		}
+	default:
+		return nil, fmt.Errorf("redirect uri must be https, or http on 127.0.0.1/[::1]: %s", redirectURI)
+	}
+	if u.Path == "" {
+		u.Path = "/callback"
 	}
 	addr := net.JoinHostPort(host, port)
 
@@ -63,15 +89,13 @@ func StartLocalCallbackServer(redirectURI string) (*LocalCallbackServer, error)
 	if err != nil {
 		return nil, fmt.Errorf("listen %s: %w", addr, err)
 	}
-
-	c, err := selfSignedCert(host)
-	if err != nil {
-		_ = ln.Close()
-		return nil, err
+	if port == "0" {
+		u.Host = ln.Addr().String()
 	}
-	cert := &c
 
 	resCh := make(chan CallbackResult, 1)
+	var mu sync.Mutex
+	done := false
 	mux := http.NewServeMux()
 	mux.HandleFunc(u.Path, func(w http.ResponseWriter, r *http.Request) {
 		q := r.URL.Query()
@@ -80,35 +104,86 @@ func StartLocalCallbackServer(redirectURI string) (*LocalCallbackServer, error)
 			State: q.Get("state"),
 			Error: q.Get("error"),
 		}
-		select {
-		case resCh <- res:
-		default:
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case done:
+			callbackPage(w, http.StatusGone, "Already used", "This login callback was already handled. Start a new login if you need another token.")
+			return
+		case res.Code == "" && res.Error == "":
+			callbackPage(w, http.StatusBadRequest, "Not a Spotify callback", "The request has neither code= nor error=.")
+			return
+		case opt.State != "" && res.State != opt.State:
+			callbackPage(w, http.StatusBadRequest, "State mismatch", "This callback doesn't belong to the running login (state mismatch) and was ignored.")
+			return
+		}
+		done = true
+		resCh <- res
+		if res.Error != "" {
+			callbackPage(w, http.StatusOK, "Spotify auth failed", "Spotify returned: "+res.Error+". Check the terminal.")
+			return
 		}
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		_, _ = w.Write([]byte("<html><body><h1>Spotify auth complete</h1><p>You can close this tab.</p></body></html>"))
+		callbackPage(w, http.StatusOK, "Spotify auth complete", "You can close this tab.")
 	})
 
-	srv := &http.Server{Handler: mux}
-	srv.TLSConfig = &tls.Config{
-		Certificates: []tls.Certificate{*cert},
-		MinVersion:   tls.VersionTLS12,
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	var caFile string
+	if u.Scheme == "https" {
+		var cert tls.Certificate
+		if opt.CertDir != "" {
+			cert, caFile, err = persistentCert(expandHome(opt.CertDir), host)
+		} else {
+			cert, err = selfSignedCert(host)
+		}
+		if err != nil {
+			_ = ln.Close()
+			return nil, err
+		}
+		srv.TLSConfig = &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		}
+		go func() {
+			_ = srv.ServeTLS(ln, "", "")
+		}()
+	} else {
+		go func() {
+			_ = srv.Serve(ln)
+		}()
 	}
 
-	go func() {
-		_ = srv.ServeTLS(ln, "", "")
-	}()
-
 	u2 := *u
 	u2.RawQuery = ""
 
 	return &LocalCallbackServer{
 		RedirectURL: u2.String(),
+		CAFile:      caFile,
 		ln:          ln,
 		srv:         srv,
 		result:      resCh,
 	}, nil
 }
 
+// ParseCallbackURL reads the code, state and error from a redirect URL
+// pasted from the browser's address bar (the page itself may have failed to
+// load, e.g. when logging in on another machine).
+func ParseCallbackURL(raw string) (CallbackResult, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return CallbackResult{}, fmt.Errorf("invalid redirect url: %w", err)
+	}
+	q := u.Query()
+	res := CallbackResult{
+		Code:  q.Get("code"),
+		State: q.Get("state"),
+		Error: q.Get("error"),
+	}
+	if res.Code == "" && res.Error == "" {
+		return CallbackResult{}, errors.New("redirect url has neither code= nor error=")
+	}
+	return res, nil
+}
+
 func (s *LocalCallbackServer) Wait(ctx context.Context) (CallbackResult, error) {
 	select {
 	case <-ctx.Done():
@@ -118,6 +193,13 @@ func (s *LocalCallbackServer) Wait(ctx context.Context) (CallbackResult, error)
 	}
 }
 
+func callbackPage(w http.ResponseWriter, status int, title, msg string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_, _ = fmt.Fprintf(w, "<html><head><title>spotctl: %[1]s</title></head><body><h1>%[1]s</h1><p>%[2]s</p></body></html>", html.EscapeString(title), html.EscapeString(msg))
+}
+
 func (s *LocalCallbackServer) Close() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
@@ -127,60 +209,3 @@ func (s *LocalCallbackServer) Close() error {
 	}
 	return nil
 }
-
-func selfSignedCert(host string) (tls.Certificate, error) {
-	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
-	if err != nil {
-		return tls.Certificate{}, err
-	}
-
-	notBefore := time.Now().Add(-1 * time.Minute)
-	notAfter := time.Now().Add(2 * time.Hour)
-
-	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
-	if err != nil {
-		return tls.Certificate{}, err
-	}
-
-	tmpl := x509.Certificate{
-		SerialNumber: serial,
-		Subject: pkix.Name{
-			CommonName: host,
-		},
-		NotBefore: notBefore,
-		NotAfter:  notAfter,
-
-		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
-		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
-		BasicConstraintsValid: true,
-	}
-
-	// SANs
-	if ip := net.ParseIP(host); ip != nil {
-		tmpl.IPAddresses = []net.IP{ip}
-	} else {
-		tmpl.DNSNames = []string{host}
-		// Convenience: if user uses localhost, include 127.0.0.1 too.
-		if strings.EqualFold(host, "localhost") {
-			tmpl.IPAddresses = []net.IP{net.ParseIP("127.0.0.1")}
-		}
-	}
-
-	der, err := x509.CreateCertificate(rand.Reader, &tmpl, &tmpl, &priv.PublicKey, priv)
-	if err != nil {
-		return tls.Certificate{}, err
-	}
-
-	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
-	keyBytes, err := x509.MarshalPKCS8PrivateKey(priv)
-	if err != nil {
-		return tls.Certificate{}, err
-	}
-	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyBytes})
-
-	c, err := tls.X509KeyPair(certPEM, keyPEM)
-	if err != nil {
-		return tls.Certificate{}, err
-	}
-	return c, nil
-}
@@ -0,0 +1,109 @@
+package spotify
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLocalCallbackServerLoopbackHTTP(t *testing.T) {
+	cb, err := StartLocalCallbackServer("http://127.0.0.1/callback", CallbackServerOptions{State: "s1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cb.Close()
+
+	u, err := url.Parse(cb.RedirectURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Port() == "" || u.Port() == "0" || u.Path != "/callback" {
+		t.Fatalf("RedirectURL=%q", cb.RedirectURL)
+	}
+
+	get := func(query string) int {
+		t.Helper()
+		resp, err := http.Get(cb.RedirectURL + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if got := get("?code=evil&state=other"); got != http.StatusBadRequest {
+		t.Fatalf("state mismatch: status %d", got)
+	}
+	if got := get("?code=abc&state=s1"); got != http.StatusOK {
+		t.Fatalf("callback: status %d", got)
+	}
+	if got := get("?code=abc&state=s1"); got != http.StatusGone {
+		t.Fatalf("replay: status %d", got)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	res, err := cb.Wait(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Code != "abc" || res.State != "s1" {
+		t.Fatalf("res=%+v", res)
+	}
+}
+
+func TestLocalCallbackServerRejectsNonLoopbackHTTP(t *testing.T) {
+	for _, uri := range []string{"http://localhost:8899/callback", "http://example.com:8899/callback", "ftp://127.0.0.1/cb"} {
+		if cb, err := StartLocalCallbackServer(uri, CallbackServerOptions{}); err == nil {
+			cb.Close()
+			t.Fatalf("%s: expected error", uri)
+		}
+	}
+}
+
+func TestParseCallbackURL(t *testing.T) {
+	res, err := ParseCallbackURL("  http://127.0.0.1:8899/callback?code=abc&state=xyz\n")
+	if err != nil || res.Code != "abc" || res.State != "xyz" {
+		t.Fatalf("res=%+v err=%v", res, err)
+	}
+	res, err = ParseCallbackURL("http://127.0.0.1:8899/callback?error=access_denied")
+	if err != nil || res.Error != "access_denied" {
+		t.Fatalf("res=%+v err=%v", res, err)
+	}
+	if _, err := ParseCallbackURL("http://127.0.0.1:8899/callback"); err == nil || !strings.Contains(err.Error(), "code=") {
+		t.Fatalf("err=%v", err)
+	}
+}
+
+func TestLocalCallbackServerPersistentCert(t *testing.T) {
+	dir := t.TempDir()
+	serial := func() string {
+		t.Helper()
+		cb, err := StartLocalCallbackServer("https://127.0.0.1:0/callback", CallbackServerOptions{CertDir: dir})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer cb.Close()
+		pemBytes, err := os.ReadFile(cb.CAFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pool := x509.NewCertPool()
+		pool.AppendCertsFromPEM(pemBytes)
+		u, _ := url.Parse(cb.RedirectURL)
+		conn, err := tls.Dial("tcp", u.Host, &tls.Config{RootCAs: pool})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		return conn.ConnectionState().PeerCertificates[0].SerialNumber.String()
+	}
+	if a, b := serial(), serial(); a != b {
+		t.Fatalf("cert changed between logins: %s vs %s", a, b)
+	}
+}
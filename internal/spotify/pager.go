@@ -0,0 +1,187 @@
+package spotify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// Page is one page of a Spotify list response. Offset-paged endpoints fill
+// Total/Limit/Offset; cursor-paged ones (followed artists, recently played)
+// fill Cursors instead. Both set Next while there is more.
+type Page[T any] struct {
+	Items   []T     `json:"items"`
+	Total   int     `json:"total"`
+	Limit   int     `json:"limit"`
+	Offset  int     `json:"offset"`
+	Next    string  `json:"next"`
+	Cursors Cursors `json:"cursors"`
+}
+
+type Cursors struct {
+	After  string `json:"after"`
+	Before string `json:"before"`
+}
+
+type PagerOptions struct {
+	// PageSize is sent as limit= (default 50, the API maximum).
+	PageSize int
+	// MaxItems stops after this many items; 0 means everything. Hitting it
+	// while more items exist makes All return a *TruncatedError.
+	MaxItems int
+	// Key unwraps responses that nest the page in an object, e.g. "artists"
+	// for /v1/me/following.
+	Key string
+}
+
+// TruncatedError reports that a listing stopped at MaxItems before the end.
+// All returns it together with the items it did collect.
+type TruncatedError struct {
+	Path  string
+	Got   int
+	Total int // 0 when the endpoint doesn't report a total
+}
+
+func (e *TruncatedError) Error() string {
+	if e.Total > 0 {
+		return fmt.Sprintf("%s: listing truncated at %d of %d items", e.Path, e.Got, e.Total)
+	}
+	return fmt.Sprintf("%s: listing truncated at %d items", e.Path, e.Got)
+}
+
+// Pager walks a paged list endpoint. It follows the API's next URLs (only
+// their path and query; requests always go to the client's API base) and
+// falls back to cursors.after when a cursor page has no next link.
+type Pager[T any] struct {
+	c    *Client
+	path string
+	base url.Values
+	opt  PagerOptions
+
+	q       url.Values
+	done    bool
+	fetched int
+	total   int
+	more    bool
+}
+
+func NewPager[T any](c *Client, path string, q url.Values, opt PagerOptions) *Pager[T] {
+	if opt.PageSize <= 0 {
+		opt.PageSize = 50
+	}
+	base := cloneValues(q)
+	first := cloneValues(base)
+	first.Set("limit", strconv.Itoa(opt.PageSize))
+	return &Pager[T]{c: c, path: path, base: base, opt: opt, q: first}
+}
+
+// Next fetches the next page. It returns (nil, false, nil) once the listing
+// is exhausted or MaxItems is reached.
+func (p *Pager[T]) Next(ctx context.Context) ([]T, bool, error) {
+	if p.done {
+		return nil, false, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	if p.opt.MaxItems > 0 {
+		if left := p.opt.MaxItems - p.fetched; left < p.opt.PageSize {
+			p.q.Set("limit", strconv.Itoa(left))
+		}
+	}
+
+	var page Page[T]
+	if p.opt.Key == "" {
+		if err := p.c.do(ctx, "GET", p.path, p.q, nil, &page, 200); err != nil {
+			return nil, false, err
+		}
+	} else {
+		var wrapped map[string]json.RawMessage
+		if err := p.c.do(ctx, "GET", p.path, p.q, nil, &wrapped, 200); err != nil {
+			return nil, false, err
+		}
+		if raw, ok := wrapped[p.opt.Key]; ok {
+			if err := json.Unmarshal(raw, &page); err != nil {
+				return nil, false, fmt.Errorf("decode %s page: %w", p.path, err)
+			}
+		}
+	}
+
+	items := page.Items
+	if p.opt.MaxItems > 0 && p.fetched+len(items) > p.opt.MaxItems {
+		items = items[:p.opt.MaxItems-p.fetched]
+	}
+	p.fetched += len(items)
+	p.total = page.Total
+
+	p.more = false
+	switch {
+	case len(page.Items) == 0:
+	case page.Next != "":
+		if u, err := url.Parse(page.Next); err == nil {
+			next := mergeValues(p.base, u.Query())
+			// A next link that points back at the same page would loop forever.
+			p.more = next.Encode() != p.q.Encode()
+			p.q = next
+		}
+	case page.Cursors.After != "":
+		p.q = cloneValues(p.base)
+		p.q.Set("limit", strconv.Itoa(p.opt.PageSize))
+		p.q.Set("after", page.Cursors.After)
+		p.more = true
+	}
+	if len(items) < len(page.Items) {
+		p.more = true
+	}
+	if !p.more || (p.opt.MaxItems > 0 && p.fetched >= p.opt.MaxItems) {
+		p.done = true
+	}
+	return items, len(items) > 0, nil
+}
+
+// Truncated reports whether the pager stopped at MaxItems with items left.
+func (p *Pager[T]) Truncated() bool {
+	return p.done && p.more
+}
+
+// All collects every remaining item. If MaxItems cut the listing short it
+// returns the collected items and a *TruncatedError.
+func (p *Pager[T]) All(ctx context.Context) ([]T, error) {
+	var out []T
+	for {
+		items, ok, err := p.Next(ctx)
+		if err != nil {
+			return out, err
+		}
+		if !ok {
+			break
+		}
+		out = append(out, items...)
+	}
+	if p.Truncated() {
+		return out, &TruncatedError{Path: p.path, Got: p.fetched, Total: p.total}
+	}
+	return out, nil
+}
+
+func cloneValues(v url.Values) url.Values {
+	out := url.Values{}
+	for k, vv := range v {
+		out[k] = append([]string(nil), vv...)
+	}
+	return out
+}
+
+// mergeValues is next's query plus any of base's parameters it dropped
+// (Spotify's next links don't always carry fields=).
+func mergeValues(base, next url.Values) url.Values {
+	out := cloneValues(next)
+	for k, v := range base {
+		if _, ok := out[k]; !ok {
+			out[k] = append([]string(nil), v...)
+		}
+	}
+	return out
+}
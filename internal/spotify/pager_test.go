@@ -0,0 +1,75 @@
+package spotify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestPagerOffsetFollowsNextAndTruncates(t *testing.T) {
+	const total = 7
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/token" {
+			tokenHandler(w)
+			return
+		}
+		if r.URL.Query().Get("fields") == "" {
+			t.Errorf("fields dropped: %s", r.URL)
+		}
+		off, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		lim, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		var items []string
+		for i := off; i < total && i < off+lim; i++ {
+			items = append(items, fmt.Sprintf(`{"id":"p%d"}`, i))
+		}
+		next := "null"
+		if off+lim < total {
+			// Spotify's next links are absolute and omit fields=.
+			next = fmt.Sprintf(`"https://api.spotify.com/v1/me/playlists?offset=%d&limit=%d"`, off+lim, lim)
+		}
+		fmt.Fprintf(w, `{"items":[%s],"total":%d,"limit":%d,"offset":%d,"next":%s}`, strings.Join(items, ","), total, lim, off, next)
+	}))
+	defer srv.Close()
+	c := newTestClient(t, srv, ClientOptions{})
+
+	q := map[string][]string{"fields": {"items(id)"}}
+	all, err := NewPager[Playlist](c, "/v1/me/playlists", q, PagerOptions{PageSize: 3}).All(context.Background())
+	if err != nil || len(all) != total || all[6].ID != "p6" {
+		t.Fatalf("all=%v err=%v", all, err)
+	}
+
+	some, err := NewPager[Playlist](c, "/v1/me/playlists", q, PagerOptions{PageSize: 3, MaxItems: 5}).All(context.Background())
+	var te *TruncatedError
+	if !errors.As(err, &te) || len(some) != 5 || te.Total != total {
+		t.Fatalf("some=%v err=%v", some, err)
+	}
+}
+
+func TestPagerCursor(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/token" {
+			tokenHandler(w)
+			return
+		}
+		switch r.URL.Query().Get("after") {
+		case "":
+			fmt.Fprint(w, `{"artists":{"items":[{"id":"a1"},{"id":"a2"}],"cursors":{"after":"a2"}}}`)
+		case "a2":
+			fmt.Fprint(w, `{"artists":{"items":[{"id":"a3"}],"cursors":{}}}`)
+		default:
+			w.WriteHeader(400)
+		}
+	}))
+	defer srv.Close()
+	c := newTestClient(t, srv, ClientOptions{})
+
+	got, err := NewPager[Artist](c, "/v1/me/following", map[string][]string{"type": {"artist"}}, PagerOptions{Key: "artists"}).All(context.Background())
+	if err != nil || len(got) != 3 || got[2].ID != "a3" {
+		t.Fatalf("got=%v err=%v", got, err)
+	}
+}
@@ -0,0 +1,81 @@
+package spotify
+
+import (
+	"sync"
+	"time"
+)
+
+// playerCache keeps /me/player and /me/player/devices responses for a short
+// TTL. It only pays off in long-lived processes (spotctl daemon) that serve
+// many commands in a row; any player command we send invalidates it.
+type playerCache struct {
+	ttl time.Duration
+
+	mu        sync.Mutex
+	devices   []Device
+	devicesAt time.Time
+	state     *PlaybackState
+	stateAt   time.Time
+}
+
+func (pc *playerCache) getDevices() ([]Device, bool) {
+	if pc == nil {
+		return nil, false
+	}
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if pc.devicesAt.IsZero() || time.Since(pc.devicesAt) > pc.ttl {
+		return nil, false
+	}
+	return append([]Device(nil), pc.devices...), true
+}
+
+func (pc *playerCache) putDevices(devs []Device) {
+	if pc == nil {
+		return
+	}
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.devices = append([]Device(nil), devs...)
+	pc.devicesAt = time.Now()
+}
+
+func (pc *playerCache) getState() (*PlaybackState, bool) {
+	if pc == nil {
+		return nil, false
+	}
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if pc.stateAt.IsZero() || time.Since(pc.stateAt) > pc.ttl {
+		return nil, false
+	}
+	if pc.state == nil {
+		return nil, true
+	}
+	st := *pc.state
+	return &st, true
+}
+
+func (pc *playerCache) putState(st *PlaybackState) {
+	if pc == nil {
+		return
+	}
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if st != nil {
+		cp := *st
+		st = &cp
+	}
+	pc.state = st
+	pc.stateAt = time.Now()
+}
+
+func (pc *playerCache) invalidate() {
+	if pc == nil {
+		return
+	}
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.devicesAt = time.Time{}
+	pc.stateAt = time.Time{}
+}
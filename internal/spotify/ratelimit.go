@@ -0,0 +1,172 @@
+package spotify
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket shared by every request a Client makes (and
+// by several Clients if passed to each). It adapts to observed 429s: each one
+// halves the rate and blocks all callers until Retry-After has passed, and
+// successes slowly raise the rate back. Safe for concurrent use.
+//
+// With StatePath set the 429 cooldown is persisted, so back-to-back CLI runs
+// wait it out instead of hitting the API again straight away.
+type RateLimiter struct {
+	maxRate float64
+	minRate float64
+	burst   float64
+	path    string
+	now     func() time.Time
+
+	mu       sync.Mutex
+	rate     float64
+	tokens   float64
+	last     time.Time
+	cooldown time.Time
+}
+
+type RateLimiterOptions struct {
+	// Rate is the steady-state requests per second (default 10).
+	Rate float64
+	// Burst is the bucket size (default 10).
+	Burst int
+	// StatePath persists the 429 cooldown across processes; "" disables.
+	StatePath string
+	Now       func() time.Time
+}
+
+type rateLimitState struct {
+	CooldownUntil time.Time `json:"cooldown_until"`
+}
+
+func NewRateLimiter(opt RateLimiterOptions) *RateLimiter {
+	rate := opt.Rate
+	if rate <= 0 {
+		rate = 10
+	}
+	burst := float64(opt.Burst)
+	if burst <= 0 {
+		burst = 10
+	}
+	now := opt.Now
+	if now == nil {
+		now = time.Now
+	}
+	l := &RateLimiter{
+		maxRate: rate,
+		minRate: rate / 16,
+		burst:   burst,
+		path:    expandHome(opt.StatePath),
+		now:     now,
+		rate:    rate,
+		tokens:  burst,
+		last:    now(),
+	}
+	if l.path != "" {
+		if b, err := os.ReadFile(l.path); err == nil {
+			var st rateLimitState
+			if json.Unmarshal(b, &st) == nil {
+				l.cooldown = st.CooldownUntil
+			}
+		}
+	}
+	return l
+}
+
+// Wait blocks until a request may be sent.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	for {
+		l.mu.Lock()
+		now := l.now()
+		l.refillLocked(now)
+		var d time.Duration
+		switch {
+		case now.Before(l.cooldown):
+			d = l.cooldown.Sub(now)
+		case l.tokens >= 1:
+			l.tokens--
+			l.mu.Unlock()
+			return nil
+		default:
+			d = time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+		}
+		l.mu.Unlock()
+		if err := sleepCtx(ctx, d); err != nil {
+			return err
+		}
+	}
+}
+
+func (l *RateLimiter) refillLocked(now time.Time) {
+	if el := now.Sub(l.last).Seconds(); el > 0 {
+		l.tokens = min(l.burst, l.tokens+el*l.rate)
+	}
+	l.last = now
+}
+
+// Throttled records a 429: the rate is halved and nobody sends anything for
+// retryAfter.
+func (l *RateLimiter) Throttled(retryAfter time.Duration) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	l.rate = max(l.minRate, l.rate/2)
+	l.tokens = 0
+	until := l.now().Add(retryAfter)
+	changed := until.After(l.cooldown)
+	if changed {
+		l.cooldown = until
+	}
+	l.mu.Unlock()
+	if changed {
+		l.save(until)
+	}
+}
+
+// Succeeded nudges the rate back up after a 2xx/3xx response.
+func (l *RateLimiter) Succeeded() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	l.rate = min(l.maxRate, l.rate+l.maxRate/20)
+	l.mu.Unlock()
+}
+
+// CooldownUntil reports when the last observed 429 cooldown ends.
+func (l *RateLimiter) CooldownUntil() time.Time {
+	if l == nil {
+		return time.Time{}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.cooldown
+}
+
+func (l *RateLimiter) save(until time.Time) {
+	if l.path == "" {
+		return
+	}
+	b, err := json.Marshal(rateLimitState{CooldownUntil: until})
+	if err != nil {
+		return
+	}
+	// Best effort: a lost cooldown only costs one extra 429.
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o700); err != nil {
+		return
+	}
+	tmp := l.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return
+	}
+	_ = os.Rename(tmp, l.path)
+}
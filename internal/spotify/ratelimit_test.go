@@ -0,0 +1,83 @@
+package spotify
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterPacesConcurrentCallers(t *testing.T) {
+	l := NewRateLimiter(RateLimiterOptions{Rate: 200, Burst: 1})
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := l.Wait(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	// 1 from the bucket + 9 at 5ms each.
+	if el := time.Since(start); el < 40*time.Millisecond {
+		t.Fatalf("10 waits took %s; limiter did not pace", el)
+	}
+}
+
+func TestRateLimiterCooldownPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ratelimit.json")
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	l := NewRateLimiter(RateLimiterOptions{StatePath: path, Now: clock})
+	l.Throttled(30 * time.Second)
+	if l.rate != 5 {
+		t.Fatalf("rate=%v want halved", l.rate)
+	}
+
+	l2 := NewRateLimiter(RateLimiterOptions{StatePath: path, Now: clock})
+	if got := l2.CooldownUntil(); !got.Equal(now.Add(30 * time.Second)) {
+		t.Fatalf("cooldown=%s", got)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l2.Wait(ctx); err == nil {
+		t.Fatal("Wait should block during the cooldown")
+	}
+}
+
+func TestRateLimiterRecoversOnlyOnSuccess(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusNotFound)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/token" {
+			tokenHandler(w)
+			return
+		}
+		w.WriteHeader(int(status.Load()))
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	l := NewRateLimiter(RateLimiterOptions{})
+	l.Throttled(0)
+	throttled := l.rate
+	c := newTestClient(t, srv, ClientOptions{Retry: fastRetry(1), Limiter: l})
+	_, _ = c.Me(context.Background())
+	if l.rate != throttled {
+		t.Fatalf("404 raised the rate to %v", l.rate)
+	}
+	status.Store(http.StatusOK)
+	_, _ = c.Me(context.Background())
+	if l.rate <= throttled {
+		t.Fatalf("200 left the rate at %v", l.rate)
+	}
+}
@@ -0,0 +1,307 @@
+package spotify
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Recorder is an http.RoundTripper that keeps every request/response pair so
+// it can be written out as a HAR file (e.g. for --record). Credentials are
+// redacted before anything is stored: auth headers, cookies, token form
+// fields and token values in JSON bodies.
+type Recorder struct {
+	next http.RoundTripper
+	now  func() time.Time
+
+	mu      sync.Mutex
+	entries []harEntry
+}
+
+func NewRecorder(next http.RoundTripper) *Recorder {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Recorder{next: next, now: time.Now}
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		b, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		reqBody = b
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(b))
+	}
+
+	start := r.now()
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	e := harEntry{
+		StartedDateTime: start.UTC().Format(time.RFC3339Nano),
+		Time:            float64(r.now().Sub(start).Microseconds()) / 1000,
+		Request: harRequest{
+			Method:      req.Method,
+			URL:         req.URL.String(),
+			HTTPVersion: "HTTP/1.1",
+			Headers:     harHeaders(req.Header),
+			QueryString: harQuery(req.URL.Query()),
+			HeadersSize: -1,
+			BodySize:    len(reqBody),
+		},
+		Response: harResponse{
+			Status:      resp.StatusCode,
+			StatusText:  http.StatusText(resp.StatusCode),
+			HTTPVersion: "HTTP/1.1",
+			Headers:     harHeaders(resp.Header),
+			Content: harContent{
+				Size:     len(respBody),
+				MimeType: resp.Header.Get("Content-Type"),
+				Text:     redactBody(resp.Header.Get("Content-Type"), respBody),
+			},
+			HeadersSize: -1,
+			BodySize:    len(respBody),
+		},
+	}
+	if len(reqBody) > 0 {
+		ct := req.Header.Get("Content-Type")
+		e.Request.PostData = &harPostData{MimeType: ct, Text: redactBody(ct, reqBody)}
+	}
+
+	r.mu.Lock()
+	r.entries = append(r.entries, e)
+	r.mu.Unlock()
+	return resp, nil
+}
+
+// WriteHAR writes everything recorded so far as a HAR 1.2 document.
+func (r *Recorder) WriteHAR(w io.Writer) error {
+	r.mu.Lock()
+	h := harFile{Log: harLog{
+		Version: "1.2",
+		Creator: harCreator{Name: "spotctl", Version: "0.1"},
+		Entries: append([]harEntry{}, r.entries...),
+	}}
+	r.mu.Unlock()
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(h)
+}
+
+// Save writes the HAR file atomically with 0600 permissions.
+func (r *Recorder) Save(path string) error {
+	path = expandHome(path)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := r.WriteHAR(&buf); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Minimal HAR 1.2 (http://www.softwareishard.com/blog/har-12-spec/); only
+// the fields we write or replay.
+type harFile struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+}
+
+type harRequest struct {
+	Method      string       `json:"method"`
+	URL         string       `json:"url"`
+	HTTPVersion string       `json:"httpVersion"`
+	Headers     []harNV      `json:"headers"`
+	QueryString []harNV      `json:"queryString"`
+	PostData    *harPostData `json:"postData,omitempty"`
+	HeadersSize int          `json:"headersSize"`
+	BodySize    int          `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int        `json:"status"`
+	StatusText  string     `json:"statusText"`
+	HTTPVersion string     `json:"httpVersion"`
+	Headers     []harNV    `json:"headers"`
+	Content     harContent `json:"content"`
+	HeadersSize int        `json:"headersSize"`
+	BodySize    int        `json:"bodySize"`
+}
+
+type harNV struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+func harHeaders(h http.Header) []harNV {
+	out := []harNV{}
+	for _, k := range sortedHeaderKeys(h) {
+		for _, v := range h[k] {
+			out = append(out, harNV{Name: k, Value: redactHeader(k, v)})
+		}
+	}
+	return out
+}
+
+func harQuery(q url.Values) []harNV {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := []harNV{}
+	for _, k := range keys {
+		for _, v := range q[k] {
+			out = append(out, harNV{Name: k, Value: v})
+		}
+	}
+	return out
+}
+
+func sortedHeaderKeys(h http.Header) []string {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+const redacted = "REDACTED"
+
+// redactHeader hides credentials but keeps the auth scheme so traces still
+// show which kind of auth was used.
+func redactHeader(name, v string) string {
+	switch http.CanonicalHeaderKey(name) {
+	case "Authorization", "Proxy-Authorization":
+		if scheme, _, ok := strings.Cut(v, " "); ok {
+			return scheme + " " + redacted
+		}
+		return redacted
+	case "Cookie", "Set-Cookie":
+		return redacted
+	}
+	return v
+}
+
+// redactHeaders is redactHeader over a whole header map, for trace logs.
+func redactHeaders(h http.Header) map[string]string {
+	out := make(map[string]string, len(h))
+	for k, v := range h {
+		out[k] = redactHeader(k, strings.Join(v, ", "))
+	}
+	return out
+}
+
+var secretFields = map[string]bool{
+	"access_token":  true,
+	"refresh_token": true,
+	"client_secret": true,
+	"code":          true,
+	"code_verifier": true,
+}
+
+func redactBody(contentType string, b []byte) string {
+	switch {
+	case strings.HasPrefix(contentType, "application/x-www-form-urlencoded"):
+		vals, err := url.ParseQuery(string(b))
+		if err != nil {
+			return redacted
+		}
+		for k := range vals {
+			if secretFields[k] {
+				vals.Set(k, redacted)
+			}
+		}
+		return vals.Encode()
+	case strings.Contains(contentType, "json") || json.Valid(b):
+		var v any
+		if json.Unmarshal(b, &v) != nil {
+			return string(b)
+		}
+		if !redactJSON(v) {
+			return string(b)
+		}
+		out, err := json.Marshal(v)
+		if err != nil {
+			return redacted
+		}
+		return string(out)
+	}
+	return string(b)
+}
+
+// redactJSON replaces secret fields in place and reports whether it did.
+func redactJSON(v any) bool {
+	changed := false
+	switch v := v.(type) {
+	case map[string]any:
+		for k, x := range v {
+			if _, ok := x.(string); ok && secretFields[k] {
+				v[k] = redacted
+				changed = true
+				continue
+			}
+			changed = redactJSON(x) || changed
+		}
+	case []any:
+		for _, x := range v {
+			changed = redactJSON(x) || changed
+		}
+	}
+	return changed
+}
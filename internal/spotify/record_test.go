@@ -0,0 +1,44 @@
+package spotify
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRecorderRedactsSecrets(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/token" {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"access_token":"AT-SECRET","refresh_token":"RT-NEW","expires_in":3600}`))
+			return
+		}
+		w.Write([]byte(`{"id":"u1"}`))
+	}))
+	defer srv.Close()
+
+	rec := NewRecorder(srv.Client().Transport)
+	hc := &http.Client{Transport: rec}
+	m, _ := NewTokenManager(Credentials{ClientID: "cid", ClientSecret: "CS-SECRET", RefreshToken: "RT-SECRET"}, TokenManagerOptions{HTTP: hc, AccountsBase: srv.URL})
+	c := NewClient(m, ClientOptions{HTTP: hc, APIBase: srv.URL})
+	if _, err := c.Me(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := rec.WriteHAR(&buf); err != nil {
+		t.Fatal(err)
+	}
+	har := buf.String()
+	for _, secret := range []string{"AT-SECRET", "RT-SECRET", "RT-NEW", "CS-SECRET", "Y2lkOkNTLVNFQ1JFVA"} {
+		if strings.Contains(har, secret) {
+			t.Fatalf("HAR leaks %s:\n%s", secret, har)
+		}
+	}
+	if !strings.Contains(har, "/v1/me") {
+		t.Fatalf("HAR missing API request:\n%s", har)
+	}
+}
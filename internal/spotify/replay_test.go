@@ -0,0 +1,91 @@
+package spotify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecordThenReplay(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/token" {
+			tokenHandler(w)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"tracks":{"items":[{"id":"t1","name":"Song %s","uri":"spotify:track:t1"}]}}`, r.URL.Query().Get("q"))
+	}))
+
+	rec := NewRecorder(srv.Client().Transport)
+	hc := &http.Client{Transport: rec}
+	m, _ := NewTokenManager(Credentials{ClientID: "cid", ClientSecret: "sec", RefreshToken: "rt"}, TokenManagerOptions{HTTP: hc, AccountsBase: srv.URL})
+	c := NewClient(m, ClientOptions{HTTP: hc, APIBase: srv.URL})
+	if _, err := c.SearchTracks(context.Background(), "abc", 1); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "session.har")
+	if err := rec.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	srv.Close()
+
+	rp, err := LoadReplayer(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A different host: only method, path and query have to match.
+	hc = &http.Client{Transport: rp}
+	m, _ = NewTokenManager(Credentials{ClientID: "cid", ClientSecret: "sec", RefreshToken: "rt"}, TokenManagerOptions{HTTP: hc, AccountsBase: "https://accounts.example"})
+	c = NewClient(m, ClientOptions{HTTP: hc, APIBase: "https://api.example"})
+	got, err := c.SearchTracks(context.Background(), "abc", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Name != "Song abc" {
+		t.Fatalf("got=%+v", got)
+	}
+
+	_, err = c.SearchTracks(context.Background(), "other", 1)
+	var miss *ReplayMissError
+	if !errors.As(err, &miss) {
+		t.Fatalf("want ReplayMissError, got %v", err)
+	}
+}
+
+func TestReplayJSONCassetteInOrder(t *testing.T) {
+	rp, err := NewReplayer(mustCassette(t, `[
+		{"request":{"method":"POST","url":"https://accounts.spotify.com/api/token"},
+		 "response":{"status":200,"body":"{\"access_token\":\"REDACTED\",\"expires_in\":3600}"}},
+		{"request":{"method":"GET","url":"https://api.spotify.com/v1/me/player"},"response":{"status":204}},
+		{"request":{"method":"GET","url":"https://api.spotify.com/v1/me/player"},
+		 "response":{"status":200,"body":"{\"device\":{\"id\":\"d1\"},\"is_playing\":true}"}}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hc := &http.Client{Transport: rp}
+	m, _ := NewTokenManager(Credentials{ClientID: "cid", ClientSecret: "sec", RefreshToken: "rt"}, TokenManagerOptions{HTTP: hc})
+	c := NewClient(m, ClientOptions{HTTP: hc})
+
+	st, err := c.PlaybackState(context.Background())
+	if err != nil || st != nil {
+		t.Fatalf("first: st=%v err=%v", st, err)
+	}
+	st, err = c.PlaybackState(context.Background())
+	if err != nil || st == nil || !st.IsPlaying {
+		t.Fatalf("second: st=%v err=%v", st, err)
+	}
+}
+
+func mustCassette(t *testing.T, s string) []Interaction {
+	t.Helper()
+	its, err := parseCassette([]byte(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return its
+}
@@ -0,0 +1,129 @@
+package spotify
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy controls how Client retries failed requests. Zero fields take
+// the defaults from DefaultRetryPolicy.
+//
+//   - 401: refresh the access token and retry once (does not count as an attempt)
+//   - 429: wait Retry-After (or back off if absent) on every 429
+//   - 5xx: exponential backoff with jitter
+//   - network errors: backoff, but only for idempotent methods
+type RetryPolicy struct {
+	// MaxAttempts is the total number of tries including the first; 1
+	// disables retries.
+	MaxAttempts int
+	// BaseDelay is the first backoff; each retry doubles it up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// MaxRetryAfter is the longest Retry-After we are willing to sleep for.
+	// Longer waits fail immediately with the 429 so the caller can decide.
+	MaxRetryAfter time.Duration
+	// Budget caps the total time spent on one request including waits; no
+	// retry is started that would exceed it.
+	Budget time.Duration
+
+	// Rand returns a value in [0,1) for jitter (tests); defaults to math/rand.
+	Rand func() float64
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:   3,
+		BaseDelay:     time.Second,
+		MaxDelay:      10 * time.Second,
+		MaxRetryAfter: 15 * time.Second,
+		Budget:        60 * time.Second,
+	}
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	d := DefaultRetryPolicy()
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = d.MaxAttempts
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = d.BaseDelay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = d.MaxDelay
+	}
+	if p.MaxRetryAfter <= 0 {
+		p.MaxRetryAfter = d.MaxRetryAfter
+	}
+	if p.Budget <= 0 {
+		p.Budget = d.Budget
+	}
+	if p.Rand == nil {
+		p.Rand = rand.Float64
+	}
+	return p
+}
+
+// backoff returns the wait before retry n (1-based): BaseDelay*2^(n-1),
+// capped at MaxDelay, with "equal jitter" (between half and all of it).
+func (p RetryPolicy) backoff(n int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < n && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	d = min(d, p.MaxDelay)
+	return d/2 + time.Duration(p.Rand()*float64(d/2))
+}
+
+// Attempt records one HTTP try made for a request.
+type Attempt struct {
+	// StatusCode is 0 when the request failed without a response.
+	StatusCode int
+	Err        string
+	Duration   time.Duration
+	// Wait is the delay before the next attempt (0 for the last one).
+	Wait time.Duration
+}
+
+// parseRetryAfter accepts delta-seconds or an HTTP date.
+func parseRetryAfter(v string, now time.Time) (time.Duration, bool) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(0, t.Sub(now)), true
+	}
+	return 0, false
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
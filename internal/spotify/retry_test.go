@@ -0,0 +1,132 @@
+package spotify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestClient points a Client at srv for both the accounts and API bases.
+func newTestClient(t *testing.T, srv *httptest.Server, opt ClientOptions) *Client {
+	t.Helper()
+	m, err := NewTokenManager(Credentials{ClientID: "cid", ClientSecret: "sec", RefreshToken: "rt"}, TokenManagerOptions{HTTP: srv.Client(), AccountsBase: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	opt.HTTP = srv.Client()
+	opt.APIBase = srv.URL
+	return NewClient(m, opt)
+}
+
+func tokenHandler(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprint(w, `{"access_token":"at","token_type":"Bearer","expires_in":3600}`)
+}
+
+func fastRetry(attempts int) RetryPolicy {
+	return RetryPolicy{MaxAttempts: attempts, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond, Rand: func() float64 { return 0 }}
+}
+
+func TestRetryHonorsEvery429(t *testing.T) {
+	var n atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/token" {
+			tokenHandler(w)
+			return
+		}
+		switch n.Add(1) {
+		case 1, 2:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(429)
+		case 3:
+			w.WriteHeader(503)
+		default:
+			fmt.Fprint(w, `{"id":"u1"}`)
+		}
+	}))
+	defer srv.Close()
+
+	c := newTestClient(t, srv, ClientOptions{Retry: fastRetry(5)})
+	u, err := c.Me(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.ID != "u1" || n.Load() != 4 {
+		t.Fatalf("user=%+v calls=%d", u, n.Load())
+	}
+}
+
+func TestRetryGivesUpWithHistory(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/token" {
+			tokenHandler(w)
+			return
+		}
+		w.WriteHeader(502)
+	}))
+	defer srv.Close()
+
+	c := newTestClient(t, srv, ClientOptions{Retry: fastRetry(3)})
+	_, err := c.Me(context.Background())
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("err=%v", err)
+	}
+	if len(apiErr.Attempts) != 3 || apiErr.Attempts[0].StatusCode != 502 || apiErr.Attempts[2].Wait != 0 {
+		t.Fatalf("attempts=%+v", apiErr.Attempts)
+	}
+}
+
+func TestRetryLongRetryAfterFailsFast(t *testing.T) {
+	var n atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/token" {
+			tokenHandler(w)
+			return
+		}
+		n.Add(1)
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(429)
+	}))
+	defer srv.Close()
+
+	c := newTestClient(t, srv, ClientOptions{Retry: fastRetry(5)})
+	_, err := c.Me(context.Background())
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 429 || n.Load() != 1 {
+		t.Fatalf("err=%v calls=%d", err, n.Load())
+	}
+}
+
+func TestRetryNetworkErrorsOnlyWhenIdempotent(t *testing.T) {
+	var n atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/token" {
+			tokenHandler(w)
+			return
+		}
+		if n.Add(1) == 1 {
+			// Drop the connection without a response.
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			_ = conn.Close()
+			return
+		}
+		w.WriteHeader(204)
+	}))
+	defer srv.Close()
+
+	c := newTestClient(t, srv, ClientOptions{Retry: fastRetry(3)})
+	if err := c.Pause(context.Background(), nil); err != nil {
+		t.Fatalf("PUT should retry: %v", err)
+	}
+
+	n.Store(0)
+	if err := c.Next(context.Background(), nil); err == nil {
+		t.Fatal("POST should not retry a dropped connection")
+	}
+}
@@ -0,0 +1,68 @@
+package spotify
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RefreshTokenWriter persists a refresh token that Spotify rotated during a
+// refresh. Once rotated, the old token may stop working, so a token that
+// only lives in memory is lost with the process.
+//
+// With a token cache, rotated tokens are also kept there (and preferred over
+// the configured one on the next start); a writer puts them back wherever the
+// credentials come from.
+type RefreshTokenWriter interface {
+	WriteRefreshToken(ctx context.Context, token string) error
+}
+
+// RefreshTokenFile writes the token to Path (e.g. SPOTIFY_REFRESH_TOKEN_FILE),
+// atomically and with 0600 permissions.
+type RefreshTokenFile struct {
+	Path string
+}
+
+func (f RefreshTokenFile) WriteRefreshToken(_ context.Context, token string) error {
+	return WriteFileAtomic(expandHome(f.Path), []byte(token+"\n"))
+}
+
+// RefreshTokenCommand runs Command with sh -c and the token on stdin, for
+// secret stores spotctl doesn't know about.
+type RefreshTokenCommand struct {
+	Command string
+}
+
+func (h RefreshTokenCommand) WriteRefreshToken(ctx context.Context, token string) error {
+	if _, err := runSecretCommand(ctx, strings.NewReader(token+"\n"), "sh", "-c", h.Command); err != nil {
+		return fmt.Errorf("refresh token hook: %w", err)
+	}
+	return nil
+}
+
+// WriteFileAtomic replaces path via a temp file (0600, from CreateTemp) in
+// the same directory so readers never see a partial write.
+func WriteFileAtomic(path string, b []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	_, err = f.Write(b)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+	}
+	return err
+}
@@ -0,0 +1,199 @@
+package spotify
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// SecretSink stores the secrets `spotctl auth bootstrap` collects, so they go
+// straight from the login flow into wherever the deployment reads them.
+// Names are the credential keys: client_id, client_secret, refresh_token.
+type SecretSink interface {
+	// Check fails early, before the browser flow, when the sink can't work
+	// (missing tool or target).
+	Check() error
+	WriteSecret(ctx context.Context, name, value string) error
+	String() string
+}
+
+// ParseSecretSink maps --sink and --dest to a sink:
+//
+//	agenix    dest is the secrets dir (with secrets.nix); writes spotify-client-id.age, …
+//	sops      dest is an existing sops file; sets spotify_client_id, … (sops >= 3.9)
+//	file      dest is a directory; writes client_id, … (0600)
+//	env-file  dest is a KEY=value file; sets SPOTIFY_CLIENT_ID, … (0600)
+//	exec      dest is a command run with sh -c per secret: the value on stdin,
+//	          the name in $SPOTCTL_SECRET_NAME
+func ParseSecretSink(kind, dest string) (SecretSink, error) {
+	if strings.TrimSpace(dest) == "" {
+		return nil, fmt.Errorf("sink %s needs a destination", kind)
+	}
+	switch kind {
+	case "agenix":
+		return AgenixSink{Dir: expandHome(dest)}, nil
+	case "sops":
+		return SopsSink{File: expandHome(dest)}, nil
+	case "file":
+		return FileSink{Dir: expandHome(dest)}, nil
+	case "env-file":
+		return EnvFileSink{Path: expandHome(dest)}, nil
+	case "exec":
+		return ExecSink{Command: dest}, nil
+	default:
+		return nil, fmt.Errorf("unknown secret sink %q (want agenix, sops, file, env-file or exec)", kind)
+	}
+}
+
+// AgenixSink writes <Dir>/spotify-<name>.age (dashes for underscores) with
+// `agenix -e`. The files must be declared in Dir's secrets.nix.
+type AgenixSink struct {
+	Dir string
+}
+
+func (s AgenixSink) Check() error {
+	if _, err := exec.LookPath("agenix"); err != nil {
+		return errors.New("agenix not found on PATH")
+	}
+	if _, err := os.Stat(filepath.Join(s.Dir, "secrets.nix")); err != nil {
+		return fmt.Errorf("secrets.nix not found in %s", s.Dir)
+	}
+	return nil
+}
+
+func (s AgenixSink) WriteSecret(ctx context.Context, name, value string) error {
+	file := "spotify-" + strings.ReplaceAll(name, "_", "-") + ".age"
+	cmd := exec.CommandContext(ctx, "agenix", "-e", file)
+	cmd.Dir = s.Dir
+	// agenix calls $EDITOR with the target file path appended.
+	// Use $0 (not $1) because bash -c sets $0 to the first arg after the script.
+	cmd.Env = append(os.Environ(), "EDITOR=bash -c 'cat > \"$0\"'")
+	cmd.Stdin = strings.NewReader(value + "\n")
+	if _, err := runCaptured(cmd); err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+	return nil
+}
+
+func (s AgenixSink) String() string { return "agenix secrets in " + s.Dir }
+
+// SopsSink sets spotify_<name> in an existing sops-encrypted File with
+// `sops set`, passing the value on stdin rather than in argv.
+type SopsSink struct {
+	File string
+}
+
+func (s SopsSink) Check() error {
+	if _, err := exec.LookPath("sops"); err != nil {
+		return errors.New("sops not found on PATH")
+	}
+	if _, err := os.Stat(s.File); err != nil {
+		return fmt.Errorf("sops file: %w (create it with sops first)", err)
+	}
+	return nil
+}
+
+func (s SopsSink) WriteSecret(ctx context.Context, name, value string) error {
+	v, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	key := fmt.Sprintf(`["spotify_%s"]`, name)
+	cmd := exec.CommandContext(ctx, "sops", "set", "--value-stdin", s.File, key)
+	cmd.Stdin = bytes.NewReader(v)
+	if _, err := runCaptured(cmd); err != nil {
+		return fmt.Errorf("%s %s: %w", s.File, key, err)
+	}
+	return nil
+}
+
+func (s SopsSink) String() string { return "sops file " + s.File }
+
+// FileSink writes one 0600 file per secret, <Dir>/<name>, for the
+// SPOTIFY_*=<path> env vars.
+type FileSink struct {
+	Dir string
+}
+
+func (s FileSink) Check() error {
+	return os.MkdirAll(s.Dir, 0o700)
+}
+
+func (s FileSink) WriteSecret(_ context.Context, name, value string) error {
+	return WriteFileAtomic(filepath.Join(s.Dir, name), []byte(value+"\n"))
+}
+
+func (s FileSink) String() string { return "files in " + s.Dir }
+
+// EnvFileSink sets SPOTIFY_<NAME>=value lines in Path (a systemd
+// EnvironmentFile or .env), keeping its other lines.
+type EnvFileSink struct {
+	Path string
+}
+
+func (s EnvFileSink) Check() error {
+	return os.MkdirAll(filepath.Dir(s.Path), 0o700)
+}
+
+func (s EnvFileSink) WriteSecret(_ context.Context, name, value string) error {
+	if strings.ContainsAny(value, "\n\r") {
+		return fmt.Errorf("%s contains a newline", name)
+	}
+	key := "SPOTIFY_" + strings.ToUpper(name)
+	old, err := os.ReadFile(s.Path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	var out bytes.Buffer
+	found := false
+	sc := bufio.NewScanner(bytes.NewReader(old))
+	for sc.Scan() {
+		line := sc.Text()
+		k, _, ok := strings.Cut(strings.TrimPrefix(strings.TrimSpace(line), "export "), "=")
+		if ok && strings.TrimSpace(k) == key {
+			if found {
+				continue
+			}
+			line, found = key+"="+value, true
+		}
+		out.WriteString(line + "\n")
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	if !found {
+		out.WriteString(key + "=" + value + "\n")
+	}
+	return WriteFileAtomic(s.Path, out.Bytes())
+}
+
+func (s EnvFileSink) String() string { return "env file " + s.Path }
+
+// ExecSink runs Command with sh -c once per secret, the value on stdin and
+// the name in $SPOTCTL_SECRET_NAME, for stores spotctl doesn't know about.
+type ExecSink struct {
+	Command string
+}
+
+func (s ExecSink) Check() error {
+	return nil
+}
+
+func (s ExecSink) WriteSecret(ctx context.Context, name, value string) error {
+	cmd := exec.CommandContext(ctx, "sh", "-c", s.Command)
+	cmd.Env = append(os.Environ(), "SPOTCTL_SECRET_NAME="+name)
+	cmd.Stdin = strings.NewReader(value + "\n")
+	if _, err := runCaptured(cmd); err != nil {
+		return fmt.Errorf("sink command (%s): %w", name, err)
+	}
+	return nil
+}
+
+func (s ExecSink) String() string { return "command " + s.Command }
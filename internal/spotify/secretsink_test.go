@@ -0,0 +1,79 @@
+package spotify
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEnvFileSink(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "spotify.env")
+	if err := os.WriteFile(p, []byte("# keep me\nOTHER=1\nexport SPOTIFY_CLIENT_ID=old\nSPOTIFY_CLIENT_ID=dup\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s := EnvFileSink{Path: p}
+	ctx := context.Background()
+	if err := s.WriteSecret(ctx, "client_id", "cid"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.WriteSecret(ctx, "refresh_token", "rt"); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := os.ReadFile(p)
+	want := "# keep me\nOTHER=1\nSPOTIFY_CLIENT_ID=cid\nSPOTIFY_REFRESH_TOKEN=rt\n"
+	if string(b) != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", b, want)
+	}
+	if fi, _ := os.Stat(p); fi.Mode().Perm() != 0o600 {
+		t.Fatalf("perm=%v", fi.Mode().Perm())
+	}
+	if err := s.WriteSecret(ctx, "client_secret", "a\nb"); err == nil {
+		t.Fatal("want error for a multi-line value")
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "spotify")
+	s := FileSink{Dir: dir}
+	if err := s.Check(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.WriteSecret(context.Background(), "client_secret", "sec"); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "client_secret"))
+	if err != nil || string(b) != "sec\n" {
+		t.Fatalf("got %q err=%v", b, err)
+	}
+}
+
+func TestExecSink(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out")
+	ctx := context.Background()
+	s := ExecSink{Command: `printf '%s=%s' "$SPOTCTL_SECRET_NAME" "$(cat)" >> ` + out}
+	if err := s.WriteSecret(ctx, "refresh_token", "rt"); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(out); string(b) != "refresh_token=rt" {
+		t.Fatalf("got %q", b)
+	}
+
+	// The sink's own explanation reaches the user.
+	err := ExecSink{Command: "echo 'vault sealed' >&2; exit 3"}.WriteSecret(ctx, "client_id", "cid")
+	if err == nil || !strings.Contains(err.Error(), "vault sealed") {
+		t.Fatalf("err=%v", err)
+	}
+}
+
+func TestParseSecretSink(t *testing.T) {
+	if s, err := ParseSecretSink("env-file", "/run/spotify.env"); err != nil || s != (EnvFileSink{Path: "/run/spotify.env"}) {
+		t.Fatalf("sink=%#v err=%v", s, err)
+	}
+	for _, bad := range [][2]string{{"vault", "x"}, {"file", ""}} {
+		if _, err := ParseSecretSink(bad[0], bad[1]); err == nil {
+			t.Errorf("%v: want error", bad)
+		}
+	}
+}
@@ -5,10 +5,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"os"
-	"path/filepath"
 	"strings"
 	"sync"
 	"time"
@@ -24,8 +24,19 @@ type Token struct {
 type TokenManagerOptions struct {
 	HTTP         *http.Client
 	AccountsBase string
-	CachePath    string // optional; empty => no cache
+	CachePath    string // optional; empty => no cache. Shared safely between processes.
 	Now          func() time.Time
+	Logger       *slog.Logger // optional; traces refreshes (never token values)
+
+	// CacheKey encrypts the cache at rest (AES-256-GCM, 32 bytes, see
+	// CacheKeyFromFile); optional. A cache it can't decrypt is refreshed and
+	// overwritten.
+	CacheKey []byte
+	// RefreshTokenWriter persists rotated refresh tokens; optional.
+	RefreshTokenWriter RefreshTokenWriter
+	// Logf reports problems that don't fail the request, such as a rotated
+	// refresh token that couldn't be saved; optional.
+	Logf func(format string, args ...any)
 }
 
 type TokenManager struct {
@@ -33,8 +44,16 @@ type TokenManager struct {
 	hc    *http.Client
 	base  string
 	now   func() time.Time
+	log   *slog.Logger
 
 	cachePath string
+	cacheKey  []byte
+	writer    RefreshTokenWriter
+	logf      func(format string, args ...any)
+	// origin is the configured credentials' Fingerprint. The cache is only
+	// used (access token and rotated refresh token alike) while it matches.
+	origin string
+	userID string // Spotify user id, remembered in the cache (see SetUserID)
 
 	mu   sync.Mutex
 	cur  Token
@@ -42,6 +61,9 @@ type TokenManager struct {
 }
 
 func NewTokenManager(creds Credentials, opt TokenManagerOptions) (*TokenManager, error) {
+	if err := creds.Check(); err != nil {
+		return nil, err
+	}
 	base := strings.TrimRight(opt.AccountsBase, "/")
 	if base == "" {
 		base = "https://accounts.spotify.com"
@@ -50,8 +72,13 @@ func NewTokenManager(creds Credentials, opt TokenManagerOptions) (*TokenManager,
 		creds:     creds,
 		hc:        opt.HTTP,
 		base:      base,
-		cachePath: opt.CachePath,
+		cachePath: expandHome(opt.CachePath),
+		cacheKey:  opt.CacheKey,
 		now:       opt.Now,
+		log:       opt.Logger,
+		writer:    opt.RefreshTokenWriter,
+		logf:      opt.Logf,
+		origin:    creds.Fingerprint(),
 	}
 	if m.hc == nil {
 		m.hc = http.DefaultClient
@@ -59,8 +86,16 @@ func NewTokenManager(creds Credentials, opt TokenManagerOptions) (*TokenManager,
 	if m.now == nil {
 		m.now = time.Now
 	}
+	if m.logf == nil {
+		m.logf = func(string, ...any) {}
+	}
+	if len(m.cacheKey) != 0 && len(m.cacheKey) != 32 {
+		return nil, errors.New("token cache key must be 32 bytes")
+	}
 	if m.cachePath != "" {
-		_ = m.loadCache()
+		if err := m.loadCache(); errors.Is(err, errCacheDecrypt) {
+			m.logf("%s: %v; refreshing", m.cachePath, err)
+		}
 	}
 	return m, nil
 }
@@ -69,14 +104,55 @@ func (m *TokenManager) AccessToken(ctx context.Context) (string, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if m.have && m.cur.AccessToken != "" {
-		// Refresh a bit early.
-		if m.now().Add(30 * time.Second).Before(m.cur.ExpiresAt) {
+	if m.fresh() {
+		return m.cur.AccessToken, nil
+	}
+	reason := "expired"
+	if !m.have {
+		reason = "no cached token"
+	}
+	return m.renew(ctx, reason, false)
+}
+
+func (m *TokenManager) ForceRefresh(ctx context.Context) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.renew(ctx, "forced", true)
+}
+
+// fresh reports whether the current token is still good (refreshing a bit
+// early). Callers hold m.mu.
+func (m *TokenManager) fresh() bool {
+	return m.have && m.cur.AccessToken != "" && m.now().Add(30*time.Second).Before(m.cur.ExpiresAt)
+}
+
+// renew refreshes the token. With a cache, it does so holding an advisory
+// lock on <cache>.lock and re-reads the cache first, so concurrent processes
+// share one refresh: those that waited pick up the token the first one
+// saved. force skips a cached token only if it's the one we already had.
+// Callers hold m.mu.
+func (m *TokenManager) renew(ctx context.Context, reason string, force bool) (string, error) {
+	if m.cachePath != "" {
+		had := m.cur.AccessToken
+		lock, err := lockFile(ctx, m.cachePath+".lock")
+		switch {
+		case err == nil:
+			defer lock.Unlock()
+		case ctx.Err() != nil:
+			return "", err
+		case m.log != nil:
+			// Not fatal: worst case, another process refreshes too.
+			m.log.DebugContext(ctx, "token cache lock failed", "error", err.Error())
+		}
+		if m.loadCache() == nil && m.fresh() && !(force && m.cur.AccessToken == had) {
+			if m.log != nil {
+				m.log.DebugContext(ctx, "token from cache", "reason", reason, "expires_at", m.cur.ExpiresAt)
+			}
 			return m.cur.AccessToken, nil
 		}
 	}
 
-	tok, err := m.refresh(ctx)
+	tok, err := m.refreshTraced(ctx, reason)
 	if err != nil {
 		return "", err
 	}
@@ -88,72 +164,209 @@ func (m *TokenManager) AccessToken(ctx context.Context) (string, error) {
 	return tok.AccessToken, nil
 }
 
-func (m *TokenManager) ForceRefresh(ctx context.Context) (string, error) {
+// Token returns the current token (refreshing it if needed), e.g. to inspect
+// its scopes or expiry.
+func (m *TokenManager) Token(ctx context.Context) (Token, error) {
+	if _, err := m.AccessToken(ctx); err != nil {
+		return Token{}, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.cur, nil
+}
+
+// UserID is the Spotify user id set with SetUserID (possibly by an earlier
+// process, via the cache), or "".
+func (m *TokenManager) UserID() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.userID
+}
+
+// SetUserID remembers the account's user id in the token cache, so it needs
+// looking up only once. Like renew, it updates the cache under its lock and
+// on top of what is there, so a newer token from another process survives.
+func (m *TokenManager) SetUserID(ctx context.Context, id string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	tok, err := m.refresh(ctx)
+	m.userID = id
+	if m.cachePath == "" {
+		return
+	}
+	lock, err := lockFile(ctx, m.cachePath+".lock")
 	if err != nil {
-		return "", err
+		return
 	}
-	m.cur = tok
-	m.have = true
-	if m.cachePath != "" {
-		_ = m.saveCache(tok)
+	defer lock.Unlock()
+	if m.loadCache() == nil {
+		m.userID = id
+		_ = m.saveCache(m.cur)
 	}
-	return tok.AccessToken, nil
+}
+
+// ClientID is the app's client id.
+func (m *TokenManager) ClientID() string {
+	return m.creds.ClientID
+}
+
+// CachePath is the token cache file, or "" when caching is off.
+func (m *TokenManager) CachePath() string {
+	return m.cachePath
+}
+
+// CacheEncrypted reports whether the token cache is encrypted at rest.
+func (m *TokenManager) CacheEncrypted() bool {
+	return m.cachePath != "" && m.cacheKey != nil
+}
+
+// RequireScopes fails with a *ScopeError (matching ErrScopeMissing) when the
+// token wasn't granted all of scopes, so callers can fail before sending a
+// request that would only come back 403.
+func (m *TokenManager) RequireScopes(ctx context.Context, scopes ...string) error {
+	if len(scopes) == 0 {
+		return nil
+	}
+	tok, err := m.Token(ctx)
+	if err != nil {
+		return err
+	}
+	if missing := tok.MissingScopes(scopes...); len(missing) > 0 {
+		return &ScopeError{Missing: missing}
+	}
+	return nil
+}
+
+// Scopes are the granted scopes.
+func (t Token) Scopes() []string {
+	return strings.Fields(t.Scope)
+}
+
+// MissingScopes returns the scopes in want that the token wasn't granted.
+// A token without scope information (e.g. from an old cache) is assumed to
+// have them all; the API still has the last word.
+func (t Token) MissingScopes(want ...string) []string {
+	if strings.TrimSpace(t.Scope) == "" {
+		return nil
+	}
+	have := map[string]bool{}
+	for _, s := range t.Scopes() {
+		have[s] = true
+	}
+	var missing []string
+	for _, s := range want {
+		if !have[s] {
+			missing = append(missing, s)
+		}
+	}
+	return missing
 }
 
 type tokenResponse struct {
-	AccessToken string `json:"access_token"`
-	TokenType   string `json:"token_type"`
-	Scope       string `json:"scope,omitempty"`
-	ExpiresIn   int    `json:"expires_in"`
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	Scope        string `json:"scope,omitempty"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+// tokenCache is the cache file: the access token, the credentials it was
+// issued for and, once Spotify has rotated it, the current refresh token.
+type tokenCache struct {
+	Token
+	Origin       string `json:"origin"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	UserID       string `json:"user_id,omitempty"`
+}
+
+func (m *TokenManager) refreshTraced(ctx context.Context, reason string) (Token, error) {
+	start := time.Now()
+	tok, rotated, err := m.refresh(ctx)
+	if err == nil && rotated != "" && rotated != m.creds.RefreshToken {
+		m.rotate(ctx, rotated)
+	}
+	if m.log != nil {
+		args := []any{"reason", reason, "duration_ms", time.Since(start).Milliseconds()}
+		if err != nil {
+			args = append(args, "error", err.Error())
+		} else {
+			args = append(args, "expires_at", tok.ExpiresAt, "scope", tok.Scope)
+		}
+		m.log.DebugContext(ctx, "token refresh", args...)
+	}
+	return tok, err
 }
 
-func (m *TokenManager) refresh(ctx context.Context) (Token, error) {
+// rotate switches to a refresh token Spotify issued in place of ours and
+// saves it. Callers hold m.mu.
+func (m *TokenManager) rotate(ctx context.Context, rt string) {
+	m.creds.RefreshToken = rt
+	if m.log != nil {
+		m.log.DebugContext(ctx, "refresh token rotated", "persisted_to_cache", m.cachePath != "")
+	}
+	if m.writer == nil {
+		if m.cachePath == "" {
+			m.logf("Spotify rotated the refresh token but there is nowhere to save it; set SPOTIFY_REFRESH_TOKEN_FILE or a token cache")
+		}
+		return
+	}
+	if err := m.writer.WriteRefreshToken(ctx, rt); err != nil {
+		m.logf("Spotify rotated the refresh token but saving it failed: %v", err)
+	}
+}
+
+// refresh returns the new access token and, if Spotify sent one, a new
+// refresh token.
+func (m *TokenManager) refresh(ctx context.Context) (Token, string, error) {
 	form := url.Values{}
 	form.Set("grant_type", "refresh_token")
 	form.Set("refresh_token", m.creds.RefreshToken)
+	if m.creds.Public() {
+		form.Set("client_id", m.creds.ClientID)
+	}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.base+"/api/token", strings.NewReader(form.Encode()))
 	if err != nil {
-		return Token{}, err
+		return Token{}, "", err
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.SetBasicAuth(m.creds.ClientID, m.creds.ClientSecret)
+	if !m.creds.Public() {
+		req.SetBasicAuth(m.creds.ClientID, m.creds.ClientSecret)
+	}
 
 	resp, err := m.hc.Do(req)
 	if err != nil {
-		return Token{}, err
+		return Token{}, "", err
 	}
 	defer resp.Body.Close()
 
 	b, _ := ioReadAllLimit(resp.Body, 1<<20)
 	if resp.StatusCode < 200 || resp.StatusCode > 299 {
-		if resp.StatusCode == 400 {
-			// Spotify often returns JSON {error, error_description}.
-			var e struct {
-				Error            string `json:"error"`
-				ErrorDescription string `json:"error_description"`
-			}
-			_ = json.Unmarshal(b, &e)
-			if e.ErrorDescription != "" {
-				return Token{}, fmt.Errorf("spotify token refresh failed: %s", e.ErrorDescription)
-			}
+		// Spotify often returns JSON {error, error_description}.
+		var e struct {
+			Error            string `json:"error"`
+			ErrorDescription string `json:"error_description"`
 		}
-		return Token{}, fmt.Errorf("spotify token refresh failed: %s: %s", resp.Status, strings.TrimSpace(string(b)))
+		_ = json.Unmarshal(b, &e)
+		te := &tokenError{
+			msg:          fmt.Sprintf("spotify token refresh failed: %s: %s", resp.Status, strings.TrimSpace(string(b))),
+			unauthorized: resp.StatusCode == 401 || e.Error == "invalid_grant" || e.Error == "invalid_client",
+		}
+		if resp.StatusCode == 400 && e.ErrorDescription != "" {
+			te.msg = "spotify token refresh failed: " + e.ErrorDescription
+		}
+		return Token{}, "", te
 	}
 
 	var tr tokenResponse
 	if err := json.Unmarshal(b, &tr); err != nil {
-		return Token{}, fmt.Errorf("decode token response: %w", err)
+		return Token{}, "", fmt.Errorf("decode token response: %w", err)
 	}
 	if tr.AccessToken == "" {
-		return Token{}, errors.New("token response missing access_token")
+		return Token{}, "", errors.New("token response missing access_token")
 	}
 
 	exp := m.now().Add(time.Duration(tr.ExpiresIn) * time.Second)
-	return Token{AccessToken: tr.AccessToken, TokenType: tr.TokenType, Scope: tr.Scope, ExpiresAt: exp}, nil
+	return Token{AccessToken: tr.AccessToken, TokenType: tr.TokenType, Scope: tr.Scope, ExpiresAt: exp}, tr.RefreshToken, nil
 }
 
 func (m *TokenManager) loadCache() error {
@@ -161,14 +374,31 @@ func (m *TokenManager) loadCache() error {
 	if err != nil {
 		return err
 	}
-	var tok Token
-	if err := json.Unmarshal(b, &tok); err != nil {
+	if m.cacheKey != nil {
+		if b, err = openCache(m.cacheKey, b); err != nil {
+			return err
+		}
+	}
+	var c tokenCache
+	if err := json.Unmarshal(b, &c); err != nil {
 		return err
 	}
-	if tok.AccessToken == "" || tok.ExpiresAt.IsZero() {
+	// The configured refresh token may also be the one this cache rotated
+	// to, once a RefreshTokenWriter has stored it.
+	rotatedTo := c.RefreshToken != "" && (Credentials{ClientID: m.creds.ClientID, RefreshToken: c.RefreshToken}).Fingerprint() == m.origin
+	if c.Origin != m.origin && !rotatedTo {
+		return errors.New("token cache is for other credentials")
+	}
+	if c.RefreshToken != "" {
+		m.creds.RefreshToken = c.RefreshToken
+	}
+	if c.UserID != "" {
+		m.userID = c.UserID
+	}
+	if c.AccessToken == "" || c.ExpiresAt.IsZero() {
 		return errors.New("invalid token cache")
 	}
-	m.cur = tok
+	m.cur = c.Token
 	m.have = true
 	return nil
 }
@@ -177,12 +407,18 @@ func (m *TokenManager) saveCache(tok Token) error {
 	if m.cachePath == "" {
 		return nil
 	}
-	if err := os.MkdirAll(filepath.Dir(m.cachePath), 0o755); err != nil {
-		return err
+	c := tokenCache{Token: tok, Origin: m.origin, UserID: m.userID}
+	if m.creds.Fingerprint() != m.origin {
+		c.RefreshToken = m.creds.RefreshToken
 	}
-	b, err := json.MarshalIndent(tok, "", "  ")
+	b, err := json.MarshalIndent(c, "", "  ")
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(m.cachePath, b, 0o600)
+	if m.cacheKey != nil {
+		if b, err = sealCache(m.cacheKey, b); err != nil {
+			return err
+		}
+	}
+	return WriteFileAtomic(m.cachePath, b)
 }
@@ -3,11 +3,15 @@ package spotify
 import (
 	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -60,3 +64,275 @@ func TestTokenManagerRefresh(t *testing.T) {
 		t.Fatalf("refresh_token=%q", vals.Get("refresh_token"))
 	}
 }
+
+func TestTokenManagerRefreshPublicClient(t *testing.T) {
+	var auth string
+	var vals url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		_ = r.ParseForm()
+		vals = r.PostForm
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token":"at","token_type":"Bearer","expires_in":3600,"refresh_token":"rt2"}`)
+	}))
+	defer srv.Close()
+
+	m, err := NewTokenManager(Credentials{ClientID: "cid", RefreshToken: "rt", PublicClient: true}, TokenManagerOptions{HTTP: srv.Client(), AccountsBase: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Token(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if auth != "" {
+		t.Fatalf("public client sent Authorization %q", auth)
+	}
+	if vals.Get("client_id") != "cid" || vals.Get("refresh_token") != "rt" {
+		t.Fatalf("form=%v", vals)
+	}
+}
+
+func TestTokenManagerMissingSecret(t *testing.T) {
+	_, err := NewTokenManager(Credentials{ClientID: "cid", RefreshToken: "rt"}, TokenManagerOptions{})
+	if !errors.Is(err, ErrMissingClientSecret) {
+		t.Fatalf("err=%v", err)
+	}
+}
+
+func TestRequireScopes(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token":"at","token_type":"Bearer","expires_in":3600,"scope":"user-read-playback-state playlist-read-private"}`)
+	}))
+	defer srv.Close()
+
+	m, err := NewTokenManager(Credentials{ClientID: "cid", ClientSecret: "sec", RefreshToken: "rt"}, TokenManagerOptions{HTTP: srv.Client(), AccountsBase: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := m.RequireScopes(ctx, "user-read-playback-state"); err != nil {
+		t.Fatal(err)
+	}
+	err = m.RequireScopes(ctx, "user-read-playback-state", "user-modify-playback-state")
+	var se *ScopeError
+	if !errors.As(err, &se) || !errors.Is(err, ErrScopeMissing) || strings.Join(se.Missing, " ") != "user-modify-playback-state" {
+		t.Fatalf("err=%v", err)
+	}
+	if got := (Token{}).MissingScopes("anything"); got != nil {
+		t.Fatalf("token without scope info: %v", got)
+	}
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	var gotRT []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		gotRT = append(gotRT, r.PostForm.Get("refresh_token"))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"at","token_type":"Bearer","expires_in":3600,"refresh_token":"rt%d"}`, len(gotRT)+1)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	rtFile := filepath.Join(dir, "refresh_token")
+	cache := filepath.Join(dir, "token.json")
+	newManager := func() *TokenManager {
+		m, err := NewTokenManager(Credentials{ClientID: "cid", ClientSecret: "sec", RefreshToken: "rt1"}, TokenManagerOptions{
+			HTTP: srv.Client(), AccountsBase: srv.URL, CachePath: cache,
+			RefreshTokenWriter: RefreshTokenFile{Path: rtFile},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+
+	ctx := context.Background()
+	if _, err := newManager().ForceRefresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(rtFile)
+	if err != nil || strings.TrimSpace(string(b)) != "rt2" {
+		t.Fatalf("rotated token file=%q err=%v", b, err)
+	}
+	if fi, err := os.Stat(rtFile); err != nil || fi.Mode().Perm() != 0o600 {
+		t.Fatalf("perm=%v err=%v", fi.Mode().Perm(), err)
+	}
+
+	// A new process with the same (now stale) configured token picks the
+	// rotated one up from the cache.
+	if _, err := newManager().ForceRefresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(gotRT, ",") != "rt1,rt2" {
+		t.Fatalf("refresh tokens sent: %v", gotRT)
+	}
+}
+
+func TestTokenCacheTiedToCredentials(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"at%d","token_type":"Bearer","expires_in":3600}`, calls)
+	}))
+	defer srv.Close()
+
+	cache := filepath.Join(t.TempDir(), "token.json")
+	token := func(creds Credentials) string {
+		m, err := NewTokenManager(creds, TokenManagerOptions{HTTP: srv.Client(), AccountsBase: srv.URL, CachePath: cache})
+		if err != nil {
+			t.Fatal(err)
+		}
+		tok, err := m.Token(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tok.AccessToken
+	}
+
+	alice := Credentials{ClientID: "cid", ClientSecret: "sec", RefreshToken: "rt-alice"}
+	if got := token(alice); got != "at1" {
+		t.Fatalf("token=%q", got)
+	}
+	if got := token(alice); got != "at1" {
+		t.Fatalf("same credentials didn't reuse the cache: %q", got)
+	}
+	// Another account or app must not get alice's access token.
+	if got := token(Credentials{ClientID: "cid", ClientSecret: "sec", RefreshToken: "rt-bob"}); got != "at2" {
+		t.Fatalf("other refresh token got %q", got)
+	}
+	if got := token(Credentials{ClientID: "cid2", ClientSecret: "sec", RefreshToken: "rt-bob"}); got != "at3" {
+		t.Fatalf("other client id got %q", got)
+	}
+}
+
+func TestTokenCacheSharedRefresh(t *testing.T) {
+	var mu sync.Mutex
+	hits := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits++
+		n := hits
+		mu.Unlock()
+		time.Sleep(50 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"at%d","token_type":"Bearer","expires_in":3600}`, n)
+	}))
+	defer srv.Close()
+
+	cache := filepath.Join(t.TempDir(), "token.json")
+	newManager := func() *TokenManager {
+		m, err := NewTokenManager(Credentials{ClientID: "cid", ClientSecret: "sec", RefreshToken: "rt"}, TokenManagerOptions{
+			HTTP: srv.Client(), AccountsBase: srv.URL, CachePath: cache,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+
+	// Separate managers stand in for separate processes: the cache lock is
+	// per open file, so they contend like processes do.
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	toks := make([]string, 8)
+	for i := range toks {
+		m := newManager()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tok, err := m.AccessToken(ctx)
+			if err != nil {
+				t.Error(err)
+			}
+			toks[i] = tok
+		}()
+	}
+	wg.Wait()
+	if hits != 1 {
+		t.Fatalf("refreshes=%d want 1", hits)
+	}
+	for _, tok := range toks {
+		if tok != "at1" {
+			t.Fatalf("tokens=%v", toks)
+		}
+	}
+
+	// After a 401, one process forces a refresh; another one rejected with
+	// the same token takes the new token instead of refreshing again.
+	a, b := newManager(), newManager()
+	if tok, err := a.ForceRefresh(ctx); err != nil || tok != "at2" {
+		t.Fatalf("tok=%q err=%v", tok, err)
+	}
+	if tok, err := b.ForceRefresh(ctx); err != nil || tok != "at2" || hits != 2 {
+		t.Fatalf("tok=%q err=%v refreshes=%d", tok, err, hits)
+	}
+}
+
+func TestEncryptedTokenCache(t *testing.T) {
+	hits := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"secret-at%d","token_type":"Bearer","expires_in":3600}`, hits)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	writeKey := func(name, content string) []byte {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		k, err := CacheKeyFromFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return k
+	}
+	key := writeKey("keys.txt", "# created: 2024-01-01\n# public key: age1xyz\nAGE-SECRET-KEY-1QQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQ\n")
+	// Only the identity line matters.
+	if k := writeKey("keys2.txt", "AGE-SECRET-KEY-1QQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQ\n"); string(k) != string(key) {
+		t.Fatal("age identity comments changed the key")
+	}
+	other := writeKey("other.key", "0123456789abcdef0123456789abcdef")
+
+	cache := filepath.Join(dir, "token.json")
+	get := func(key []byte) string {
+		m, err := NewTokenManager(Credentials{ClientID: "cid", ClientSecret: "sec", RefreshToken: "rt"}, TokenManagerOptions{
+			HTTP: srv.Client(), AccountsBase: srv.URL, CachePath: cache, CacheKey: key,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		tok, err := m.AccessToken(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tok
+	}
+
+	if tok := get(key); tok != "secret-at1" {
+		t.Fatalf("tok=%q", tok)
+	}
+	b, _ := os.ReadFile(cache)
+	if strings.Contains(string(b), "secret-at") {
+		t.Fatalf("cache is plaintext: %s", b)
+	}
+	if tok := get(key); tok != "secret-at1" || hits != 1 {
+		t.Fatalf("tok=%q refreshes=%d", tok, hits)
+	}
+	// A different key (or none) can't read it and refreshes instead.
+	if tok := get(other); tok != "secret-at2" {
+		t.Fatalf("tok=%q", tok)
+	}
+	if tok := get(nil); tok != "secret-at3" {
+		t.Fatalf("tok=%q", tok)
+	}
+	// Nor is a plaintext cache trusted once a key is set.
+	if tok := get(key); tok != "secret-at4" {
+		t.Fatalf("tok=%q", tok)
+	}
+}
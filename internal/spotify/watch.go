@@ -0,0 +1,71 @@
+package spotify
+
+import (
+	"context"
+	"time"
+)
+
+type WatchOptions struct {
+	Interval time.Duration // default 5s
+	Now      func() time.Time
+}
+
+// PlaybackUpdate is one poll of /v1/me/player.
+//
+// State is nil when nothing is playing. Err is set when the poll failed; the
+// watcher keeps polling and leaves it to the callback to decide whether the
+// error is fatal.
+type PlaybackUpdate struct {
+	At      time.Time
+	State   *PlaybackState
+	Changed bool // track, device or play/pause state differs from the previous successful poll
+	Err     error
+}
+
+// WatchPlayback polls the playback state until ctx is done or fn returns an
+// error. fn is called for every poll, not just on changes, so consumers that
+// need progress (scrobbling) and consumers that need changes (event streams)
+// can share it.
+func (c *Client) WatchPlayback(ctx context.Context, opt WatchOptions, fn func(PlaybackUpdate) error) error {
+	interval := opt.Interval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	now := opt.Now
+	if now == nil {
+		now = time.Now
+	}
+
+	var prev *PlaybackState
+	first := true
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		st, err := c.PlaybackState(ctx)
+		if err != nil && ctx.Err() != nil {
+			return ctx.Err()
+		}
+		up := PlaybackUpdate{At: now(), State: st, Err: err}
+		if err == nil {
+			up.Changed = first || playbackChanged(prev, st)
+			prev = st
+			first = false
+		}
+		if err := fn(up); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+func playbackChanged(a, b *PlaybackState) bool {
+	if a == nil || b == nil {
+		return a != b
+	}
+	return a.Item.URI != b.Item.URI || a.Device.ID != b.Device.ID || a.IsPlaying != b.IsPlaying
+}
@@ -0,0 +1,501 @@
+// Package toml reads and edits the small subset of TOML that spotctl's config
+// file uses: key = value pairs (strings, integers, booleans and one-line
+// arrays of those) at the top level and in [table] sections. Set and Delete
+// edit the text in place, so comments and layout survive.
+package toml
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Parse returns the top-level keys; each [table] is a nested map[string]any
+// (dotted headers nest further). Values are string, int64, bool or []any.
+func Parse(data []byte) (map[string]any, error) {
+	root := map[string]any{}
+	cur := root
+	for i, raw := range lines(data) {
+		l, err := parseLine(raw)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		switch {
+		case l.table != nil:
+			cur = root
+			for _, part := range l.table {
+				next, ok := cur[part]
+				if !ok {
+					m := map[string]any{}
+					cur[part] = m
+					cur = m
+					continue
+				}
+				m, ok := next.(map[string]any)
+				if !ok {
+					return nil, fmt.Errorf("line %d: %q is already a key", i+1, part)
+				}
+				cur = m
+			}
+		case l.key != "":
+			if _, dup := cur[l.key]; dup {
+				return nil, fmt.Errorf("line %d: duplicate key %q", i+1, l.key)
+			}
+			cur[l.key] = l.value
+		}
+	}
+	return root, nil
+}
+
+// Decode parses data into the struct v points to. Fields are matched by their
+// `toml:"name"` tag and may be string, bool, any int kind, []string or
+// map[string]string (a table). Unknown keys are an error, so typos surface.
+func Decode(data []byte, v any) error {
+	m, err := Parse(data)
+	if err != nil {
+		return err
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("toml: Decode needs a pointer to a struct")
+	}
+	return decodeStruct(m, rv.Elem())
+}
+
+func decodeStruct(m map[string]any, sv reflect.Value) error {
+	fields := map[string]reflect.Value{}
+	st := sv.Type()
+	for i := 0; i < st.NumField(); i++ {
+		if name, _, _ := strings.Cut(st.Field(i).Tag.Get("toml"), ","); name != "" && name != "-" {
+			fields[name] = sv.Field(i)
+		}
+	}
+	for k, val := range m {
+		f, ok := fields[k]
+		if !ok {
+			return fmt.Errorf("unknown key %q", k)
+		}
+		if err := assign(f, val); err != nil {
+			return fmt.Errorf("%s: %w", k, err)
+		}
+	}
+	return nil
+}
+
+func assign(f reflect.Value, val any) error {
+	switch f.Kind() {
+	case reflect.String:
+		s, ok := val.(string)
+		if !ok {
+			return fmt.Errorf("want a string, got %s", typeName(val))
+		}
+		f.SetString(s)
+	case reflect.Bool:
+		b, ok := val.(bool)
+		if !ok {
+			return fmt.Errorf("want true or false, got %s", typeName(val))
+		}
+		f.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := val.(int64)
+		if !ok {
+			return fmt.Errorf("want an integer, got %s", typeName(val))
+		}
+		if f.OverflowInt(n) {
+			return fmt.Errorf("%d is out of range", n)
+		}
+		f.SetInt(n)
+	case reflect.Slice:
+		arr, ok := val.([]any)
+		if !ok || f.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("want an array of strings, got %s", typeName(val))
+		}
+		out := reflect.MakeSlice(f.Type(), len(arr), len(arr))
+		for i, e := range arr {
+			s, ok := e.(string)
+			if !ok {
+				return fmt.Errorf("want an array of strings, got %s in it", typeName(e))
+			}
+			out.Index(i).SetString(s)
+		}
+		f.Set(out)
+	case reflect.Map:
+		tbl, ok := val.(map[string]any)
+		if !ok || f.Type().Key().Kind() != reflect.String || f.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("want a table of strings, got %s", typeName(val))
+		}
+		out := reflect.MakeMapWithSize(f.Type(), len(tbl))
+		for k, e := range tbl {
+			s, ok := e.(string)
+			if !ok {
+				return fmt.Errorf("%s: want a string, got %s", k, typeName(e))
+			}
+			out.SetMapIndex(reflect.ValueOf(k), reflect.ValueOf(s))
+		}
+		f.Set(out)
+	default:
+		return fmt.Errorf("unsupported field type %s", f.Type())
+	}
+	return nil
+}
+
+func typeName(v any) string {
+	switch v.(type) {
+	case string:
+		return "a string"
+	case int64:
+		return "an integer"
+	case bool:
+		return "a boolean"
+	case []any:
+		return "an array"
+	case map[string]any:
+		return "a table"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// Format encodes a string, bool, integer or []string as a TOML value.
+func Format(v any) (string, error) {
+	switch x := v.(type) {
+	case string:
+		return quote(x), nil
+	case bool:
+		return strconv.FormatBool(x), nil
+	case int:
+		return strconv.Itoa(x), nil
+	case int64:
+		return strconv.FormatInt(x, 10), nil
+	case []string:
+		parts := make([]string, len(x))
+		for i, s := range x {
+			parts[i] = quote(s)
+		}
+		return "[" + strings.Join(parts, ", ") + "]", nil
+	}
+	return "", fmt.Errorf("toml: can't format %T", v)
+}
+
+// Set makes key = value in table ("" for the top level), replacing an
+// existing line or adding one (and the table header if needed).
+func Set(data []byte, table, key string, value any) ([]byte, error) {
+	enc, err := Format(value)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := Parse(data); err != nil {
+		return nil, err
+	}
+	kv := formatKey(key) + " = " + enc
+	ls := lines(data)
+	start, end, found := tableSpan(ls, table)
+	if !found {
+		if len(ls) > 0 && strings.TrimSpace(ls[len(ls)-1]) != "" {
+			ls = append(ls, "")
+		}
+		return join(append(ls, "["+table+"]", kv)), nil
+	}
+	for i := start; i < end; i++ {
+		if l, _ := parseLine(ls[i]); l.key == key {
+			ls[i] = kv
+			return join(ls), nil
+		}
+	}
+	// After the table's last non-blank line.
+	at := end
+	for at > start && strings.TrimSpace(ls[at-1]) == "" {
+		at--
+	}
+	return join(insert(ls, at, kv)), nil
+}
+
+// Delete removes key from table; it is not an error if it isn't there.
+func Delete(data []byte, table, key string) ([]byte, error) {
+	if _, err := Parse(data); err != nil {
+		return nil, err
+	}
+	ls := lines(data)
+	start, end, _ := tableSpan(ls, table)
+	for i := start; i < end; i++ {
+		if l, _ := parseLine(ls[i]); l.key == key {
+			return join(append(ls[:i], ls[i+1:]...)), nil
+		}
+	}
+	return data, nil
+}
+
+// tableSpan returns the line range holding table's keys; found is false for
+// a missing table. The top level always exists and ends at the first header.
+func tableSpan(ls []string, table string) (start, end int, found bool) {
+	found = table == ""
+	for i, raw := range ls {
+		l, _ := parseLine(raw)
+		if l.table == nil {
+			continue
+		}
+		if found {
+			return start, i, true
+		}
+		if strings.Join(l.table, ".") == table {
+			found, start = true, i+1
+		}
+	}
+	if !found {
+		return 0, 0, false
+	}
+	return start, len(ls), true
+}
+
+func lines(data []byte) []string {
+	s := strings.TrimSuffix(string(data), "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+}
+
+func join(ls []string) []byte {
+	if len(ls) == 0 {
+		return nil
+	}
+	return []byte(strings.Join(ls, "\n") + "\n")
+}
+
+func insert(ls []string, at int, line string) []string {
+	ls = append(ls, "")
+	copy(ls[at+1:], ls[at:])
+	ls[at] = line
+	return ls
+}
+
+type line struct {
+	table []string // set for a [header]
+	key   string   // set for key = value
+	value any
+}
+
+func parseLine(raw string) (line, error) {
+	s := strings.TrimSpace(raw)
+	if s == "" || s[0] == '#' {
+		return line{}, nil
+	}
+	if s[0] == '[' {
+		if strings.HasPrefix(s, "[[") {
+			return line{}, errors.New("arrays of tables are not supported")
+		}
+		end := strings.IndexByte(s, ']')
+		if end < 0 {
+			return line{}, errors.New("unterminated table header")
+		}
+		if rest := strings.TrimSpace(s[end+1:]); rest != "" && rest[0] != '#' {
+			return line{}, fmt.Errorf("unexpected %q after table header", rest)
+		}
+		var parts []string
+		for _, p := range strings.Split(s[1:end], ".") {
+			p = strings.TrimSpace(p)
+			if !bareKey(p) {
+				return line{}, fmt.Errorf("bad table name %q", s[1:end])
+			}
+			parts = append(parts, p)
+		}
+		return line{table: parts}, nil
+	}
+
+	key, rest, err := parseKey(s)
+	if err != nil {
+		return line{}, err
+	}
+	rest = strings.TrimSpace(rest)
+	if !strings.HasPrefix(rest, "=") {
+		return line{}, fmt.Errorf("expected = after key %q", key)
+	}
+	v, rest, err := parseValue(strings.TrimSpace(rest[1:]))
+	if err != nil {
+		return line{}, fmt.Errorf("%s: %w", key, err)
+	}
+	if rest = strings.TrimSpace(rest); rest != "" && rest[0] != '#' {
+		return line{}, fmt.Errorf("%s: unexpected %q after value", key, rest)
+	}
+	return line{key: key, value: v}, nil
+}
+
+func parseKey(s string) (key, rest string, err error) {
+	if s[0] == '"' || s[0] == '\'' {
+		v, rest, err := parseValue(s)
+		if err != nil {
+			return "", "", err
+		}
+		return v.(string), rest, nil
+	}
+	i := 0
+	for i < len(s) && isBareKeyByte(s[i]) {
+		i++
+	}
+	if i == 0 {
+		return "", "", fmt.Errorf("bad key in %q", s)
+	}
+	if i < len(s) && s[i] == '.' {
+		return "", "", errors.New("dotted keys are not supported; use a [table]")
+	}
+	return s[:i], s[i:], nil
+}
+
+func parseValue(s string) (any, string, error) {
+	if s == "" {
+		return nil, "", errors.New("missing value")
+	}
+	switch c := s[0]; {
+	case c == '"':
+		if strings.HasPrefix(s, `"""`) {
+			return nil, "", errors.New("multi-line strings are not supported")
+		}
+		for i := 1; i < len(s); i++ {
+			switch s[i] {
+			case '\\':
+				i++
+			case '"':
+				v, err := unquote(s[1:i])
+				return v, s[i+1:], err
+			}
+		}
+		return nil, "", errors.New("unterminated string")
+	case c == '\'':
+		end := strings.IndexByte(s[1:], '\'')
+		if end < 0 {
+			return nil, "", errors.New("unterminated string")
+		}
+		return s[1 : end+1], s[end+2:], nil
+	case c == '[':
+		var arr []any
+		rest := strings.TrimSpace(s[1:])
+		for {
+			if strings.HasPrefix(rest, "]") {
+				return arr, rest[1:], nil
+			}
+			v, r, err := parseValue(rest)
+			if err != nil {
+				return nil, "", err
+			}
+			arr = append(arr, v)
+			rest = strings.TrimSpace(r)
+			if strings.HasPrefix(rest, ",") {
+				rest = strings.TrimSpace(rest[1:])
+			} else if !strings.HasPrefix(rest, "]") {
+				return nil, "", errors.New("expected , or ] in array (arrays must fit on one line)")
+			}
+		}
+	}
+	end := strings.IndexAny(s, " \t,]#")
+	if end < 0 {
+		end = len(s)
+	}
+	word, rest := s[:end], s[end:]
+	switch word {
+	case "true":
+		return true, rest, nil
+	case "false":
+		return false, rest, nil
+	}
+	n, err := strconv.ParseInt(strings.ReplaceAll(word, "_", ""), 10, 64)
+	if err != nil {
+		return nil, "", fmt.Errorf("unsupported value %q (strings need quotes)", word)
+	}
+	return n, rest, nil
+}
+
+// unquote handles TOML basic-string escapes.
+func unquote(s string) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		if i >= len(s) {
+			return "", errors.New("bad escape")
+		}
+		switch s[i] {
+		case 'b':
+			b.WriteByte('\b')
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'f':
+			b.WriteByte('\f')
+		case 'r':
+			b.WriteByte('\r')
+		case '"', '\\':
+			b.WriteByte(s[i])
+		case 'u', 'U':
+			n := 4
+			if s[i] == 'U' {
+				n = 8
+			}
+			if i+1+n > len(s) {
+				return "", errors.New("bad unicode escape")
+			}
+			r, err := strconv.ParseUint(s[i+1:i+1+n], 16, 32)
+			if err != nil || !utf8.ValidRune(rune(r)) {
+				return "", errors.New("bad unicode escape")
+			}
+			b.WriteRune(rune(r))
+			i += n
+		default:
+			return "", fmt.Errorf("bad escape \\%c", s[i])
+		}
+	}
+	return b.String(), nil
+}
+
+func quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, `\u%04X`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+func formatKey(k string) string {
+	if bareKey(k) {
+		return k
+	}
+	return quote(k)
+}
+
+func bareKey(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isBareKeyByte(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isBareKeyByte(c byte) bool {
+	return c == '_' || c == '-' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
@@ -0,0 +1,119 @@
+package toml
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const sample = `# spotctl config
+device = "Kitchen" # the speaker
+max_attempts = 1_0
+verbose = true
+tags = ['a', "b\"c"]
+
+[aliases]
+np = "status"
+"work mix" = 'play spotify:playlist:x'
+`
+
+type sampleConfig struct {
+	Device      string            `toml:"device"`
+	MaxAttempts int               `toml:"max_attempts"`
+	Verbose     bool              `toml:"verbose"`
+	Tags        []string          `toml:"tags"`
+	Aliases     map[string]string `toml:"aliases"`
+}
+
+func TestDecode(t *testing.T) {
+	var c sampleConfig
+	if err := Decode([]byte(sample), &c); err != nil {
+		t.Fatal(err)
+	}
+	want := sampleConfig{
+		Device:      "Kitchen",
+		MaxAttempts: 10,
+		Verbose:     true,
+		Tags:        []string{"a", `b"c`},
+		Aliases:     map[string]string{"np": "status", "work mix": "play spotify:playlist:x"},
+	}
+	if !reflect.DeepEqual(c, want) {
+		t.Fatalf("got %+v\nwant %+v", c, want)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	for in, want := range map[string]string{
+		"devic = \"x\"":                  `unknown key "devic"`,
+		"device = 3":                     "want a string",
+		"device = Kitchen":               "strings need quotes",
+		"device = \"x\"\ndevice = \"y\"": "line 2: duplicate key",
+		"device = \"x":                   "unterminated string",
+		"[aliases]\nnp = 1":              "np: want a string",
+	} {
+		var c sampleConfig
+		err := Decode([]byte(in), &c)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: err=%v, want %q", in, err, want)
+		}
+	}
+}
+
+func TestSetKeepsLayout(t *testing.T) {
+	out, err := Set([]byte(sample), "", "device", "Office")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err = Set(out, "", "market", "NL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err = Set(out, "aliases", "skip", "next")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err = Set(out, "extra", "n", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err = Delete(out, "", "verbose")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# spotctl config
+device = "Office"
+max_attempts = 1_0
+tags = ['a', "b\"c"]
+market = "NL"
+
+[aliases]
+np = "status"
+"work mix" = 'play spotify:playlist:x'
+skip = "next"
+
+[extra]
+n = 5
+`
+	if string(out) != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", out, want)
+	}
+	if _, err := Parse(out); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	for _, s := range []string{"plain", `quo"te`, `back\slash`, "tab\tnew\nline", "ctl\x01", "ünïcode"} {
+		enc, err := Format(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := Parse([]byte("k = " + enc))
+		if err != nil {
+			t.Fatalf("%q: %v", enc, err)
+		}
+		if m["k"] != s {
+			t.Fatalf("%q -> %s -> %q", s, enc, m["k"])
+		}
+	}
+}